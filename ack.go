@@ -0,0 +1,470 @@
+package telegramhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/andoma-go/logrus"
+)
+
+// Acknowledgement makes severe alerts carry an "Acknowledge" button and
+// re-pings them until someone presses it. Button presses are received with
+// getUpdates, so a bot can only be used by one process: the hooks of a
+// process share one poller per bot, but Telegram rejects concurrent polls
+// from several processes and each poll takes the presses away from the
+// others.
+type Acknowledgement struct {
+	// Level is the least severe level that requires acknowledgement, e.g.
+	// logrus.FatalLevel.
+	Level logrus.Level
+	// Interval is the time between re-pings, 10 minutes when zero.
+	Interval time.Duration
+	// MaxPings limits the number of re-pings, 6 when zero.
+	MaxPings int
+	// Mention is added to re-pings, e.g. "@oncall".
+	Mention string
+}
+
+func (a *Acknowledgement) interval() time.Duration {
+	if a.Interval > 0 {
+		return a.Interval
+	}
+	return 10 * time.Minute
+}
+
+func (a *Acknowledgement) maxPings() int {
+	if a.MaxPings > 0 {
+		return a.MaxPings
+	}
+	return 6
+}
+
+// ackPrefix starts the callback data of acknowledge buttons, which continues
+// with the ID of the hook and the token of the alert, e.g. "ack:1f2e3d4c:...".
+const ackPrefix = "ack:"
+
+// longPollTimeout is how long getUpdates waits for updates to arrive.
+const longPollTimeout = 25 * time.Second
+
+// longPollKey marks the context of getUpdates requests, which are not bound by
+// the timeout of the HTTP client.
+type longPollKey struct{}
+
+// pendingAck is an alert waiting for acknowledgement.
+type pendingAck struct {
+	cfg       config
+	messageId int64
+	text      string
+	sent      time.Time
+	pings     int
+	timer     *time.Timer
+}
+
+// acknowledgements holds the alerts waiting for acknowledgement by token.
+type acknowledgements struct {
+	mu      sync.Mutex
+	id      string // namespaces the callback data of the buttons of the hook
+	pending map[string]*pendingAck
+}
+
+// hookID returns the ID that tells the buttons of the hook apart from those
+// of other hooks using the same bot.
+func (a *acknowledgements) hookID() string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.id == "" {
+		a.id = newOutboxID()[:8]
+	}
+	return a.id
+}
+
+// inlineButton is a button of an inline keyboard.
+type inlineButton struct {
+	Text         string `json:"text"`
+	CallbackData string `json:"callback_data"`
+}
+
+// inlineKeyboard is the reply markup of a message with buttons.
+type inlineKeyboard struct {
+	InlineKeyboard [][]inlineButton `json:"inline_keyboard"`
+}
+
+// editMarkupRequest is the payload of editMessageReplyMarkup.
+type editMarkupRequest struct {
+	ChatId      string          `json:"chat_id"`
+	MessageId   int64           `json:"message_id"`
+	ReplyMarkup *inlineKeyboard `json:"reply_markup"`
+}
+
+// getUpdatesRequest is the payload of getUpdates.
+type getUpdatesRequest struct {
+	Offset         int64    `json:"offset"`
+	Timeout        int      `json:"timeout"`
+	AllowedUpdates []string `json:"allowed_updates"`
+}
+
+// apiUpdate is the part of an update that the hook uses.
+type apiUpdate struct {
+	UpdateId      int64             `json:"update_id"`
+	CallbackQuery *apiCallbackQuery `json:"callback_query"`
+	Message       *apiIncoming      `json:"message"`
+}
+
+// apiIncoming is the part of a received message that the hook uses.
+type apiIncoming struct {
+	MessageId int64  `json:"message_id"`
+	Text      string `json:"text"`
+	Chat      struct {
+		Id       int64  `json:"id"`
+		Username string `json:"username"`
+	} `json:"chat"`
+}
+
+// apiCallbackQuery is the part of a callback query that the hook uses.
+type apiCallbackQuery struct {
+	Id   string `json:"id"`
+	Data string `json:"data"`
+	From struct {
+		Username  string `json:"username"`
+		FirstName string `json:"first_name"`
+	} `json:"from"`
+}
+
+// answerCallbackRequest is the payload of answerCallbackQuery.
+type answerCallbackRequest struct {
+	CallbackQueryId string `json:"callback_query_id"`
+	Text            string `json:"text,omitempty"`
+}
+
+// requestAck adds the acknowledge button to a sent alert and schedules its
+// re-pings. text is the text of the message with the given ID.
+func (h *TelegramHook) requestAck(cfg config, messageId int64, text string) {
+	token := newOutboxID()[:16]
+
+	_, err := h.callJSON(cfg, "editMessageReplyMarkup", editMarkupRequest{
+		ChatId:    cfg.chatId,
+		MessageId: messageId,
+		ReplyMarkup: &inlineKeyboard{InlineKeyboard: [][]inlineButton{{
+			{Text: "Acknowledge", CallbackData: ackPrefix + h.acks.hookID() + ":" + token},
+		}}},
+	})
+	if err != nil {
+		h.handleError(err)
+		return
+	}
+
+	a := &h.acks
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.pending == nil {
+		a.pending = map[string]*pendingAck{}
+	}
+	a.pending[token] = &pendingAck{
+		cfg:       cfg,
+		messageId: messageId,
+		text:      text,
+		sent:      time.Now(),
+		timer:     time.AfterFunc(cfg.ack.interval(), func() { h.pingAck(token) }),
+	}
+}
+
+// pingAck re-pings an alert that is still not acknowledged.
+func (h *TelegramHook) pingAck(token string) {
+	a := &h.acks
+	a.mu.Lock()
+	p, ok := a.pending[token]
+	if !ok {
+		a.mu.Unlock()
+		return
+	}
+	p.pings++
+	if p.pings < p.cfg.ack.maxPings() {
+		p.timer = time.AfterFunc(p.cfg.ack.interval(), func() { h.pingAck(token) })
+	} else {
+		// Nobody is reminded anymore, so the alert is forgotten; pressing its
+		// button only answers the press.
+		delete(a.pending, token)
+	}
+	cfg, messageId, text, sent := p.cfg, p.messageId, p.text, p.sent
+	a.mu.Unlock()
+
+	msg := fmt.Sprintf("<b>UNACKNOWLEDGED</b> for %s", time.Since(sent).Round(time.Second))
+	if cfg.ack.Mention != "" {
+		msg += " " + html.EscapeString(cfg.ack.Mention)
+	}
+	reply := replyParameters{MessageId: messageId, Quote: quoteOf(text)}
+	if _, err := h.sendReply(context.Background(), cfg, msg, reply); err != nil {
+		h.handleError(err)
+	}
+}
+
+// stop cancels the re-pings of all pending alerts.
+func (a *acknowledgements) stop() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, p := range a.pending {
+		p.timer.Stop()
+	}
+}
+
+// startUpdateListener polls for presses of acknowledge buttons and for
+// firehose commands. It uses getUpdates, so the bot must not have a webhook
+// and no other process may consume its updates. Hooks of the process using
+// the same bot share one poller.
+func (h *TelegramHook) startUpdateListener() {
+	cfg := h.snapshot()
+	if cfg.ack == nil && !cfg.firehoseCommand {
+		return
+	}
+
+	tokens := []string{cfg.authToken}
+	if alarm := cfg; alarm.ack != nil && alarm.useAlarmBot(alarm.alarmLevel) {
+		tokens = append(tokens, alarm.authToken)
+	}
+	for _, token := range tokens {
+		updatePoller(token).subscribe(h)
+	}
+	go func() {
+		<-h.done
+		for _, token := range tokens {
+			updatePoller(token).unsubscribe(h)
+		}
+	}()
+}
+
+// updatePollers holds the getUpdates pollers shared by the hooks of a
+// process, one per bot token. Telegram answers concurrent long polls of one
+// bot with 409 Conflict, and every poll consumes the updates of all hooks.
+var updatePollers = struct {
+	mu   sync.Mutex
+	bots map[string]*poller
+}{}
+
+// poller polls the updates of a bot for the hooks subscribed to it.
+type poller struct {
+	token   string
+	mu      sync.Mutex
+	offset  int64 // next update to fetch
+	hooks   []*TelegramHook
+	running bool
+}
+
+// updatePoller returns the process-wide poller of the bot with token.
+func updatePoller(token string) *poller {
+	updatePollers.mu.Lock()
+	defer updatePollers.mu.Unlock()
+
+	if updatePollers.bots == nil {
+		updatePollers.bots = map[string]*poller{}
+	}
+	p, ok := updatePollers.bots[token]
+	if !ok {
+		p = &poller{token: token}
+		updatePollers.bots[token] = p
+	}
+	return p
+}
+
+// subscribe adds h to the hooks receiving the updates and starts polling if
+// no poll is running.
+func (p *poller) subscribe(h *TelegramHook) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.hooks = append(p.hooks, h)
+	if !p.running {
+		p.running = true
+		go p.run()
+	}
+}
+
+// unsubscribe removes h from the hooks receiving the updates. Polling stops
+// once no hook is left.
+func (p *poller) unsubscribe(h *TelegramHook) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for i, hook := range p.hooks {
+		if hook == h {
+			p.hooks = append(p.hooks[:i:i], p.hooks[i+1:]...)
+			break
+		}
+	}
+}
+
+// subscribers returns the hooks receiving the updates.
+func (p *poller) subscribers() []*TelegramHook {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]*TelegramHook(nil), p.hooks...)
+}
+
+// run polls the updates through the first subscribed hook, until no hook is
+// left. A poll is cancelled when the hook doing it is closed.
+func (p *poller) run() {
+	for {
+		p.mu.Lock()
+		if len(p.hooks) == 0 {
+			p.running = false
+			p.mu.Unlock()
+			return
+		}
+		h := p.hooks[0]
+		p.mu.Unlock()
+
+		select {
+		case <-h.done:
+			p.unsubscribe(h)
+			continue
+		default:
+		}
+
+		cfg := h.snapshot()
+		cfg.authToken = p.token
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() {
+			select {
+			case <-h.done:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+		err := h.pollUpdates(ctx, cfg)
+		closed := ctx.Err() != nil
+		cancel()
+
+		if err != nil && !closed {
+			h.handleError(err)
+			select {
+			case <-h.done:
+			case <-time.After(5 * time.Second):
+			}
+		}
+	}
+}
+
+// pollUpdates fetches one batch of updates of the bot of cfg using long
+// polling and hands them to h and the hooks subscribed to the bot.
+func (h *TelegramHook) pollUpdates(ctx context.Context, cfg config) error {
+	p := updatePoller(cfg.authToken)
+	hooks := p.subscribers()
+	found := false
+	for _, hook := range hooks {
+		found = found || hook == h
+	}
+	if !found {
+		hooks = append(hooks, h)
+	}
+
+	// The client timeout would cut the long poll short, the context bounds it
+	// instead.
+	ctx, cancel := context.WithTimeout(context.WithValue(ctx, longPollKey{}, true), longPollTimeout+10*time.Second)
+	defer cancel()
+
+	allowed := []string{"callback_query"}
+	for _, hook := range hooks {
+		if hook.FirehoseCommand() {
+			allowed = append(allowed, "message")
+			break
+		}
+	}
+
+	p.mu.Lock()
+	offset := p.offset
+	p.mu.Unlock()
+	result, err := h.callJSONContext(ctx, cfg, "getUpdates", getUpdatesRequest{
+		Offset:         offset,
+		Timeout:        int(longPollTimeout / time.Second),
+		AllowedUpdates: allowed,
+	})
+	if err != nil {
+		return err
+	}
+
+	var updates []apiUpdate
+	if err := json.Unmarshal(result, &updates); err != nil {
+		return err
+	}
+
+	for _, u := range updates {
+		p.mu.Lock()
+		if u.UpdateId >= p.offset {
+			p.offset = u.UpdateId + 1
+		}
+		p.mu.Unlock()
+
+		if u.CallbackQuery != nil {
+			dispatchCallback(hooks, cfg, u.CallbackQuery)
+		}
+		if u.Message != nil {
+			for _, hook := range hooks {
+				hcfg := hook.snapshot()
+				hcfg.authToken = cfg.authToken
+				if hcfg.firehoseCommand {
+					hook.handleCommand(hcfg, u.Message)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// dispatchCallback hands a button press to the hook whose acknowledge button
+// was pressed. Presses of buttons of other hooks, e.g. of another process
+// using the same bot, are left unanswered.
+func dispatchCallback(hooks []*TelegramHook, cfg config, q *apiCallbackQuery) {
+	rest, ok := strings.CutPrefix(q.Data, ackPrefix)
+	if !ok {
+		return
+	}
+	id, token, ok := strings.Cut(rest, ":")
+	if !ok {
+		return
+	}
+	for _, hook := range hooks {
+		if hook.acks.hookID() == id {
+			hook.handleCallback(cfg, token, q)
+			return
+		}
+	}
+}
+
+// handleCallback acknowledges the alert with token whose button was pressed
+// and records who acknowledged it and how long it took in the message.
+func (h *TelegramHook) handleCallback(cfg config, token string, q *apiCallbackQuery) {
+	a := &h.acks
+	a.mu.Lock()
+	p, ok := a.pending[token]
+	if ok {
+		delete(a.pending, token)
+		p.timer.Stop()
+	}
+	a.mu.Unlock()
+
+	answer := answerCallbackRequest{CallbackQueryId: q.Id}
+	if !ok {
+		answer.Text = "Already acknowledged or expired"
+	}
+	if _, err := h.callJSON(cfg, "answerCallbackQuery", answer); err != nil {
+		h.handleError(err)
+	}
+	if !ok {
+		return
+	}
+
+	by := q.From.FirstName
+	if q.From.Username != "" {
+		by = "@" + q.From.Username
+	}
+	text := fmt.Sprintf("%s\n<i>acknowledged by %s after %s</i>",
+		p.text, html.EscapeString(by), time.Since(p.sent).Round(time.Second))
+	if err := h.editMessage(p.cfg, p.messageId, text); err != nil {
+		h.handleError(err)
+	}
+}
@@ -0,0 +1,150 @@
+package telegramhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	log "github.com/andoma-go/logrus"
+)
+
+func TestAcknowledgement(t *testing.T) {
+	var token string
+	api := &fakeAPI{respond: func(method string, body []byte) *http.Response {
+		switch method {
+		case "editMessageReplyMarkup":
+			var req editMarkupRequest
+			_ = json.Unmarshal(body, &req)
+			token = req.ReplyMarkup.InlineKeyboard[0][0].CallbackData
+		case "getUpdates":
+			return jsonResponse(http.StatusOK, `{"ok":true,"result":[{"update_id":5,"callback_query":{"id":"q1","data":"`+token+`","from":{"username":"alice"}}}]}`)
+		}
+		return nil
+	}}
+	h := newTestHook(WithAcknowledgement(Acknowledgement{Level: log.FatalLevel, Interval: time.Hour, Mention: "@oncall"}))
+	h.authToken, h.client = "1:acks", api.client()
+
+	if err := h.Fire(&log.Entry{Level: log.ErrorLevel, Message: "minor"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Fire(&log.Entry{Level: log.FatalLevel, Message: "disk gone"}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(token, ackPrefix+h.acks.hookID()+":") {
+		t.Fatalf("Expected an acknowledge button of the hook, got callback data %q", token)
+	}
+
+	h.pingAck(token[strings.LastIndex(token, ":")+1:])
+	if err := h.pollUpdates(context.Background(), h.snapshot()); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"sendMessage", "sendMessage", "editMessageReplyMarkup", "sendMessage", "getUpdates", "answerCallbackQuery", "editMessageText"}
+	if got := api.methods(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Unexpected calls %v, want %v", got, want)
+	}
+
+	texts := api.texts()
+	if ping := texts[2]; !strings.HasPrefix(ping, "<b>UNACKNOWLEDGED</b> for ") || !strings.HasSuffix(ping, " @oncall") {
+		t.Errorf("Unexpected re-ping %q", ping)
+	}
+	if edit := texts[len(texts)-1]; !strings.HasPrefix(edit, "<b>FATAL</b>@testing - disk gone\n<i>acknowledged by @alice after ") {
+		t.Errorf("Unexpected acknowledged message %q", edit)
+	}
+	if offset := updatePoller("1:acks").offset; offset != 6 || len(h.acks.pending) != 0 {
+		t.Errorf("Expected the update to be consumed and the alert acknowledged, offset %d, pending %d", offset, len(h.acks.pending))
+	}
+}
+
+func TestAcknowledgementSharedBot(t *testing.T) {
+	var updates string
+	api := &fakeAPI{respond: func(method string, body []byte) *http.Response {
+		if method == "getUpdates" {
+			return jsonResponse(http.StatusOK, `{"ok":true,"result":[`+updates+`]}`)
+		}
+		return nil
+	}}
+	hooks := make([]*TelegramHook, 2)
+	for i := range hooks {
+		hooks[i] = newTestHook(WithAcknowledgement(Acknowledgement{Level: log.FatalLevel, Interval: time.Hour}))
+		hooks[i].authToken, hooks[i].client = "1:shared", api.client()
+		hooks[i].requestAck(hooks[i].snapshot(), int64(i+1), "disk gone")
+	}
+	p := updatePoller("1:shared")
+	p.hooks = hooks // subscribed without starting a poll
+	defer func() { p.hooks = nil }()
+
+	var token string
+	for token = range hooks[1].acks.pending {
+	}
+	updates = `{"update_id":1,"callback_query":{"id":"q1","data":"ack:0000:x"}},` +
+		`{"update_id":2,"callback_query":{"id":"q2","data":"ack:` + hooks[1].acks.hookID() + `:` + token + `"}}`
+	if err := hooks[0].pollUpdates(context.Background(), hooks[0].snapshot()); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(hooks[0].acks.pending) != 1 || len(hooks[1].acks.pending) != 0 {
+		t.Errorf("Expected only the pressed alert of the second hook to be acknowledged")
+	}
+	var answered []string
+	api.mu.Lock()
+	for _, c := range api.calls {
+		if c.method == "answerCallbackQuery" {
+			var req answerCallbackRequest
+			_ = json.Unmarshal(c.body, &req)
+			answered = append(answered, req.CallbackQueryId)
+		}
+	}
+	api.mu.Unlock()
+	if !reflect.DeepEqual(answered, []string{"q2"}) {
+		t.Errorf("Expected only the press of a known button to be answered, got %v", answered)
+	}
+	for _, h := range hooks {
+		h.acks.stop()
+	}
+}
+
+func TestAcknowledgementExpires(t *testing.T) {
+	api := &fakeAPI{}
+	h := newTestHook(WithAcknowledgement(Acknowledgement{Level: log.FatalLevel, Interval: time.Hour, MaxPings: 2}))
+	h.client = api.client()
+
+	h.requestAck(h.snapshot(), 1, "disk gone")
+	var token string
+	for token = range h.acks.pending {
+	}
+
+	h.pingAck(token)
+	if len(h.acks.pending) != 1 {
+		t.Fatal("Expected the alert to be pending after the first re-ping")
+	}
+	h.acks.pending[token].timer.Stop()
+	h.pingAck(token)
+	if len(h.acks.pending) != 0 {
+		t.Error("Expected the alert to be forgotten after the last re-ping")
+	}
+}
+
+func TestLongPollTimeout(t *testing.T) {
+	var timeout int
+	api := &fakeAPI{respond: func(method string, body []byte) *http.Response {
+		var req getUpdatesRequest
+		_ = json.Unmarshal(body, &req)
+		timeout = req.Timeout
+		return jsonResponse(http.StatusOK, `{"ok":true,"result":[]}`)
+	}}
+	h := newTestHook(WithAcknowledgement(Acknowledgement{Level: log.FatalLevel}))
+	h.client = api.client()
+	h.client.Timeout = 5 * time.Second
+
+	if err := h.pollUpdates(context.Background(), h.snapshot()); err != nil {
+		t.Fatal(err)
+	}
+	if timeout != 25 {
+		t.Errorf("Expected a 25 second long poll despite the client timeout, got %d", timeout)
+	}
+}
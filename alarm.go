@@ -0,0 +1,35 @@
+package telegramhook
+
+import (
+	"context"
+	"errors"
+
+	"github.com/andoma-go/logrus"
+)
+
+// useAlarmBot switches to the token of the alarm bot if it is configured and
+// level is severe enough, and reports whether it did.
+func (c *config) useAlarmBot(level logrus.Level) bool {
+	if c.alarmToken == "" || level > c.alarmLevel {
+		return false
+	}
+	c.authToken = c.alarmToken
+	return true
+}
+
+// verifyAlarmBot verifies the token of the alarm bot, if one is configured.
+func (h *TelegramHook) verifyAlarmBot() error {
+	cfg := h.snapshot()
+	if !cfg.useAlarmBot(cfg.alarmLevel) {
+		return nil
+	}
+
+	if _, err := h.call(context.Background(), cfg, "getMe", "", nil); err != nil {
+		var apiErr *APIError
+		if errors.As(err, &apiErr) {
+			return &InvalidTokenError{Err: apiErr}
+		}
+		return &NetworkError{Err: err}
+	}
+	return nil
+}
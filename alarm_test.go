@@ -0,0 +1,77 @@
+package telegramhook
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	log "github.com/andoma-go/logrus"
+)
+
+func TestAlarmBot(t *testing.T) {
+	var mu sync.Mutex
+	var paths []string
+	client := &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		mu.Lock()
+		paths = append(paths, r.URL.Path)
+		mu.Unlock()
+		return jsonResponse(200, `{"ok":true,"result":{"message_id":1}}`), nil
+	})}
+
+	h, err := NewTelegramHookWithClient("app", "1:main", "1", "", client,
+		WithLevel(log.InfoLevel), WithAlarmBot("2:alarm", log.ErrorLevel))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, level := range []log.Level{log.InfoLevel, log.WarnLevel, log.ErrorLevel, log.FatalLevel} {
+		if err := h.Fire(&log.Entry{Level: level, Message: "m"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := []string{
+		"/bot1:main/getMe", "/bot2:alarm/getMe",
+		"/bot1:main/sendMessage", "/bot1:main/sendMessage",
+		"/bot2:alarm/sendMessage", "/bot2:alarm/sendMessage",
+	}
+	if got := strings.Join(paths, " "); got != strings.Join(want, " ") {
+		t.Errorf("Unexpected requests:\n%s\nwant\n%s", got, strings.Join(want, " "))
+	}
+}
+
+func TestAlarmBotRetract(t *testing.T) {
+	var mu sync.Mutex
+	var paths []string
+	client := &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		mu.Lock()
+		paths = append(paths, r.URL.Path)
+		mu.Unlock()
+		return jsonResponse(200, `{"ok":true,"result":{"message_id":1}}`), nil
+	})}
+
+	h, err := NewTelegramHookWithClient("app", "1:main", "1", "", client,
+		WithLevel(log.InfoLevel), WithAlarmBot("2:alarm", log.ErrorLevel))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, level := range []log.Level{log.InfoLevel, log.ErrorLevel} {
+		if err := h.Fire(&log.Entry{Level: level, Message: "m", Data: log.Fields{CorrelationKey: "k"}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mu.Lock()
+	paths = nil
+	mu.Unlock()
+
+	if err := h.Retract("k"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"/bot1:main/deleteMessage", "/bot2:alarm/deleteMessage"}
+	if got := strings.Join(paths, " "); got != strings.Join(want, " ") {
+		t.Errorf("Unexpected requests:\n%s\nwant\n%s", got, strings.Join(want, " "))
+	}
+}
@@ -0,0 +1,318 @@
+package telegramhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultApiBaseURL is the official Telegram Bot API server.
+const defaultApiBaseURL = "https://api.telegram.org"
+
+// defaultFailoverCooldown is how long an endpoint is avoided after it failed.
+const defaultFailoverCooldown = 30 * time.Second
+
+// apiRequest encapsulates the request structure we are sending to the Telegram API.
+type apiRequest struct {
+	ChatId    string           `json:"chat_id"`
+	ThreadId  string           `json:"message_thread_id,omitempty"`
+	Text      string           `json:"text"`
+	ParseMode string           `json:"parse_mode,omitempty"`
+	Silent    bool             `json:"disable_notification,omitempty"`
+	Reply     *replyParameters `json:"reply_parameters,omitempty"`
+}
+
+// replyParameters makes a message a reply to another message in the same chat,
+// optionally quoting the relevant part of it. A zero MessageId sends no reply.
+type replyParameters struct {
+	MessageId int64  `json:"message_id"`
+	Quote     string `json:"quote,omitempty"`
+}
+
+// maxQuoteLength is the Telegram limit for the quote of a reply.
+const maxQuoteLength = 1024
+
+// quoteOf returns the quote of a reply to the HTML message msg: its first line
+// as plain text, which is what follow-ups refer to, e.g. the headline of an
+// alert.
+func quoteOf(msg string) string {
+	line, _, _ := strings.Cut(msg, "\n")
+	quote := strings.TrimSpace(html.UnescapeString(stripTags(line)))
+
+	n := 0
+	for i, r := range quote {
+		if n += runeLen(r); n > maxQuoteLength {
+			return quote[:i]
+		}
+	}
+	return quote
+}
+
+// quoteRejected reports whether err rejects the quote of a reply, e.g.
+// because the quoted message was edited since.
+func quoteRejected(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.Code == http.StatusBadRequest &&
+		strings.Contains(strings.ToUpper(apiErr.Description), "QUOTE")
+}
+
+// apiMessage is the part of a sent message returned by the Telegram API that the hook uses.
+type apiMessage struct {
+	MessageId int64 `json:"message_id"`
+}
+
+// apiResponse encapsulates the response structure received from the Telegram API.
+type apiResponse struct {
+	Ok         bool                `json:"ok"`
+	ErrorCode  *int                `json:"error_code,omitempty"`
+	Desc       *string             `json:"description,omitempty"`
+	Result     json.RawMessage     `json:"result,omitempty"`
+	Parameters *responseParameters `json:"parameters,omitempty"`
+}
+
+// responseParameters explains why a request failed.
+type responseParameters struct {
+	RetryAfter int `json:"retry_after,omitempty"`
+}
+
+// endpointHealth remembers which API endpoints recently failed. Endpoints are
+// checked passively: a network error, an undecodable response or a 5xx error
+// marks an endpoint as down until its cooldown passes.
+type endpointHealth struct {
+	mu   sync.Mutex
+	down map[string]time.Time
+}
+
+// order returns bases with healthy endpoints first, in configured order,
+// followed by failed endpoints ordered by when they recover.
+func (e *endpointHealth) order(bases []string) []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := time.Now()
+	healthy := make([]string, 0, len(bases))
+	var failed []string
+	for _, base := range bases {
+		if until, ok := e.down[base]; ok && now.Before(until) {
+			failed = append(failed, base)
+		} else {
+			healthy = append(healthy, base)
+		}
+	}
+
+	sort.SliceStable(failed, func(i, j int) bool {
+		return e.down[failed[i]].Before(e.down[failed[j]])
+	})
+
+	return append(healthy, failed...)
+}
+
+// markDown excludes base from the preferred endpoints for cooldown.
+func (e *endpointHealth) markDown(base string, cooldown time.Duration) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.down == nil {
+		e.down = map[string]time.Time{}
+	}
+	e.down[base] = time.Now().Add(cooldown)
+}
+
+// markUp restores base after a successful call.
+func (e *endpointHealth) markUp(base string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	delete(e.down, base)
+}
+
+// verifyToken issues a test request to the Telegram API to ensure the provided token is correct and valid.
+func (h *TelegramHook) verifyToken() error {
+	cfg := h.snapshot()
+
+	if _, err := h.call(context.Background(), cfg, "getMe", "", nil); err != nil {
+		var apiErr *APIError
+		if errors.As(err, &apiErr) {
+			return &InvalidTokenError{Err: apiErr}
+		}
+		return &NetworkError{Err: err}
+	}
+
+	return nil
+}
+
+// sendPart issues a single message that fits the Telegram length limit and
+// returns its message ID, as a reply unless reply has no message ID. When
+// Telegram rejects the quote of the reply, it is sent without.
+func (h *TelegramHook) sendPart(ctx context.Context, cfg config, msg string, reply replyParameters) (int64, error) {
+	apiReq := apiRequest{
+		ChatId:    cfg.chatId,
+		ThreadId:  cfg.threadId,
+		Text:      cfg.parseMode.convert(msg),
+		ParseMode: cfg.parseMode.apiValue(),
+		Silent:    cfg.silent,
+	}
+	if reply.MessageId != 0 {
+		apiReq.Reply = &reply
+	}
+
+	var sent apiMessage
+	err := h.retry(ctx, cfg, func() error {
+		if err := h.pace(ctx, cfg); err != nil {
+			return err
+		}
+		result, err := h.callJSONContext(ctx, cfg, "sendMessage", apiReq)
+		if err != nil && apiReq.Reply != nil && apiReq.Reply.Quote != "" && quoteRejected(err) {
+			apiReq.Reply = &replyParameters{MessageId: reply.MessageId}
+			result, err = h.callJSONContext(ctx, cfg, "sendMessage", apiReq)
+		}
+		if err != nil {
+			return err
+		}
+		h.trackVolume(cfg)
+		return json.Unmarshal(result, &sent)
+	})
+	return sent.MessageId, err
+}
+
+// callJSON issues the Bot API method with payload encoded as JSON.
+func (h *TelegramHook) callJSON(cfg config, method string, payload interface{}) (json.RawMessage, error) {
+	return h.callJSONContext(context.Background(), cfg, method, payload)
+}
+
+// callJSONContext is callJSON with a context that cancels the request.
+func (h *TelegramHook) callJSONContext(ctx context.Context, cfg config, method string, payload interface{}) (json.RawMessage, error) {
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	return h.call(ctx, cfg, method, "application/json", b)
+}
+
+// call issues the Bot API method and returns the result of a successful call.
+// Requests without a body use GET. Endpoints that fail with a network error,
+// an undecodable response or a server error are marked down and the next
+// configured endpoint is tried.
+func (h *TelegramHook) call(ctx context.Context, cfg config, method, contentType string, body []byte) (json.RawMessage, error) {
+	var lastErr error
+	for _, base := range h.endpoints.order(cfg.apiBaseURLs()) {
+		result, err := h.callEndpoint(ctx, cfg, base, method, contentType, body)
+		if err == nil {
+			h.endpoints.markUp(base)
+			return result, nil
+		}
+
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && apiErr.Code < 500 {
+			return nil, err
+		}
+
+		if ctx.Err() != nil {
+			return nil, err
+		}
+
+		h.endpoints.markDown(base, cfg.failoverCooldown)
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// callEndpoint issues the Bot API method against a single endpoint.
+func (h *TelegramHook) callEndpoint(ctx context.Context, cfg config, base, method, contentType string, body []byte) (json.RawMessage, error) {
+	endpoint, err := url.JoinPath(cfg.apiEndpoint(base), method)
+	if err != nil {
+		return nil, err
+	}
+
+	httpMethod := http.MethodGet
+	var r io.Reader
+	if body != nil {
+		httpMethod, r = http.MethodPost, bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, httpMethod, endpoint, r)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	res, err := h.do(cfg, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeResponse(res)
+}
+
+// do issues req with the identification headers of the hook, unless an
+// injected fault answers it. Long polls are not bound by the client timeout.
+func (h *TelegramHook) do(cfg config, req *http.Request) (*http.Response, error) {
+	if ua := cfg.userAgent(); ua != "" {
+		req.Header.Set("User-Agent", ua)
+	}
+	if cfg.faults != nil {
+		if res, injected, err := cfg.faults.inject(req); injected {
+			return res, err
+		}
+	}
+	if h.client.Timeout > 0 && req.Context().Value(longPollKey{}) != nil {
+		client := *h.client
+		client.Timeout = 0
+		return client.Do(req)
+	}
+	return h.client.Do(req)
+}
+
+// decodeResponse reads a Telegram API response and converts a failure into an error.
+func decodeResponse(res *http.Response) (json.RawMessage, error) {
+	defer res.Body.Close()
+
+	apiRes := apiResponse{}
+	if err := json.NewDecoder(res.Body).Decode(&apiRes); err != nil {
+		return nil, fmt.Errorf("unable to decode Telegram API response (HTTP %d): %w", res.StatusCode, err)
+	}
+
+	if !apiRes.Ok {
+		// Received an error from the Telegram API
+		apiErr := &APIError{}
+
+		if apiRes.ErrorCode != nil {
+			apiErr.Code = *apiRes.ErrorCode
+		}
+
+		if apiRes.Desc != nil {
+			apiErr.Description = *apiRes.Desc
+		}
+
+		if apiRes.Parameters != nil {
+			apiErr.RetryAfter = time.Duration(apiRes.Parameters.RetryAfter) * time.Second
+		}
+
+		return nil, apiErr
+	}
+
+	return apiRes.Result, nil
+}
+
+// apiBaseURLs returns the configured API base URLs, the official server by default.
+func (c *config) apiBaseURLs() []string {
+	if len(c.baseURLs) == 0 {
+		return []string{defaultApiBaseURL}
+	}
+	return c.baseURLs
+}
+
+// apiEndpoint returns the bot endpoint for the configured token on base.
+func (c *config) apiEndpoint(base string) string {
+	return fmt.Sprintf("%s/bot%s", strings.TrimSuffix(base, "/"), c.authToken)
+}
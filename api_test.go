@@ -0,0 +1,97 @@
+package telegramhook
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	log "github.com/andoma-go/logrus"
+)
+
+func TestCallFailover(t *testing.T) {
+	var hosts []string
+	client := &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		hosts = append(hosts, r.URL.Host)
+		if r.URL.Host == "blocked.example" {
+			return nil, errors.New("connection timed out")
+		}
+		return jsonResponse(200, `{"ok":true,"result":{}}`), nil
+	})}
+
+	h, err := NewTelegramHookWithClient("app", "123:abc", "1", "", client,
+		WithApiEndpoints("https://blocked.example", "https://mirror.example/"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := h.Fire(&log.Entry{Level: log.ErrorLevel, Message: "m"}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "blocked.example mirror.example mirror.example"
+	if got := strings.Join(hosts, " "); got != want {
+		t.Errorf("Requested hosts %q, want %q", got, want)
+	}
+
+	if got := h.ApiEndpoint(); got != "https://mirror.example/" {
+		t.Errorf("ApiEndpoint() = %q after failover", got)
+	}
+}
+
+func TestApiEndpoint(t *testing.T) {
+	var urls []string
+	client := &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		urls = append(urls, r.URL.String())
+		return jsonResponse(200, `{"ok":true,"result":{}}`), nil
+	})}
+
+	h, err := NewTelegramHookWithClient("app", "123:abc", "1", "", client,
+		WithApiEndpoint("http://localhost:8081"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := h.ApiEndpoint(); got != "http://localhost:8081" {
+		t.Errorf("ApiEndpoint() = %q", got)
+	}
+	if want := "http://localhost:8081/bot123:abc/getMe"; len(urls) != 1 || urls[0] != want {
+		t.Errorf("Requested %v, want %s", urls, want)
+	}
+
+	h.SetApiEndpoint("")
+	if got := h.ApiEndpoint(); got != defaultApiBaseURL {
+		t.Errorf("ApiEndpoint() = %q after reset", got)
+	}
+
+	var configErr *ConfigError
+	if _, err := NewTelegramHookWithClient("app", "123:abc", "1", "", client, WithApiEndpoint("localhost:8081")); !errors.As(err, &configErr) {
+		t.Errorf("Expected ConfigError for a URL without scheme, got %v", err)
+	}
+}
+
+func TestQuoteRejected(t *testing.T) {
+	api := &fakeAPI{respond: func(method string, body []byte) *http.Response {
+		if strings.Contains(string(body), `"quote"`) {
+			return jsonResponse(400, `{"ok":false,"error_code":400,"description":"Bad Request: QUOTE_TEXT_INVALID"}`)
+		}
+		return nil
+	}}
+	h := newTestHook()
+	h.chatId = "42"
+	h.client = api.client()
+
+	if _, err := h.sendReply(context.Background(), h.snapshot(), "<b>Disk full</b>\ndetails", replyParameters{MessageId: 7, Quote: quoteOf("<b>Disk full</b>\ndetails")}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(api.calls) != 2 {
+		t.Fatalf("Sent %d requests, want the reply and its resend without quote", len(api.calls))
+	}
+	if body := string(api.calls[0].body); !strings.Contains(body, `"quote":"Disk full"`) {
+		t.Errorf("First request %s does not quote the headline", body)
+	}
+	if body := string(api.calls[1].body); !strings.Contains(body, `"reply_parameters":{"message_id":7}`) {
+		t.Errorf("Resend %s should reply without quote", body)
+	}
+}
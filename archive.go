@@ -0,0 +1,66 @@
+package telegramhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Blob stores objects, e.g. in an S3 or GCS bucket, see WithArchive.
+type Blob interface {
+	// Put stores data under key, replacing an existing object.
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+}
+
+// ArchivedMessage is a delivered message as it is stored by the archive, encoded
+// as JSON.
+type ArchivedMessage struct {
+	AppName    string    `json:"app_name"`
+	ChatId     string    `json:"chat_id"`
+	ThreadId   string    `json:"thread_id,omitempty"`
+	MessageIds []int64   `json:"message_ids"`
+	Level      string    `json:"level"`
+	Key        string    `json:"key,omitempty"`
+	Text       string    `json:"text"`
+	Document   string    `json:"document,omitempty"`
+	Content    []byte    `json:"content,omitempty"`
+	Sent       time.Time `json:"sent"`
+}
+
+// archiveKey returns where m is stored, grouped by day and chat so the archive
+// can be listed by prefix, e.g. "2024/05/17/-100123/153012.123456789-42.json".
+func archiveKey(m ArchivedMessage) string {
+	var id int64
+	if len(m.MessageIds) > 0 {
+		id = m.MessageIds[0]
+	}
+	t := m.Sent.UTC()
+	return fmt.Sprintf("%s/%s/%s-%d.json", t.Format("2006/01/02"), m.ChatId, t.Format("150405.000000000"), id)
+}
+
+// archiveMessage stores a delivered message in the configured Blob.
+func (h *TelegramHook) archiveMessage(ctx context.Context, cfg config, out outgoing, ids []int64) {
+	m := ArchivedMessage{
+		AppName:    cfg.appName,
+		ChatId:     cfg.chatId,
+		ThreadId:   cfg.threadId,
+		MessageIds: ids,
+		Level:      out.level.String(),
+		Key:        out.key,
+		Text:       out.msg,
+		Sent:       time.Now(),
+	}
+	if out.doc != nil {
+		m.Document, m.Content = out.doc.name, out.doc.content
+	}
+
+	b, err := json.Marshal(m)
+	if err != nil {
+		h.handleError(err)
+		return
+	}
+	if err := cfg.archive.Put(ctx, archiveKey(m), b, "application/json"); err != nil {
+		h.handleError(fmt.Errorf("archive: %w", err))
+	}
+}
@@ -0,0 +1,53 @@
+package telegramhook
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+
+	log "github.com/andoma-go/logrus"
+)
+
+type memBlob struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func (b *memBlob) Put(_ context.Context, key string, data []byte, _ string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.objects == nil {
+		b.objects = map[string][]byte{}
+	}
+	b.objects[key] = data
+	return nil
+}
+
+func TestArchive(t *testing.T) {
+	blob := &memBlob{}
+	h := newTestHook(WithChatTarget(Chat("-100")), WithArchive(blob))
+	h.client = (&fakeAPI{}).client()
+
+	if err := h.Fire(&log.Entry{Level: log.ErrorLevel, Message: "keep me", Data: log.Fields{CorrelationKey: "job-7"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(blob.objects) != 1 {
+		t.Fatalf("Expected one archived message, got %d", len(blob.objects))
+	}
+	for key, data := range blob.objects {
+		if !strings.Contains(key, "/-100/") || !strings.HasSuffix(key, "-1.json") {
+			t.Errorf("Unexpected key %q", key)
+		}
+
+		var m ArchivedMessage
+		if err := json.Unmarshal(data, &m); err != nil {
+			t.Fatal(err)
+		}
+		if m.ChatId != "-100" || m.Key != "job-7" || m.Level != "error" || len(m.MessageIds) != 1 || !strings.Contains(m.Text, "keep me") {
+			t.Errorf("Unexpected archived message %+v", m)
+		}
+	}
+}
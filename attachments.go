@@ -0,0 +1,200 @@
+package telegramhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"path"
+	"strings"
+
+	"github.com/andoma-go/logrus"
+)
+
+// AttachmentsKey is the field that sends files with an entry, an Attachment or
+// []Attachment, e.g. several screenshots or a log file and a config dump. They
+// are sent as one media group with the alert as caption. The field itself is
+// not shown.
+const AttachmentsKey = "telegram_attachments"
+
+// Attachment is a file sent with an entry, see AttachmentsKey. Files named
+// *.jpg, *.jpeg or *.png are sent as photos when all files of the entry are
+// photos, otherwise every file is sent as a document.
+type Attachment struct {
+	Name    string
+	Content []byte
+}
+
+// photo reports whether a is sent as a photo when grouped with other photos.
+func (a Attachment) photo() bool {
+	switch strings.ToLower(path.Ext(a.Name)) {
+	case ".jpg", ".jpeg", ".png":
+		return true
+	}
+	return false
+}
+
+// maxCaptionLength is the Telegram limit for the caption of a media message.
+const maxCaptionLength = 1024
+
+// maxMediaGroup is the maximum number of files in a media group.
+const maxMediaGroup = 10
+
+// entryAttachments returns the files of the AttachmentsKey field of entry and
+// entry without that field.
+func entryAttachments(entry *logrus.Entry) ([]Attachment, *logrus.Entry) {
+	var attachments []Attachment
+	switch v := entry.Data[AttachmentsKey].(type) {
+	case Attachment:
+		attachments = []Attachment{v}
+	case []Attachment:
+		attachments = v
+	default:
+		return nil, entry
+	}
+
+	stripped := *entry
+	stripped.Data = make(logrus.Fields, len(entry.Data)-1)
+	for k, v := range entry.Data {
+		if k != AttachmentsKey {
+			stripped.Data[k] = v
+		}
+	}
+	return attachments, &stripped
+}
+
+// inputMedia describes a file of a media group.
+type inputMedia struct {
+	Type      string `json:"type"`
+	Media     string `json:"media"`
+	Caption   string `json:"caption,omitempty"`
+	ParseMode string `json:"parse_mode,omitempty"`
+}
+
+// sendAttachments sends msg with the attachments as caption, in media groups of
+// up to ten files. A message too long for a caption is sent on its own first.
+// It returns the IDs of all sent messages.
+func (h *TelegramHook) sendAttachments(ctx context.Context, cfg config, msg string, reply replyParameters, attachments []Attachment) ([]int64, error) {
+	var ids []int64
+	caption := msg
+	if htmlTextLen(msg) > maxCaptionLength {
+		sent, err := h.sendReply(ctx, cfg, msg, reply)
+		ids = append(ids, sent...)
+		if err != nil {
+			return ids, err
+		}
+		caption, reply = "", replyParameters{MessageId: sent[len(sent)-1]}
+	}
+
+	mediaType := "photo"
+	for _, a := range attachments {
+		if !a.photo() {
+			mediaType = "document"
+		}
+	}
+
+	for len(attachments) > 0 {
+		n := len(attachments)
+		if n > maxMediaGroup {
+			n = maxMediaGroup
+		}
+		sent, err := h.sendMedia(ctx, cfg, mediaType, caption, reply, attachments[:n])
+		ids = append(ids, sent...)
+		if err != nil {
+			return ids, err
+		}
+		attachments, caption = attachments[n:], ""
+	}
+	return ids, nil
+}
+
+// sendMedia sends the files with the caption on the first one, as a media
+// group unless there is only one file.
+func (h *TelegramHook) sendMedia(ctx context.Context, cfg config, mediaType, caption string, reply replyParameters, files []Attachment) ([]int64, error) {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+
+	w.WriteField("chat_id", cfg.chatId)
+	if threadId := cfg.threadId; threadId != "" {
+		w.WriteField("message_thread_id", threadId)
+	}
+	if cfg.silent {
+		w.WriteField("disable_notification", "true")
+	}
+	if reply.MessageId != 0 {
+		b, _ := json.Marshal(reply)
+		w.WriteField("reply_parameters", string(b))
+	}
+	if caption != "" {
+		caption = cfg.parseMode.convert(caption)
+	}
+
+	method := "sendMediaGroup"
+	if len(files) == 1 {
+		method = "sendDocument"
+		if mediaType == "photo" {
+			method = "sendPhoto"
+		}
+		if caption != "" {
+			w.WriteField("caption", caption)
+			w.WriteField("parse_mode", cfg.parseMode.apiValue())
+		}
+		if err := writeFile(w, mediaType, files[0]); err != nil {
+			return nil, err
+		}
+	} else {
+		media := make([]inputMedia, len(files))
+		for i, f := range files {
+			media[i] = inputMedia{Type: mediaType, Media: fmt.Sprintf("attach://file%d", i)}
+			if err := writeFile(w, fmt.Sprintf("file%d", i), f); err != nil {
+				return nil, err
+			}
+		}
+		media[0].Caption = caption
+		if caption != "" {
+			media[0].ParseMode = cfg.parseMode.apiValue()
+		}
+		b, err := json.Marshal(media)
+		if err != nil {
+			return nil, err
+		}
+		w.WriteField("media", string(b))
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	var sent []apiMessage
+	err := h.retry(ctx, cfg, func() error {
+		if err := h.pace(ctx, cfg); err != nil {
+			return err
+		}
+		result, err := h.call(ctx, cfg, method, w.FormDataContentType(), body.Bytes())
+		if err != nil {
+			return err
+		}
+		h.trackVolume(cfg)
+		if method != "sendMediaGroup" {
+			sent = make([]apiMessage, 1)
+			return json.Unmarshal(result, &sent[0])
+		}
+		return json.Unmarshal(result, &sent)
+	})
+
+	ids := make([]int64, 0, len(sent))
+	for _, m := range sent {
+		ids = append(ids, m.MessageId)
+	}
+	return ids, err
+}
+
+// writeFile adds f to w as the form file field.
+func writeFile(w *multipart.Writer, field string, f Attachment) error {
+	part, err := w.CreateFormFile(field, f.Name)
+	if err != nil {
+		return err
+	}
+	_, err = part.Write(f.Content)
+	return err
+}
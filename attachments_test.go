@@ -0,0 +1,76 @@
+package telegramhook
+
+import (
+	"bytes"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	log "github.com/andoma-go/logrus"
+)
+
+func TestAttachmentsMediaGroup(t *testing.T) {
+	api := &fakeAPI{respond: func(method string, body []byte) *http.Response {
+		if method == "sendMediaGroup" {
+			return jsonResponse(http.StatusOK, `{"ok":true,"result":[{"message_id":7},{"message_id":8}]}`)
+		}
+		return nil
+	}}
+	h := newTestHook()
+	h.chatId = "42"
+	h.client = api.client()
+
+	entry := &log.Entry{Level: log.ErrorLevel, Message: "checkout broken", Data: log.Fields{
+		"user": "bob",
+		AttachmentsKey: []Attachment{
+			{Name: "app.log", Content: []byte("panic: nil map")},
+			{Name: "config.yaml", Content: []byte("replicas: 3")},
+		},
+	}}
+	if err := h.Fire(entry); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := api.methods(); !reflect.DeepEqual(got, []string{"sendMediaGroup"}) {
+		t.Fatalf("Expected a single media group, got calls %v", got)
+	}
+	body := string(api.calls[0].body)
+	for _, want := range []string{
+		`"type":"document","media":"attach://file0","caption":"\u003cb\u003eERROR\u003c/b\u003e@testing - checkout broken`,
+		`"media":"attach://file1"}`,
+		`filename="app.log"`, "panic: nil map", `filename="config.yaml"`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected the request to contain %q, got %s", want, body)
+		}
+	}
+	if strings.Contains(body, AttachmentsKey) {
+		t.Errorf("Expected the attachments field not to be shown, got %s", body)
+	}
+	if alerts := h.RecentAlerts(AlertFilter{}); len(alerts) != 1 || !reflect.DeepEqual(alerts[0].MessageIds, []int64{7, 8}) {
+		t.Errorf("Expected the messages of the group to be tracked, got %+v", alerts)
+	}
+}
+
+func TestAttachmentsSinglePhotoLongMessage(t *testing.T) {
+	api := &fakeAPI{}
+	h := newTestHook()
+	h.chatId = "42"
+	h.client = api.client()
+
+	entry := &log.Entry{Level: log.ErrorLevel, Message: strings.Repeat("x", maxCaptionLength), Data: log.Fields{
+		AttachmentsKey: Attachment{Name: "screen.png", Content: []byte("png")},
+	}}
+	if err := h.Fire(entry); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := api.methods(); !reflect.DeepEqual(got, []string{"sendMessage", "sendPhoto"}) {
+		t.Fatalf("Expected the message followed by the photo, got calls %v", got)
+	}
+	photo := api.calls[1].body
+	if bytes.Contains(photo, []byte(`name="caption"`)) || !bytes.Contains(photo, []byte(`{"message_id":1}`)) {
+		t.Errorf("Expected the photo without caption as a reply to the message, got %s", photo)
+	}
+}
@@ -0,0 +1,147 @@
+package telegramhook
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/andoma-go/logrus"
+)
+
+// AdaptiveBatching sends messages immediately while traffic is light and
+// combines them during bursts. Zero values use the defaults.
+type AdaptiveBatching struct {
+	// Threshold is the number of messages per minute above which messages are
+	// batched, 20 when zero.
+	Threshold int
+	// Interval is how long messages are collected during a burst, 10 seconds
+	// when zero.
+	Interval time.Duration
+}
+
+func (a *AdaptiveBatching) threshold() int {
+	if a.Threshold > 0 {
+		return a.Threshold
+	}
+	return 20
+}
+
+func (a *AdaptiveBatching) interval() time.Duration {
+	if a.Interval > 0 {
+		return a.Interval
+	}
+	return 10 * time.Second
+}
+
+// batchWindow is the window traffic is measured over for adaptive batching.
+const batchWindow = time.Minute
+
+// maxBatchSize is the size of the collected messages from which on a batch is
+// sent before its interval ends, so a burst does not turn into one huge
+// message that floods the chat at once or is shed from the queue.
+const maxBatchSize = 8 * maxMessageLength
+
+// batchBuffer collects messages that are combined into one message.
+type batchBuffer struct {
+	mu     sync.Mutex
+	recent []time.Time // latest messages within the batch window
+	msgs   []string
+	size   int          // total length of msgs
+	level  logrus.Level // most severe level of the collected messages
+	timer  *time.Timer
+}
+
+// batchable reports whether out can be combined with other messages. Replies,
+// forum topics and fields documents are sent on their own.
+func (out outgoing) batchable() bool {
+	return out.replyTo == 0 && out.topic == "" && out.doc == nil
+}
+
+// batchingEnabled reports whether messages may be combined at all.
+func (c *config) batchingEnabled() bool {
+	return c.batchInterval > 0 || c.batching != nil
+}
+
+// batchMessage collects out for a combined message. With a batch interval all
+// messages are collected; with adaptive batching out counts towards the
+// traffic and is only collected while traffic is above the threshold. It
+// reports whether out was collected; otherwise it should be sent right away.
+func (h *TelegramHook) batchMessage(cfg config, out outgoing) bool {
+	b := &h.batch
+	b.mu.Lock()
+
+	interval := cfg.batchInterval
+	if interval == 0 {
+		// Only the latest threshold+1 messages decide whether the threshold
+		// is exceeded, so older ones are not kept.
+		now := time.Now()
+		b.recent = append(b.recent, now)
+		if n := cfg.batching.threshold() + 1; len(b.recent) > n {
+			b.recent = append(b.recent[:0], b.recent[len(b.recent)-n:]...)
+		}
+		for len(b.recent) > 0 && now.Sub(b.recent[0]) >= batchWindow {
+			b.recent = b.recent[1:]
+		}
+
+		if len(b.recent) <= cfg.batching.threshold() {
+			b.mu.Unlock()
+			return false
+		}
+		interval = cfg.batching.interval()
+	}
+
+	if !out.batchable() {
+		b.mu.Unlock()
+		return false
+	}
+
+	if len(b.msgs) == 0 || out.level < b.level {
+		b.level = out.level
+	}
+	b.msgs = append(b.msgs, out.msg)
+	b.size += len(out.msg)
+	if b.size >= maxBatchSize {
+		msgs, level := b.take()
+		b.mu.Unlock()
+		h.sendBatch(cfg, msgs, level)
+		return true
+	}
+	if b.timer == nil {
+		b.timer = time.AfterFunc(interval, func() {
+			h.flushBatch(cfg)
+		})
+	}
+	b.mu.Unlock()
+	return true
+}
+
+// take returns and resets the collected messages and stops the timer. The
+// caller must hold the lock.
+func (b *batchBuffer) take() ([]string, logrus.Level) {
+	msgs, level := b.msgs, b.level
+	b.msgs, b.size = nil, 0
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	return msgs, level
+}
+
+// flushBatch sends the collected messages as one combined message.
+func (h *TelegramHook) flushBatch(cfg config) {
+	b := &h.batch
+	b.mu.Lock()
+	msgs, level := b.take()
+	b.mu.Unlock()
+
+	h.sendBatch(cfg, msgs, level)
+}
+
+// sendBatch sends msgs as one combined message of the most severe level.
+func (h *TelegramHook) sendBatch(cfg config, msgs []string, level logrus.Level) {
+	if len(msgs) == 0 {
+		return
+	}
+
+	h.post(cfg, outgoing{level: level, msg: strings.Join(msgs, "\n\n")})
+}
@@ -0,0 +1,78 @@
+package telegramhook
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	log "github.com/andoma-go/logrus"
+)
+
+func TestAdaptiveBatching(t *testing.T) {
+	api := &fakeAPI{}
+	h := newTestHook(WithAdaptiveBatching(AdaptiveBatching{Threshold: 2, Interval: 20 * time.Millisecond}))
+	h.client = api.client()
+
+	for _, msg := range []string{"one", "two", "three", "four", "five"} {
+		if err := h.Fire(&log.Entry{Level: log.ErrorLevel, Message: msg}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := len(api.methods()); n != 2 {
+		t.Fatalf("Expected 2 messages below the threshold to be sent right away, got %d", n)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	texts := api.texts()
+	if len(texts) != 3 {
+		t.Fatalf("Expected the burst to be sent as one message, got %d messages", len(texts))
+	}
+	if last := texts[2]; !strings.Contains(last, "ERROR</b>@testing - three") || !strings.Contains(last, "five") {
+		t.Errorf("Unexpected combined message:\n%s", last)
+	}
+}
+
+func TestBatchInterval(t *testing.T) {
+	api := &fakeAPI{}
+	h := newTestHook(WithBatchInterval(20 * time.Millisecond))
+	h.client = api.client()
+
+	long := strings.Repeat("x", 2000)
+	for i := 0; i < 3; i++ {
+		if err := h.Fire(&log.Entry{Level: log.ErrorLevel, Message: long}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := len(api.methods()); n != 0 {
+		t.Fatalf("Expected messages to be collected, got %d sent", n)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	texts := api.texts()
+	if len(texts) != 2 {
+		t.Fatalf("Expected the combined message to be split into 2 parts, got %d", len(texts))
+	}
+	if n := strings.Count(strings.Join(texts, ""), long); n != 3 {
+		t.Errorf("Expected all 3 messages in the combined message, got %d", n)
+	}
+}
+
+func TestBatchSizeLimit(t *testing.T) {
+	api := &fakeAPI{}
+	h := newTestHook(WithAdaptiveBatching(AdaptiveBatching{Threshold: 1, Interval: time.Hour}))
+	h.client = api.client()
+
+	long := strings.Repeat("x", 3000)
+	n := maxBatchSize/len(long) + 2
+	for i := 0; i < n; i++ {
+		if err := h.Fire(&log.Entry{Level: log.ErrorLevel, Message: long}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(api.methods()) < 2 {
+		t.Fatal("Expected a full batch to be sent before its interval ended")
+	}
+	if got := strings.Count(strings.Join(api.texts(), ""), long); got != n {
+		t.Errorf("Expected all %d messages to be sent, got %d", n, got)
+	}
+}
@@ -0,0 +1,70 @@
+package telegramhook
+
+import (
+	"html"
+	"strings"
+
+	"github.com/andoma-go/logrus"
+)
+
+// fieldBlock is a field rendered as a separate titled block after the fields
+// list, for values that need more room than a single line.
+type fieldBlock struct {
+	title string
+	body  string
+	lang  string // language of the code block, empty for plain preformatted text
+}
+
+// html renders the block as Telegram HTML.
+func (b fieldBlock) html() string {
+	body := html.EscapeString(b.body)
+	if b.lang != "" {
+		body = `<code class="language-` + b.lang + `">` + body + "</code>"
+	}
+	return "<b>" + html.EscapeString(b.title) + "</b>\n<pre>" + body + "</pre>"
+}
+
+// blockRenderer renders some fields as blocks and returns the keys it consumed.
+type blockRenderer func(c *config, fields logrus.Fields) ([]fieldBlock, []string)
+
+// blockRenderers are applied in order to the fields of every entry.
+var blockRenderers = []blockRenderer{
+	renderHTTPBlocks,
+	renderSQLBlock,
+	renderPayloadBlocks,
+}
+
+// extractBlocks renders fields that have a block renderer and returns the
+// remaining fields together with the blocks. Fields consumed by a renderer are
+// not passed to the following ones.
+func (c *config) extractBlocks(fields logrus.Fields) (logrus.Fields, []fieldBlock) {
+	var blocks []fieldBlock
+	rest := fields
+	for _, render := range blockRenderers {
+		b, keys := render(c, rest)
+		if len(keys) == 0 {
+			continue
+		}
+		blocks = append(blocks, b...)
+
+		remaining := make(logrus.Fields, len(rest))
+		for k, v := range rest {
+			remaining[k] = v
+		}
+		for _, k := range keys {
+			delete(remaining, k)
+		}
+		rest = remaining
+	}
+
+	return rest, blocks
+}
+
+// renderBlocks joins blocks for appending to a message.
+func renderBlocks(blocks []fieldBlock) string {
+	parts := make([]string, 0, len(blocks))
+	for _, b := range blocks {
+		parts = append(parts, b.html())
+	}
+	return strings.Join(parts, "\n")
+}
@@ -0,0 +1,120 @@
+package telegramhook
+
+import (
+	"sync"
+	"time"
+
+	"github.com/andoma-go/logrus"
+)
+
+// BudgetProvider reports the error budget burn rate of a service, e.g. from an
+// SLO tool. A burn rate of 1 consumes the budget exactly within its window,
+// higher rates exhaust it early.
+type BudgetProvider interface {
+	Query() (burnRate float64, err error)
+}
+
+// ErrorBudget adjusts the level of the hook to the error budget of the service:
+// while the budget burns fast the hook sends one level more, while it is
+// healthy one level less, but never less than Floor.
+type ErrorBudget struct {
+	Provider BudgetProvider
+	// Interval is the minimum time between two queries, 1 minute when zero.
+	Interval time.Duration
+	// HighBurn is the burn rate from which the level is lowered, 2 when zero.
+	HighBurn float64
+	// LowBurn is the burn rate below which the level is raised, 0.5 when zero.
+	LowBurn float64
+	// Floor is the least severe level that is still sent while the budget is
+	// healthy, ErrorLevel when zero, so errors are never muted by a healthy
+	// budget. A configured level more severe than Floor applies unchanged.
+	Floor logrus.Level
+}
+
+// budgetState caches the level shift derived from the last query.
+type budgetState struct {
+	mu       sync.Mutex
+	shift    int
+	queried  time.Time
+	querying bool
+}
+
+// budgetLevel returns the level of cfg shifted by the error budget. The
+// provider is queried in the background once the last result is older than the
+// interval, so Fire never waits for it.
+func (h *TelegramHook) budgetLevel(cfg config) logrus.Level {
+	b := &h.budget
+	b.mu.Lock()
+	if !b.querying && time.Since(b.queried) >= cfg.errorBudget.interval() {
+		b.querying = true
+		go h.queryBudget(cfg)
+	}
+	shift := b.shift
+	b.mu.Unlock()
+
+	level := int(cfg.level) + shift
+	if floor := cfg.errorBudget.floor(); shift < 0 && level < int(floor) {
+		level = int(floor)
+		if cfg.level < floor {
+			level = int(cfg.level)
+		}
+	}
+	if level < int(logrus.PanicLevel) {
+		level = int(logrus.PanicLevel)
+	}
+	if level > int(logrus.TraceLevel) {
+		level = int(logrus.TraceLevel)
+	}
+	return logrus.Level(level)
+}
+
+// queryBudget asks the provider for the burn rate. While the provider fails the
+// configured level applies unchanged.
+func (h *TelegramHook) queryBudget(cfg config) {
+	rate, err := cfg.errorBudget.Provider.Query()
+
+	b := &h.budget
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.querying = false
+	b.queried = time.Now()
+
+	switch {
+	case err != nil:
+		b.shift = 0
+	case rate >= cfg.errorBudget.highBurn():
+		b.shift = 1
+	case rate < cfg.errorBudget.lowBurn():
+		b.shift = -1
+	default:
+		b.shift = 0
+	}
+}
+
+func (b *ErrorBudget) interval() time.Duration {
+	if b.Interval > 0 {
+		return b.Interval
+	}
+	return time.Minute
+}
+
+func (b *ErrorBudget) highBurn() float64 {
+	if b.HighBurn > 0 {
+		return b.HighBurn
+	}
+	return 2
+}
+
+func (b *ErrorBudget) lowBurn() float64 {
+	if b.LowBurn > 0 {
+		return b.LowBurn
+	}
+	return 0.5
+}
+
+func (b *ErrorBudget) floor() logrus.Level {
+	if b.Floor > 0 {
+		return b.Floor
+	}
+	return logrus.ErrorLevel
+}
@@ -0,0 +1,60 @@
+package telegramhook
+
+import (
+	"errors"
+	"testing"
+
+	log "github.com/andoma-go/logrus"
+)
+
+type budgetFunc func() (float64, error)
+
+func (f budgetFunc) Query() (float64, error) {
+	return f()
+}
+
+func TestBudgetLevel(t *testing.T) {
+	tests := []struct {
+		rate  float64
+		err   error
+		level log.Level
+		floor log.Level
+		want  log.Level
+	}{
+		{3, nil, log.ErrorLevel, 0, log.WarnLevel},
+		{1, nil, log.ErrorLevel, 0, log.ErrorLevel},
+		{0.1, nil, log.ErrorLevel, 0, log.ErrorLevel},
+		{0.1, nil, log.WarnLevel, 0, log.ErrorLevel},
+		{0.1, nil, log.ErrorLevel, log.FatalLevel, log.FatalLevel},
+		{0.1, nil, log.FatalLevel, 0, log.FatalLevel},
+		{3, errors.New("unavailable"), log.ErrorLevel, 0, log.ErrorLevel},
+	}
+	for _, tt := range tests {
+		h := newTestHook(WithLevel(tt.level), WithErrorBudget(ErrorBudget{
+			Provider: budgetFunc(func() (float64, error) { return tt.rate, tt.err }),
+			Floor:    tt.floor,
+		}))
+		cfg := h.snapshot()
+		h.queryBudget(cfg)
+
+		if got := h.budgetLevel(cfg); got != tt.want {
+			t.Errorf("Burn rate %v (err %v) at %v: level %v, want %v", tt.rate, tt.err, tt.level, got, tt.want)
+		}
+	}
+}
+
+func TestBudgetFire(t *testing.T) {
+	api := &fakeAPI{}
+	h := newTestHook(WithErrorBudget(ErrorBudget{
+		Provider: budgetFunc(func() (float64, error) { return 5, nil }),
+	}))
+	h.client = api.client()
+	h.queryBudget(h.snapshot())
+
+	if err := h.Fire(&log.Entry{Level: log.WarnLevel, Message: "disk almost full"}); err != nil {
+		t.Fatal(err)
+	}
+	if texts := api.texts(); len(texts) != 1 {
+		t.Errorf("Expected the warning to be sent while the budget burns, got %q", texts)
+	}
+}
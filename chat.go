@@ -0,0 +1,133 @@
+package telegramhook
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/andoma-go/logrus"
+)
+
+// ChatTarget is a destination for messages: a chat, optionally a forum topic
+// in it, and how messages are sent there. A zero ParseMode (ParseModeDefault)
+// and a nil Formatter keep the settings of the hook; every other parse mode,
+// including ParseModeHTML, replaces it. Messages to a target are silent if
+// either the target or the hook is silent.
+type ChatTarget struct {
+	ChatId    string
+	ThreadId  string
+	Silent    bool // send without notification sound
+	ParseMode ParseMode
+	Formatter Formatter
+}
+
+// Chat returns the target for a chat ID or @username.
+func Chat(chatId string) ChatTarget {
+	return ChatTarget{ChatId: chatId}
+}
+
+// GroupTopic returns the target for a forum topic of a supergroup.
+func GroupTopic(chatId, topicId int64) ChatTarget {
+	return ChatTarget{ChatId: strconv.FormatInt(chatId, 10), ThreadId: strconv.FormatInt(topicId, 10)}
+}
+
+// Channel returns the target for a channel, e.g. Channel("@ops").
+func Channel(username string) ChatTarget {
+	return ChatTarget{ChatId: username}
+}
+
+// User returns the target for a private chat with a user who started the bot.
+func User(userId int64) ChatTarget {
+	return ChatTarget{ChatId: strconv.FormatInt(userId, 10)}
+}
+
+// ChatTargetKey is the field that sends a single entry to another ChatTarget,
+// e.g. log.WithField(telegramhook.ChatTargetKey, telegramhook.User(42)). The
+// field itself is not shown.
+const ChatTargetKey = "telegram_chat"
+
+// apply sends messages of c to the target.
+func (t ChatTarget) apply(c *config) {
+	c.chatId, c.threadId = t.ChatId, t.ThreadId
+	c.silent = c.silent || t.Silent
+	if t.ParseMode != ParseModeDefault {
+		c.parseMode = t.ParseMode
+	}
+	if t.Formatter != nil {
+		c.formatter = t.Formatter
+	}
+}
+
+// silentLevel reports whether entries of level are sent without notification
+// sound, see WithSilentLevels.
+func (c *config) silentLevel(level logrus.Level) bool {
+	for _, l := range c.silentLevels {
+		if l == level {
+			return true
+		}
+	}
+	return false
+}
+
+// name identifies the target as "chat" or "chat/thread".
+func (t ChatTarget) name() string {
+	if t.ThreadId != "" {
+		return t.ChatId + "/" + t.ThreadId
+	}
+	return t.ChatId
+}
+
+// target returns the destination of the hook.
+func (c *config) target() ChatTarget {
+	return ChatTarget{
+		ChatId:    c.chatId,
+		ThreadId:  c.threadId,
+		Silent:    c.silent,
+		ParseMode: c.parseMode,
+		Formatter: c.formatter,
+	}
+}
+
+// entryTarget returns the ChatTarget requested by the ChatTargetKey field of
+// entry and entry without that field.
+func entryTarget(entry *logrus.Entry) (*ChatTarget, *logrus.Entry) {
+	t, ok := entry.Data[ChatTargetKey].(ChatTarget)
+	if !ok {
+		return nil, entry
+	}
+
+	stripped := *entry
+	stripped.Data = make(logrus.Fields, len(entry.Data)-1)
+	for k, v := range entry.Data {
+		if k != ChatTargetKey {
+			stripped.Data[k] = v
+		}
+	}
+	return &t, &stripped
+}
+
+// levelRoute returns the target entries of level are routed to, nil if they
+// go to the chat of the hook.
+func (c *config) levelRoute(level logrus.Level) *ChatTarget {
+	t, ok := c.levelRoutes[level]
+	if !ok {
+		return nil
+	}
+	return &t
+}
+
+// fieldRoute returns the target entry is routed to by the value of the routing
+// field, nil if it has no route.
+func (c *config) fieldRoute(entry *logrus.Entry) *ChatTarget {
+	if c.routeField == "" {
+		return nil
+	}
+	v, ok := entry.Data[c.routeField]
+	if !ok {
+		return nil
+	}
+	t, ok := c.fieldRoutes[fmt.Sprint(v)]
+	if !ok {
+		return nil
+	}
+	return &t
+}
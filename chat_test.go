@@ -0,0 +1,186 @@
+package telegramhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	log "github.com/andoma-go/logrus"
+)
+
+func TestChatTargets(t *testing.T) {
+	tests := []struct {
+		target ChatTarget
+		chat   string
+		thread string
+	}{
+		{GroupTopic(-1001234, 42), "-1001234", "42"},
+		{Channel("@ops"), "@ops", ""},
+		{User(42), "42", ""},
+		{Chat("-100"), "-100", ""},
+	}
+	for _, tt := range tests {
+		if tt.target.ChatId != tt.chat || tt.target.ThreadId != tt.thread {
+			t.Errorf("Unexpected target %+v, want chat %q thread %q", tt.target, tt.chat, tt.thread)
+		}
+	}
+}
+
+func TestChatTargetOverride(t *testing.T) {
+	api := &fakeAPI{}
+	h := newTestHook(WithChatTarget(GroupTopic(-100, 7)))
+	h.client = api.client()
+
+	target := User(42)
+	target.Silent = true
+	target.ParseMode = ParseModePlain
+	entry := &log.Entry{Level: log.ErrorLevel, Message: "for you", Data: log.Fields{ChatTargetKey: target, "user": "bob"}}
+	if err := h.Fire(entry); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Fire(&log.Entry{Level: log.ErrorLevel, Message: "for all"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var direct, group apiRequest
+	_ = json.Unmarshal(api.calls[0].body, &direct)
+	_ = json.Unmarshal(api.calls[1].body, &group)
+	if direct.ChatId != "42" || direct.ThreadId != "" || !direct.Silent || direct.ParseMode != "" {
+		t.Errorf("Unexpected request for the overridden target: %+v", direct)
+	}
+	if strings.Contains(direct.Text, ChatTargetKey) || !strings.Contains(direct.Text, "user: bob") {
+		t.Errorf("Expected the target field to be hidden:\n%s", direct.Text)
+	}
+	if group.ChatId != "-100" || group.ThreadId != "7" || group.Silent || group.ParseMode != "HTML" {
+		t.Errorf("Unexpected request for the hook's target: %+v", group)
+	}
+}
+
+func TestChatTargetKeepsSilent(t *testing.T) {
+	api := &fakeAPI{}
+	h := newTestHook(WithSilent(true), WithParseMode(ParseModeMarkdownV2))
+	h.client = api.client()
+
+	entry := &log.Entry{Level: log.ErrorLevel, Message: "m", Data: log.Fields{ChatTargetKey: Chat("42")}}
+	if err := h.Fire(entry); err != nil {
+		t.Fatal(err)
+	}
+
+	var req apiRequest
+	_ = json.Unmarshal(api.calls[0].body, &req)
+	if req.ChatId != "42" || !req.Silent || req.ParseMode != "MarkdownV2" {
+		t.Errorf("Expected the target to keep the hook's settings: %+v", req)
+	}
+}
+
+func TestChatTargetHTMLOnPlainHook(t *testing.T) {
+	api := &fakeAPI{}
+	h := newTestHook(WithParseMode(ParseModePlain))
+	h.client = api.client()
+
+	target := Chat("42")
+	target.ParseMode = ParseModeHTML
+	entry := &log.Entry{Level: log.ErrorLevel, Message: "<b>m</b>", Data: log.Fields{ChatTargetKey: target}}
+	if err := h.Fire(entry); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Fire(&log.Entry{Level: log.ErrorLevel, Message: "m"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var html, plain apiRequest
+	_ = json.Unmarshal(api.calls[0].body, &html)
+	_ = json.Unmarshal(api.calls[1].body, &plain)
+	if html.ChatId != "42" || html.ParseMode != "HTML" {
+		t.Errorf("Expected the target to select HTML: %+v", html)
+	}
+	if plain.ParseMode != "" {
+		t.Errorf("Expected the hook to keep plain text: %+v", plain)
+	}
+}
+
+func TestLevelRouting(t *testing.T) {
+	api := &fakeAPI{}
+	h := newTestHook(
+		WithLevel(log.InfoLevel),
+		WithChatTarget(Chat("-100")),
+		WithLevelRouting(map[log.Level]ChatTarget{
+			log.ErrorLevel: Chat("-200"),
+			log.WarnLevel:  GroupTopic(-300, 4),
+		}),
+	)
+	h.client = api.client()
+
+	for _, level := range []log.Level{log.ErrorLevel, log.WarnLevel, log.InfoLevel} {
+		if err := h.Fire(&log.Entry{Level: level, Message: "m"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var chats []string
+	for _, c := range api.calls {
+		var req apiRequest
+		_ = json.Unmarshal(c.body, &req)
+		chats = append(chats, req.ChatId+"/"+req.ThreadId)
+	}
+	if got := strings.Join(chats, " "); got != "-200/ -300/4 -100/" {
+		t.Errorf("Entries sent to %s", got)
+	}
+}
+
+func TestFieldRouting(t *testing.T) {
+	api := &fakeAPI{}
+	h := newTestHook(
+		WithChatTarget(Chat("-100")),
+		WithFieldRouting("component", map[string]ChatTarget{"payments": GroupTopic(-200, 9)}),
+		WithLevelRouting(map[log.Level]ChatTarget{log.ErrorLevel: Chat("-300")}),
+	)
+	h.client = api.client()
+
+	for _, component := range []string{"payments", "search"} {
+		if err := h.Fire(&log.Entry{Level: log.ErrorLevel, Message: "m", Data: log.Fields{"component": component}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var chats []string
+	for _, c := range api.calls {
+		var req apiRequest
+		_ = json.Unmarshal(c.body, &req)
+		chats = append(chats, req.ChatId+"/"+req.ThreadId)
+	}
+	if got := strings.Join(chats, " "); got != "-200/9 -300/" {
+		t.Errorf("Expected the field route to take precedence over the level route, sent to %s", got)
+	}
+}
+
+func TestSilentLevels(t *testing.T) {
+	api := &fakeAPI{}
+	h := newTestHook(
+		WithLevel(log.DebugLevel),
+		WithChatTarget(Chat("-100")),
+		WithSilentLevels(log.InfoLevel, log.DebugLevel),
+	)
+	h.client = api.client()
+
+	for _, level := range []log.Level{log.ErrorLevel, log.InfoLevel, log.DebugLevel} {
+		if err := h.Fire(&log.Entry{Level: level, Message: "m"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	h.SetSilent(true)
+	if err := h.Fire(&log.Entry{Level: log.ErrorLevel, Message: "m"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var silent []string
+	for _, c := range api.calls {
+		var req apiRequest
+		_ = json.Unmarshal(c.body, &req)
+		silent = append(silent, fmt.Sprint(req.Silent))
+	}
+	if got := strings.Join(silent, " "); got != "false true true true" {
+		t.Errorf("Entries sent silently: %s", got)
+	}
+}
@@ -0,0 +1,57 @@
+// Command telegramhook-decrypt decrypts the fields of alerts sent with
+// WithFieldEncryption. It reads a message copied from Telegram, or a
+// downloaded fields.txt.enc, from standard input and prints it with the
+// encrypted sections replaced by their plaintext.
+//
+// The key is read base64-encoded from the TELEGRAMHOOK_KEY environment
+// variable or from the file given with -key-file.
+package main
+
+import (
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	telegramhook "github.com/andoma-go/logrus-hook-telegram"
+)
+
+func main() {
+	keyFile := flag.String("key-file", "", "file with the base64-encoded key")
+	flag.Parse()
+
+	if err := run(*keyFile, os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "telegramhook-decrypt:", err)
+		os.Exit(1)
+	}
+}
+
+func run(keyFile string, in io.Reader, out io.Writer) error {
+	encoded := os.Getenv("TELEGRAMHOOK_KEY")
+	if keyFile != "" {
+		b, err := os.ReadFile(keyFile)
+		if err != nil {
+			return err
+		}
+		encoded = string(b)
+	}
+	if encoded == "" {
+		return fmt.Errorf("no key, set TELEGRAMHOOK_KEY or use -key-file")
+	}
+
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return fmt.Errorf("key is not base64: %w", err)
+	}
+
+	text, err := io.ReadAll(in)
+	if err != nil {
+		return err
+	}
+
+	plaintext, err := telegramhook.Decrypt(key, string(text))
+	fmt.Fprint(out, plaintext)
+	return err
+}
@@ -0,0 +1,120 @@
+package telegramhook
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Coalescing sends the first entry of a burst right away and, once the burst
+// ends, the latest entry with the number of entries coalesced. Entries belong
+// to the same burst when they share a signature. Zero values use the defaults.
+type Coalescing struct {
+	// Quiet is how long no entry of a burst must arrive for it to end, 30
+	// seconds when zero.
+	Quiet time.Duration
+	// MaxWait is how long a burst lasts at most before the trailing message is
+	// sent, 10 minutes when zero. The next entry starts a new burst.
+	MaxWait time.Duration
+}
+
+func (c *Coalescing) quiet() time.Duration {
+	if c.Quiet > 0 {
+		return c.Quiet
+	}
+	return 30 * time.Second
+}
+
+func (c *Coalescing) maxWait() time.Duration {
+	if c.MaxWait > 0 {
+		return c.MaxWait
+	}
+	return 10 * time.Minute
+}
+
+// bursts tracks the ongoing bursts by signature, see WithCoalescing.
+type bursts struct {
+	mu     sync.Mutex
+	active map[string]*burst
+}
+
+// burst is an ongoing burst and the latest entry coalesced into it.
+type burst struct {
+	cfg     config
+	started time.Time
+	count   int
+	latest  outgoing
+	timer   *time.Timer
+}
+
+// coalesced reports whether out continues a burst and is held back for the
+// trailing message. Otherwise out starts a new burst and is sent.
+func (h *TelegramHook) coalesced(cfg config, out outgoing) bool {
+	b := &h.bursts
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	if cur, ok := b.active[out.signature]; ok {
+		cur.cfg, cur.latest = cfg, out
+		cur.count++
+		delay := cfg.coalesce.quiet()
+		if rest := cur.started.Add(cfg.coalesce.maxWait()).Sub(now); rest < delay {
+			delay = rest
+		}
+		cur.timer.Reset(delay)
+		return true
+	}
+
+	if b.active == nil {
+		b.active = map[string]*burst{}
+	}
+	cur := &burst{cfg: cfg, started: now}
+	cur.timer = time.AfterFunc(cfg.coalesce.quiet(), func() { h.endBurst(out.signature, cur) })
+	b.active[out.signature] = cur
+	return false
+}
+
+// endBurst ends the burst cur of signature and sends its trailing message. It
+// does nothing if cur already ended, e.g. when its timer was reset just as it
+// fired.
+func (h *TelegramHook) endBurst(signature string, cur *burst) {
+	b := &h.bursts
+	b.mu.Lock()
+	ok := b.active[signature] == cur
+	if ok {
+		delete(b.active, signature)
+	}
+	b.mu.Unlock()
+
+	if ok {
+		h.sendTrailing(cur)
+	}
+}
+
+// flushBursts ends all bursts, sending their trailing messages.
+func (h *TelegramHook) flushBursts() {
+	b := &h.bursts
+	b.mu.Lock()
+	active := b.active
+	b.active = nil
+	b.mu.Unlock()
+
+	for _, cur := range active {
+		cur.timer.Stop()
+		h.sendTrailing(cur)
+	}
+}
+
+// sendTrailing sends the latest entry of a burst noting how many entries it
+// stands for, nothing if the burst had only its first entry.
+func (h *TelegramHook) sendTrailing(cur *burst) {
+	if cur.count == 0 {
+		return
+	}
+
+	out := cur.latest
+	out.msg += fmt.Sprintf("\n<i>latest of %d more like this in %s</i>",
+		cur.count, time.Since(cur.started).Round(time.Second))
+	h.post(cur.cfg, out)
+}
@@ -0,0 +1,60 @@
+package telegramhook
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	log "github.com/andoma-go/logrus"
+)
+
+func TestCoalescing(t *testing.T) {
+	api := &fakeAPI{}
+	h := newTestHook(WithCoalescing(Coalescing{Quiet: 40 * time.Millisecond}), WithSignatureNormalizers(NormalizeNumbers))
+	h.client = api.client()
+
+	for _, msg := range []string{"timeout after 10ms", "timeout after 12ms", "disk full", "timeout after 31ms"} {
+		if err := h.Fire(&log.Entry{Level: log.ErrorLevel, Message: msg}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	texts := api.texts()
+	if len(texts) != 2 || !strings.Contains(texts[0], "10ms") || !strings.Contains(texts[1], "disk full") {
+		t.Fatalf("Expected the first entry of each burst right away, got %q", texts)
+	}
+
+	time.Sleep(120 * time.Millisecond)
+	texts = api.texts()
+	if len(texts) != 3 {
+		t.Fatalf("Expected a single trailing message, got %q", texts)
+	}
+	if !strings.HasPrefix(texts[2], "<b>ERROR</b>@testing - timeout after 31ms\n<i>latest of 2 more like this in ") {
+		t.Errorf("Unexpected trailing message %q", texts[2])
+	}
+
+	if err := h.Fire(&log.Entry{Level: log.ErrorLevel, Message: "timeout after 50ms"}); err != nil {
+		t.Fatal(err)
+	}
+	if texts := api.texts(); len(texts) != 4 || !strings.Contains(texts[3], "50ms") {
+		t.Errorf("Expected a new burst to start after the quiet period, got %q", texts)
+	}
+}
+
+func TestCoalescingFlush(t *testing.T) {
+	api := &fakeAPI{}
+	h := newTestHook(WithCoalescing(Coalescing{}))
+	h.client = api.client()
+
+	for i := 0; i < 3; i++ {
+		if err := h.Fire(&log.Entry{Level: log.ErrorLevel, Message: "loop"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := h.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if texts := api.texts(); len(texts) != 2 || !strings.Contains(texts[1], "latest of 2 more") {
+		t.Errorf("Expected Flush to send the trailing message, got %q", texts)
+	}
+}
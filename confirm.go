@@ -0,0 +1,81 @@
+package telegramhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// scheduleConfirm checks after the confirmation delay that the messages with
+// ids exist in the chat and sends out again once if one is missing. Messages
+// the hook deleted itself, e.g. with Retract, are not sent again.
+func (h *TelegramHook) scheduleConfirm(cfg config, out outgoing, ids []int64) {
+	time.AfterFunc(cfg.confirmDelay, func() {
+		select {
+		case <-h.done:
+			return
+		default:
+		}
+
+		// Close cancels a confirmation in progress
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go func() {
+			select {
+			case <-h.done:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+
+		for _, id := range ids {
+			if ctx.Err() != nil || h.sent.wasDeleted(cfg.chatId, id) {
+				return
+			}
+			exists, err := h.messageExists(ctx, cfg, id)
+			if err != nil {
+				if ctx.Err() == nil {
+					h.handleError(fmt.Errorf("confirm message %d: %w", id, err))
+				}
+				return
+			}
+			if exists || h.sent.wasDeleted(cfg.chatId, id) {
+				continue
+			}
+
+			h.handleError(fmt.Errorf("message %d missing in chat %s, sending it again", id, cfg.chatId))
+			cfg.confirmDelay = 0
+			out.spoolFile = ""
+			if err := h.deliver(ctx, cfg, out); err != nil && ctx.Err() == nil {
+				h.handleError(err)
+			}
+			return
+		}
+	})
+}
+
+// messageExists reports whether the message with id exists in the chat. The
+// Bot API cannot fetch a message, so its (empty) reply markup is replaced by
+// an empty one: Telegram answers that the message is not modified if it
+// exists and that it was not found otherwise.
+func (h *TelegramHook) messageExists(ctx context.Context, cfg config, id int64) (bool, error) {
+	_, err := h.callJSONContext(ctx, cfg, "editMessageReplyMarkup", editMarkupRequest{
+		ChatId:      cfg.chatId,
+		MessageId:   id,
+		ReplyMarkup: &inlineKeyboard{InlineKeyboard: [][]inlineButton{}},
+	})
+
+	var apiErr *APIError
+	switch {
+	case err == nil:
+		return true, nil
+	case errors.As(err, &apiErr) && strings.Contains(apiErr.Description, "message is not modified"):
+		return true, nil
+	case errors.As(err, &apiErr) && (strings.Contains(apiErr.Description, "message to edit not found") ||
+		strings.Contains(apiErr.Description, "MESSAGE_ID_INVALID")):
+		return false, nil
+	}
+	return false, err
+}
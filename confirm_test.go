@@ -0,0 +1,73 @@
+package telegramhook
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	log "github.com/andoma-go/logrus"
+)
+
+func TestDeliveryConfirmation(t *testing.T) {
+	api := &fakeAPI{respond: func(method string, body []byte) *http.Response {
+		if method != "editMessageReplyMarkup" {
+			return nil
+		}
+		return jsonResponse(http.StatusBadRequest, `{"ok":false,"error_code":400,"description":"Bad Request: message to edit not found"}`)
+	}}
+	h := newTestHook(WithDeliveryConfirmation(10 * time.Millisecond))
+	h.client = api.client()
+
+	if err := h.Fire(&log.Entry{Level: log.ErrorLevel, Message: "lost"}); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	// The message is sent again once, without another confirmation.
+	want := "sendMessage editMessageReplyMarkup sendMessage"
+	if got := strings.Join(api.methods(), " "); got != want {
+		t.Errorf("Got requests %q, want %q", got, want)
+	}
+}
+
+func TestDeliveryConfirmationRetracted(t *testing.T) {
+	api := &fakeAPI{respond: func(method string, body []byte) *http.Response {
+		if method != "editMessageReplyMarkup" {
+			return nil
+		}
+		return jsonResponse(http.StatusBadRequest, `{"ok":false,"error_code":400,"description":"Bad Request: message to edit not found"}`)
+	}}
+	h := newTestHook(WithDeliveryConfirmation(10 * time.Millisecond))
+	h.client = api.client()
+
+	if err := h.Fire(&log.Entry{Level: log.ErrorLevel, Message: "false alarm", Data: log.Fields{CorrelationKey: "k"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Retract("k"); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if got, want := strings.Join(api.methods(), " "), "sendMessage deleteMessage"; got != want {
+		t.Errorf("Got requests %q, want %q", got, want)
+	}
+}
+
+func TestMessageExists(t *testing.T) {
+	for description, want := range map[string]bool{
+		"Bad Request: message is not modified: specified new message content and reply markup are exactly the same": true,
+		"Bad Request: message to edit not found": false,
+	} {
+		api := &fakeAPI{respond: func(string, []byte) *http.Response {
+			return jsonResponse(http.StatusBadRequest, `{"ok":false,"error_code":400,"description":"`+description+`"}`)
+		}}
+		h := newTestHook()
+		h.client = api.client()
+
+		if got, err := h.messageExists(context.Background(), h.snapshot(), 1); err != nil || got != want {
+			t.Errorf("messageExists() = %v, %v for %q, want %v", got, err, description, want)
+		}
+	}
+}
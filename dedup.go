@@ -0,0 +1,102 @@
+package telegramhook
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/andoma-go/logrus"
+)
+
+// dedupWindow counts repeats of messages sent recently, see WithDedupWindow.
+type dedupWindow struct {
+	mu   sync.Mutex
+	seen map[uint64]*repeatedMessage
+}
+
+// repeatedMessage is a message sent within the dedup window and how often it
+// was suppressed since.
+type repeatedMessage struct {
+	cfg      config
+	level    logrus.Level
+	headline string
+	repeats  int
+	timer    *time.Timer
+}
+
+// dedupKey hashes msg together with the chat it is sent to.
+func dedupKey(cfg config, msg string) uint64 {
+	f := fnv.New64a()
+	f.Write([]byte(cfg.chatId + "/" + cfg.threadId + "\x00" + msg))
+	return f.Sum64()
+}
+
+// repeated reports whether msg was sent to the same chat within the dedup
+// window, counting it for the summary in that case. Otherwise msg opens a new
+// window.
+func (h *TelegramHook) repeated(cfg config, level logrus.Level, msg string) bool {
+	d := &h.dedup
+	key := dedupKey(cfg, msg)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if r, ok := d.seen[key]; ok {
+		r.repeats++
+		return true
+	}
+
+	if d.seen == nil {
+		d.seen = map[uint64]*repeatedMessage{}
+	}
+	headline, _, _ := strings.Cut(msg, "\n")
+	d.seen[key] = &repeatedMessage{
+		cfg:      cfg,
+		level:    level,
+		headline: stripTags(headline),
+		timer:    time.AfterFunc(cfg.dedupWindow, func() { h.closeDedup(key) }),
+	}
+	return false
+}
+
+// closeDedup ends the window of the message with key and sends a summary if
+// it was repeated.
+func (h *TelegramHook) closeDedup(key uint64) {
+	d := &h.dedup
+	d.mu.Lock()
+	r, ok := d.seen[key]
+	delete(d.seen, key)
+	d.mu.Unlock()
+
+	if ok {
+		h.sendRepeats(r)
+	}
+}
+
+// flushDedup ends all windows, sending the summaries of repeated messages.
+func (h *TelegramHook) flushDedup() {
+	d := &h.dedup
+	d.mu.Lock()
+	seen := d.seen
+	d.seen = nil
+	d.mu.Unlock()
+
+	for _, r := range seen {
+		r.timer.Stop()
+		h.sendRepeats(r)
+	}
+}
+
+// sendRepeats sends how often r was repeated, nothing if it was not.
+func (h *TelegramHook) sendRepeats(r *repeatedMessage) {
+	if r.repeats == 0 {
+		return
+	}
+
+	h.post(r.cfg, outgoing{
+		level: r.level,
+		msg:   fmt.Sprintf("<b>REPEATED</b> %d times in %s: %s", r.repeats, r.cfg.dedupWindow, r.headline),
+	})
+}
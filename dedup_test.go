@@ -0,0 +1,40 @@
+package telegramhook
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	log "github.com/andoma-go/logrus"
+)
+
+func TestDedupWindow(t *testing.T) {
+	api := &fakeAPI{}
+	h := newTestHook(WithDedupWindow(30 * time.Millisecond))
+	h.client = api.client()
+
+	for _, msg := range []string{"loop", "loop", "other", "loop"} {
+		if err := h.Fire(&log.Entry{Level: log.ErrorLevel, Message: msg}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := len(api.texts()); n != 2 {
+		t.Fatalf("Expected repeats to be suppressed, got %d messages", n)
+	}
+
+	time.Sleep(80 * time.Millisecond)
+	texts := api.texts()
+	if len(texts) != 3 {
+		t.Fatalf("Expected a single summary, got %d messages", len(texts))
+	}
+	if want := "<b>REPEATED</b> 2 times in 30ms: ERROR@testing - loop"; texts[2] != want {
+		t.Errorf("Unexpected summary %q, want %q", texts[2], want)
+	}
+
+	if err := h.Fire(&log.Entry{Level: log.ErrorLevel, Message: "loop"}); err != nil {
+		t.Fatal(err)
+	}
+	if texts := api.texts(); len(texts) != 4 || !strings.Contains(texts[3], "loop") {
+		t.Errorf("Expected the message to be sent again after the window, got %d messages", len(texts))
+	}
+}
@@ -0,0 +1,39 @@
+package telegramhook
+
+import (
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// TestCoreDependencies keeps the core package free of dependencies other than
+// the standard library and logrus. Integrations belong in separate modules.
+func TestCoreDependencies(t *testing.T) {
+	files, err := filepath.Glob("*.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fset := token.NewFileSet()
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+
+		f, err := parser.ParseFile(fset, file, nil, parser.ImportsOnly)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, imp := range f.Imports {
+			path, _ := strconv.Unquote(imp.Path.Value)
+			if path == "github.com/andoma-go/logrus" || !strings.Contains(strings.Split(path, "/")[0], ".") {
+				continue
+			}
+			t.Errorf("%s imports %s, the core package may only depend on the standard library and logrus", file, path)
+		}
+	}
+}
@@ -0,0 +1,293 @@
+package telegramhook
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// EffectiveConfig is a snapshot of the configuration a hook is running with,
+// e.g. for display on an admin or debug endpoint. The bot token is redacted.
+// Durations are formatted like "30s" and zero values mean the feature is off
+// or its default applies.
+type EffectiveConfig struct {
+	AppName      string            `json:"app_name"`
+	Token        string            `json:"token"`
+	ChatId       string            `json:"chat_id"`
+	ThreadId     string            `json:"thread_id,omitempty"`
+	Chats        []string          `json:"chats,omitempty"`
+	LevelRouting map[string]string `json:"level_routing,omitempty"`
+	FieldRouting map[string]string `json:"field_routing,omitempty"`
+	Silent       bool              `json:"silent"`
+	SilentLevels []string          `json:"silent_levels,omitempty"`
+	Level        string            `json:"level"`
+
+	LevelProvider    bool `json:"level_provider"`
+	SkipVerification bool `json:"skip_verification"`
+
+	AlarmBot      string `json:"alarm_bot,omitempty"`
+	AlarmBotLevel string `json:"alarm_bot_level,omitempty"`
+
+	SkipEmpty            bool                     `json:"skip_empty"`
+	HeadlineFields       int                      `json:"headline_fields"`
+	ErrorKeyPromotion    bool                     `json:"error_key_promotion"`
+	FieldsTable          *TableLayout             `json:"fields_table,omitempty"`
+	MaxFields            int                      `json:"max_fields"`
+	FieldOrder           []string                 `json:"field_order,omitempty"`
+	HiddenFields         []string                 `json:"hidden_fields,omitempty"`
+	FieldsDocument       bool                     `json:"fields_document"`
+	Humanize             bool                     `json:"humanize"`
+	RelativeTimes        bool                     `json:"relative_times"`
+	Caller               bool                     `json:"caller"`
+	LevelLabels          map[string]string        `json:"level_labels,omitempty"`
+	Timestamp            string                   `json:"timestamp,omitempty"`
+	Locale               string                   `json:"locale,omitempty"`
+	Timezone             string                   `json:"timezone,omitempty"`
+	ProcessInfo          bool                     `json:"process_info"`
+	Hostname             bool                     `json:"hostname"`
+	StaticFields         []string                 `json:"static_fields,omitempty"`
+	MaxEntrySize         int                      `json:"max_entry_size"`
+	HTTPBodyLimit        int                      `json:"http_body_limit"`
+	SignatureNormalizers int                      `json:"signature_normalizers"`
+	SignatureHandlers    int                      `json:"signature_handlers"`
+	FieldEncryption      bool                     `json:"field_encryption"`
+	RedactedFields       []string                 `json:"redacted_fields,omitempty"`
+	RedactionPatterns    []string                 `json:"redaction_patterns,omitempty"`
+	Formatter            string                   `json:"formatter,omitempty"`
+	ParseMode            string                   `json:"parse_mode"`
+	RawHTML              bool                     `json:"raw_html"`
+	ControlChars         string                   `json:"control_chars"`
+	CommonNoiseFilters   bool                     `json:"common_noise_filters"`
+	ChatProfiles         map[string]RenderProfile `json:"chat_profiles,omitempty"`
+
+	Async            bool              `json:"async"`
+	SoftFail         bool              `json:"soft_fail"`
+	UserAgent        string            `json:"user_agent"`
+	Timeout          string            `json:"timeout"`
+	ApiEndpoints     []string          `json:"api_endpoints"`
+	FailoverCooldown string            `json:"failover_cooldown"`
+	DNSCache         string            `json:"dns_cache"`
+	IPPreference     string            `json:"ip_preference"`
+	LocalAddr        string            `json:"local_addr,omitempty"`
+	MaxQueueBytes    int               `json:"max_queue_bytes"`
+	QueueSize        int               `json:"queue_size"`
+	QueuePolicy      string            `json:"queue_policy"`
+	Workers          int               `json:"workers"`
+	ExitFlush        string            `json:"exit_flush"`
+	DeliveryFooter   bool              `json:"delivery_footer"`
+	Confirmation     string            `json:"delivery_confirmation"`
+	RetryAttempts    int               `json:"retry_attempts"`
+	RetryDelay       string            `json:"retry_delay"`
+	Jitter           string            `json:"jitter"`
+	RateLimit        *RateLimit        `json:"rate_limit,omitempty"`
+	SharedRateLimit  bool              `json:"shared_rate_limit"`
+	LimitWarnings    float64           `json:"limit_warnings,omitempty"`
+	AdaptiveBatching *AdaptiveBatching `json:"adaptive_batching,omitempty"`
+	FaultInjection   *FaultInjection   `json:"fault_injection,omitempty"`
+	PressureGate     *PressureGate     `json:"pressure_gate,omitempty"`
+	FloodSummary     *FloodSummary     `json:"flood_summary,omitempty"`
+	Coalescing       *Coalescing       `json:"coalescing,omitempty"`
+	BatchInterval    string            `json:"batch_interval"`
+	DedupWindow      string            `json:"dedup_window"`
+
+	FirehoseUntil       *time.Time `json:"firehose_until,omitempty"`
+	FirehoseCommand     bool       `json:"firehose_command"`
+	LivePanel           *LivePanel `json:"live_panel,omitempty"`
+	ErrorBudget         bool       `json:"error_budget"`
+	SystemdWatchdog     string     `json:"systemd_watchdog"`
+	AutoTopics          string     `json:"auto_topics"`
+	TopicArchive        string     `json:"topic_archive"`
+	StartupAnnouncement bool       `json:"startup_announcement"`
+	ConfigEcho          bool       `json:"config_echo"`
+	Outbox              bool       `json:"outbox"`
+	Archive             bool       `json:"archive"`
+	Fallback            bool       `json:"fallback"`
+	FallbackHook        bool       `json:"fallback_hook"`
+	SpoolDir            string     `json:"spool_dir,omitempty"`
+	ReplayRate          int        `json:"replay_rate,omitempty"`
+}
+
+// Config returns a redacted snapshot of the effective configuration.
+func (h *TelegramHook) Config() EffectiveConfig {
+	c := h.snapshot()
+
+	ec := EffectiveConfig{
+		AppName:  c.appName,
+		Token:    redactToken(c.authToken),
+		ChatId:   c.chatId,
+		ThreadId: c.threadId,
+		Silent:   c.silent,
+		Level:    c.level.String(),
+
+		LevelProvider:    c.levelProvider != nil,
+		SkipVerification: c.skipVerification,
+
+		SkipEmpty:            c.skipEmpty,
+		HeadlineFields:       c.headlineFields,
+		ErrorKeyPromotion:    c.promoteErrorKey,
+		MaxFields:            c.maxFields,
+		FieldOrder:           append([]string(nil), c.fieldOrder...),
+		HiddenFields:         append([]string(nil), c.hiddenFields...),
+		FieldsDocument:       c.fieldsDocument,
+		Humanize:             c.humanize,
+		RelativeTimes:        c.relativeTimes,
+		Caller:               c.caller,
+		Timestamp:            c.timeLayout,
+		Locale:               c.localeTag,
+		ProcessInfo:          c.processInfo,
+		Hostname:             c.hostname,
+		MaxEntrySize:         c.maxEntrySize,
+		HTTPBodyLimit:        c.httpBodyLimit,
+		SignatureNormalizers: len(c.normalizers),
+		SignatureHandlers:    len(c.handlers),
+		FieldEncryption:      c.encryptionKey != nil,
+		CommonNoiseFilters:   c.noiseFilters,
+		ParseMode:            c.parseMode.String(),
+		RawHTML:              c.rawHTML,
+		ControlChars:         c.controlChars.String(),
+
+		Async:            c.async,
+		SoftFail:         c.softFail,
+		UserAgent:        c.userAgent(),
+		ApiEndpoints:     append([]string(nil), c.apiBaseURLs()...),
+		FailoverCooldown: c.failoverCooldown.String(),
+		DNSCache:         c.dnsRefresh.String(),
+		IPPreference:     c.ipPreference.String(),
+		LocalAddr:        c.localAddr,
+		MaxQueueBytes:    c.maxQueueBytes,
+		QueueSize:        c.queueSize,
+		QueuePolicy:      c.queuePolicy.String(),
+		Workers:          c.workers,
+		ExitFlush:        c.exitFlush.String(),
+		BatchInterval:    c.batchInterval.String(),
+		DedupWindow:      c.dedupWindow.String(),
+		DeliveryFooter:   c.deliveryFooter,
+		Confirmation:     c.confirmDelay.String(),
+		RetryAttempts:    c.retryAttempts,
+		RetryDelay:       c.retryDelay.String(),
+		Jitter:           c.jitter.String(),
+		SharedRateLimit:  c.sharedRateLimit,
+
+		FirehoseCommand:     c.firehoseCommand,
+		ErrorBudget:         c.errorBudget != nil,
+		SystemdWatchdog:     c.watchdogMaxAge.String(),
+		AutoTopics:          c.autoTopics.String(),
+		TopicArchive:        c.topicArchive.String(),
+		StartupAnnouncement: c.startup,
+		ConfigEcho:          c.configEcho,
+		Outbox:              c.outbox != nil,
+		Archive:             c.archive != nil,
+		Fallback:            c.fallbackWriter != nil,
+		FallbackHook:        c.fallbackHook != nil,
+		SpoolDir:            c.spoolDir,
+		ReplayRate:          c.replayRate,
+	}
+
+	// Copies keep callers from modifying the configuration of the hook.
+	if c.table != nil {
+		table := *c.table
+		ec.FieldsTable = &table
+	}
+	if c.formatter != nil {
+		ec.Formatter = fmt.Sprintf("%T", c.formatter)
+	}
+	if c.alarmToken != "" {
+		ec.AlarmBot = redactToken(c.alarmToken)
+		ec.AlarmBotLevel = c.alarmLevel.String()
+	}
+	for _, t := range c.chats {
+		ec.Chats = append(ec.Chats, t.name())
+	}
+	if c.routeField != "" {
+		ec.FieldRouting = make(map[string]string, len(c.fieldRoutes))
+		for value, t := range c.fieldRoutes {
+			ec.FieldRouting[c.routeField+"="+value] = t.name()
+		}
+	}
+	for _, level := range c.silentLevels {
+		ec.SilentLevels = append(ec.SilentLevels, level.String())
+	}
+	ec.RedactedFields = append([]string(nil), c.redactKeys...)
+	for _, p := range c.redactPatterns {
+		ec.RedactionPatterns = append(ec.RedactionPatterns, p.String())
+	}
+	for k := range c.staticFields {
+		ec.StaticFields = append(ec.StaticFields, k)
+	}
+	sort.Strings(ec.StaticFields)
+	if len(c.levelLabels) > 0 {
+		ec.LevelLabels = make(map[string]string, len(c.levelLabels))
+		for level, label := range c.levelLabels {
+			ec.LevelLabels[level.String()] = label
+		}
+	}
+	if len(c.levelRoutes) > 0 {
+		ec.LevelRouting = make(map[string]string, len(c.levelRoutes))
+		for level, t := range c.levelRoutes {
+			ec.LevelRouting[level.String()] = t.name()
+		}
+	}
+	if c.limitWarnings != nil {
+		ec.LimitWarnings = c.limitWarnings.threshold()
+	}
+	if c.timezone != nil {
+		ec.Timezone = c.timezone.String()
+	}
+	if c.flood != nil {
+		flood := *c.flood
+		ec.FloodSummary = &flood
+	}
+	if c.coalesce != nil {
+		coalescing := *c.coalesce
+		ec.Coalescing = &coalescing
+	}
+	if c.gate != nil {
+		gate := *c.gate
+		ec.PressureGate = &gate
+	}
+	if c.faults != nil {
+		faults := *c.faults
+		faults.Endpoints = append([]string(nil), c.faults.Endpoints...)
+		ec.FaultInjection = &faults
+	}
+	if c.batching != nil {
+		batching := *c.batching
+		ec.AdaptiveBatching = &batching
+	}
+	if c.rateLimit != nil {
+		limit := *c.rateLimit
+		ec.RateLimit = &limit
+	}
+	if c.panel != nil {
+		panel := *c.panel
+		ec.LivePanel = &panel
+	}
+	if len(c.profiles) > 0 {
+		ec.ChatProfiles = make(map[string]RenderProfile, len(c.profiles))
+		for k, v := range c.profiles {
+			ec.ChatProfiles[k] = v
+		}
+	}
+
+	if h.client != nil {
+		ec.Timeout = h.client.Timeout.String()
+	}
+	if c.firehoseActive() {
+		until := c.firehoseUntil
+		ec.FirehoseUntil = &until
+	}
+
+	return ec
+}
+
+// redactToken keeps the bot ID of a token, which is public, and hides its secret.
+func redactToken(token string) string {
+	if id, _, ok := strings.Cut(token, ":"); ok {
+		return id + ":***"
+	}
+	if token == "" {
+		return ""
+	}
+	return "***"
+}
@@ -0,0 +1,29 @@
+package telegramhook
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConfig(t *testing.T) {
+	h := newTestHook(WithRetry(3, time.Second), WithAutoTopics(TopicPerApp), WithHumanize(true))
+	h.authToken = "123456:ABC-secret"
+
+	c := h.Config()
+	if c.Token != "123456:***" || c.RetryAttempts != 3 || c.RetryDelay != "1s" || c.AutoTopics != "per-app" || !c.Humanize {
+		t.Errorf("Unexpected config %+v", c)
+	}
+
+	b, err := json.Marshal(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(b), "secret") {
+		t.Errorf("Token leaked into %s", b)
+	}
+	if !strings.Contains(string(b), `"level":"error"`) {
+		t.Errorf("Expected the level in %s", b)
+	}
+}
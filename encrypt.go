@@ -0,0 +1,117 @@
+package telegramhook
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/andoma-go/logrus"
+)
+
+// encryptedPrefix marks an encrypted fields section in a message.
+const encryptedPrefix = "tgenc1:"
+
+// EncryptedKey is the only field Formatters and templates see with
+// WithFieldEncryption. It holds the fields of the entry encrypted.
+const EncryptedKey = "encrypted"
+
+// encryptedDocument is the name of the document encrypted sections are moved
+// to when they do not fit into a single message.
+const encryptedDocument = "encrypted.txt"
+
+// encryptedPattern finds encrypted sections in a message text.
+var encryptedPattern = regexp.MustCompile(regexp.QuoteMeta(encryptedPrefix) + `[A-Za-z0-9+/]+`)
+
+// encrypt seals plaintext with the configured key using AES-GCM and returns
+// it as a prefixed base64 blob.
+func (c *config) encrypt(plaintext string) string {
+	aead, err := newAEAD(c.encryptionKey)
+	if err != nil {
+		// The key is validated when the hook is created.
+		return "[encryption failed: " + err.Error() + "]"
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "[encryption failed: " + err.Error() + "]"
+	}
+
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return encryptedPrefix + base64.RawStdEncoding.EncodeToString(sealed)
+}
+
+// encryptedEntry returns a copy of entry whose fields are replaced by the
+// EncryptedKey field, for Formatters that would render them in plaintext.
+func (c *config) encryptedEntry(entry *logrus.Entry) *logrus.Entry {
+	if len(entry.Data) == 0 {
+		return entry
+	}
+
+	lines := make([]string, 0, len(entry.Data))
+	for _, k := range c.fieldKeys(entry.Data) {
+		lines = append(lines, fmt.Sprintf("%s: %s", k, formatValue(entry.Data[k])))
+	}
+	e := *entry
+	e.Data = logrus.Fields{EncryptedKey: c.encrypt(strings.Join(lines, "\n"))}
+	return &e
+}
+
+// detachEncrypted moves the encrypted sections of msg into a document, ahead of
+// the content of doc, when msg does not fit into a single message: a section
+// split across messages could not be decrypted.
+func (c *config) detachEncrypted(msg string, doc *document) (string, *document) {
+	if c.encryptionKey == nil || len(splitMessage(msg)) < 2 {
+		return msg, doc
+	}
+	sections := encryptedPattern.FindAllString(msg, -1)
+	if len(sections) == 0 {
+		return msg, doc
+	}
+
+	content := strings.Join(sections, "\n")
+	if doc != nil {
+		content += "\n\n" + string(doc.content)
+	}
+	msg = encryptedPattern.ReplaceAllString(msg, "[encrypted, see "+encryptedDocument+"]")
+	return msg, &document{name: encryptedDocument, content: []byte(content)}
+}
+
+// Decrypt replaces the encrypted sections in text, e.g. a message copied from
+// Telegram, with their plaintext.
+func Decrypt(key []byte, text string) (string, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return "", err
+	}
+
+	var decryptErr error
+	result := encryptedPattern.ReplaceAllStringFunc(text, func(blob string) string {
+		sealed, err := base64.RawStdEncoding.DecodeString(strings.TrimPrefix(blob, encryptedPrefix))
+		if err != nil || len(sealed) < aead.NonceSize() {
+			decryptErr = errors.New("malformed encrypted section")
+			return blob
+		}
+
+		plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], nil)
+		if err != nil {
+			decryptErr = errors.New("cannot decrypt section, wrong key?")
+			return blob
+		}
+		return string(plaintext)
+	})
+	return result, decryptErr
+}
+
+// newAEAD returns AES-GCM for a 16, 24 or 32 byte key.
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,87 @@
+package telegramhook
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	log "github.com/andoma-go/logrus"
+)
+
+func TestFieldEncryption(t *testing.T) {
+	key := bytes.Repeat([]byte{7}, 32)
+	msg := createMessage(newTestHook(WithFieldEncryption(key)), &log.Entry{
+		Level:   log.ErrorLevel,
+		Message: "login failed",
+		Data:    log.Fields{"user": "alice", "ip": "10.0.0.1"},
+	})
+
+	headline, blob, ok := strings.Cut(msg, "\n")
+	if !ok || headline != "<b>ERROR</b>@testing - login failed" {
+		t.Fatalf("Expected a plaintext headline, got %q", msg)
+	}
+	if strings.Contains(blob, "alice") || !strings.HasPrefix(blob, "<pre>"+encryptedPrefix) {
+		t.Fatalf("Expected encrypted fields, got %q", blob)
+	}
+
+	plaintext, err := Decrypt(key, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := headline + "\n<pre>\n\tip: 10.0.0.1\n\tuser: alice\n</pre>"; plaintext != want {
+		t.Errorf("Decrypted %q, want %q", plaintext, want)
+	}
+
+	if _, err := Decrypt(bytes.Repeat([]byte{8}, 32), msg); err == nil {
+		t.Error("Expected an error for the wrong key")
+	}
+}
+
+func TestFieldEncryptionLeaks(t *testing.T) {
+	key := bytes.Repeat([]byte{7}, 32)
+	h := newTestHook(WithFieldEncryption(key), WithErrorKeyPromotion(true))
+	entry := &log.Entry{
+		Level: log.ErrorLevel,
+		Data:  log.Fields{log.ErrorKey: errors.New("password hunter2 rejected"), "user": "alice"},
+	}
+
+	msg := createMessage(h, entry)
+	if headline, _, _ := strings.Cut(msg, "\n"); headline != "<b>ERROR</b>@testing - error, user" {
+		t.Errorf("Expected a headline naming the fields, got %q", headline)
+	}
+	entry.Message = "login failed"
+	if msg := createMessage(h, entry); strings.Contains(msg, "hunter2") || strings.Contains(msg, "alice") {
+		t.Errorf("Expected the promoted error to stay encrypted, got %q", msg)
+	}
+
+	var seen log.Fields
+	h = newTestHook(WithFieldEncryption(key), WithFormatter(FormatterFunc(func(e *log.Entry) (string, error) {
+		seen = e.Data
+		return fmt.Sprintf("%s %v", e.Message, e.Data[EncryptedKey]), nil
+	})))
+	cfg := h.snapshot()
+	msg, _ = cfg.renderFor(entry, "")
+	if len(seen) != 1 || !strings.HasPrefix(fmt.Sprint(seen[EncryptedKey]), encryptedPrefix) {
+		t.Errorf("Expected the formatter to see only the encrypted fields, got %v", seen)
+	}
+	if plaintext, err := Decrypt(key, msg); err != nil || plaintext != "login failed error: password hunter2 rejected\nuser: alice" {
+		t.Errorf("Decrypted %q (%v)", plaintext, err)
+	}
+}
+
+func TestFieldEncryptionDocument(t *testing.T) {
+	key := bytes.Repeat([]byte{7}, 32)
+	h := newTestHook(WithFieldEncryption(key))
+	cfg := h.snapshot()
+
+	long := strings.Repeat("x", 4000)
+	msg, doc := cfg.renderFor(&log.Entry{Level: log.ErrorLevel, Message: "dump", Data: log.Fields{"state": long}}, "")
+	if strings.Contains(msg, encryptedPrefix) || doc == nil || doc.name != encryptedDocument {
+		t.Fatalf("Expected the encrypted fields in a document, got %q and %+v", msg, doc)
+	}
+	if plaintext, err := Decrypt(key, string(doc.content)); err != nil || !strings.Contains(plaintext, "state: "+long) {
+		t.Errorf("Unexpected document %q (%v)", plaintext, err)
+	}
+}
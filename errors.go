@@ -0,0 +1,94 @@
+package telegramhook
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/andoma-go/logrus"
+)
+
+// ErrorHandler is called with failures the hook cannot return from Fire, e.g.
+// in async mode, and with failed deliveries in general. entry is the entry
+// whose message failed, nil for failures that do not concern a single entry
+// such as batched messages or summaries.
+type ErrorHandler func(entry *logrus.Entry, err error)
+
+// APIError is an error response received from the Telegram API.
+type APIError struct {
+	Code        int
+	Description string
+	// RetryAfter is how long Telegram asks to wait after a 429 error.
+	RetryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+	msg := "Received error response from Telegram API"
+
+	if e.Code != 0 {
+		msg = fmt.Sprintf("%s (error code %d)", msg, e.Code)
+	}
+
+	if e.Description != "" {
+		msg = fmt.Sprintf("%s: %s", msg, e.Description)
+	}
+
+	return msg
+}
+
+// ConfigError is returned by the constructors when the hook is configured in a
+// way that can never work. Retrying is pointless, the configuration must be fixed.
+type ConfigError struct {
+	Field  string
+	Reason string
+}
+
+func (e *ConfigError) Error() string {
+	return fmt.Sprintf("invalid Telegram hook configuration: %s %s", e.Field, e.Reason)
+}
+
+// NetworkError is returned by the constructors when the Telegram API could not
+// be reached or did not answer properly. It is usually worth retrying later.
+type NetworkError struct {
+	Err error
+}
+
+func (e *NetworkError) Error() string {
+	return fmt.Sprintf("unable to reach Telegram API: %v", e.Err)
+}
+
+func (e *NetworkError) Unwrap() error {
+	return e.Err
+}
+
+// InvalidTokenError is returned by the constructors when Telegram rejected the
+// bot token.
+type InvalidTokenError struct {
+	Err *APIError
+}
+
+func (e *InvalidTokenError) Error() string {
+	return fmt.Sprintf("invalid Telegram bot token: %v", e.Err)
+}
+
+func (e *InvalidTokenError) Unwrap() error {
+	return e.Err
+}
+
+// ChatError is a failed delivery to one of several chats, see WithChats.
+// Errors for several chats are joined, use errors.As to inspect them.
+type ChatError struct {
+	ChatId   string
+	ThreadId string
+	Err      error
+}
+
+func (e *ChatError) Error() string {
+	if e.ThreadId != "" {
+		return fmt.Sprintf("chat %s, thread %s: %v", e.ChatId, e.ThreadId, e.Err)
+	}
+	return fmt.Sprintf("chat %s: %v", e.ChatId, e.Err)
+}
+
+func (e *ChatError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,45 @@
+package telegramhook
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func jsonResponse(status int, body string) *http.Response {
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+	}
+}
+
+func TestConstructorErrors(t *testing.T) {
+	unauthorized := &http.Client{Transport: roundTripFunc(func(*http.Request) (*http.Response, error) {
+		return jsonResponse(401, `{"ok":false,"error_code":401,"description":"Unauthorized"}`), nil
+	})}
+	unreachable := &http.Client{Transport: roundTripFunc(func(*http.Request) (*http.Response, error) {
+		return nil, errors.New("connection refused")
+	})}
+
+	var configErr *ConfigError
+	if _, err := NewTelegramHookWithClient("app", "", "1", "", unauthorized); !errors.As(err, &configErr) {
+		t.Errorf("Expected ConfigError for empty token, got %v", err)
+	}
+
+	var tokenErr *InvalidTokenError
+	if _, err := NewTelegramHookWithClient("app", "123:abc", "1", "", unauthorized); !errors.As(err, &tokenErr) || tokenErr.Err.Code != 401 {
+		t.Errorf("Expected InvalidTokenError, got %v", err)
+	}
+
+	var networkErr *NetworkError
+	if _, err := NewTelegramHookWithClient("app", "123:abc", "1", "", unreachable); !errors.As(err, &networkErr) {
+		t.Errorf("Expected NetworkError, got %v", err)
+	}
+
+	if _, err := NewTelegramHookWithClient("app", "123:abc", "1", "", unreachable, WithSkipVerification(true)); err != nil {
+		t.Errorf("Expected the hook to be constructed without verification, got %v", err)
+	}
+}
@@ -0,0 +1,120 @@
+package telegramhook
+
+import (
+	"sync"
+	"time"
+
+	"github.com/andoma-go/logrus"
+)
+
+// EventType identifies what happened in an Event.
+type EventType int
+
+const (
+	// EventEnqueued is emitted when a message is queued for asynchronous delivery.
+	EventEnqueued EventType = iota
+	// EventSent is emitted when a message was delivered; MessageIds holds the
+	// IDs of its parts.
+	EventSent
+	// EventFailed is emitted when a message could not be delivered.
+	EventFailed
+	// EventRetried is emitted before an API request is retried after the
+	// transient error Err; Attempt is the number of the coming attempt.
+	EventRetried
+	// EventDropped is emitted for each message shed under queue pressure.
+	EventDropped
+	// EventMuted is emitted for entries that are not sent because they are
+	// below the hook's level or empty.
+	EventMuted
+	// EventEdited is emitted when a sent message was edited, e.g. the live
+	// panel, an incident header or an acknowledged alert.
+	EventEdited
+)
+
+func (t EventType) String() string {
+	switch t {
+	case EventEnqueued:
+		return "enqueued"
+	case EventSent:
+		return "sent"
+	case EventFailed:
+		return "failed"
+	case EventRetried:
+		return "retried"
+	case EventDropped:
+		return "dropped"
+	case EventMuted:
+		return "muted"
+	case EventEdited:
+		return "edited"
+	}
+	return "unknown"
+}
+
+// Event describes something the hook did. Fields that do not apply to the
+// type of the event are zero.
+type Event struct {
+	Type       EventType
+	Time       time.Time
+	Level      logrus.Level
+	Key        string  // correlation key, see CorrelationKey
+	MessageIds []int64 // messages sent or edited
+	Attempt    int
+	Err        error
+}
+
+// eventBuffer is the number of events buffered for a slow reader.
+const eventBuffer = 256
+
+// eventStream delivers events to the channel returned by Events.
+type eventStream struct {
+	mu     sync.Mutex
+	ch     chan Event
+	closed bool
+}
+
+// Events returns a channel of events describing what the hook does, for UIs,
+// tests or metrics bridges. Events are emitted from the first call on. The
+// channel is buffered and events are discarded while it is full, so a slow
+// reader never stalls logging. Close closes the channel.
+func (h *TelegramHook) Events() <-chan Event {
+	s := &h.events
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.ch == nil {
+		s.ch = make(chan Event, eventBuffer)
+		if s.closed {
+			close(s.ch)
+		}
+	}
+	return s.ch
+}
+
+// emit sends e to the events channel if anybody asked for it.
+func (h *TelegramHook) emit(e Event) {
+	s := &h.events
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.ch == nil || s.closed {
+		return
+	}
+
+	e.Time = time.Now()
+	select {
+	case s.ch <- e:
+	default:
+	}
+}
+
+// close closes the events channel.
+func (s *eventStream) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.closed && s.ch != nil {
+		close(s.ch)
+	}
+	s.closed = true
+}
@@ -0,0 +1,59 @@
+package telegramhook
+
+import (
+	"testing"
+	"time"
+
+	log "github.com/andoma-go/logrus"
+)
+
+func TestEvents(t *testing.T) {
+	api := &fakeAPI{}
+	h := newTestHook(WithAsync(true))
+	h.client = api.client()
+	events := h.Events()
+
+	if err := h.Fire(&log.Entry{Level: log.InfoLevel, Message: "quiet"}); err != nil {
+		t.Fatal(err)
+	}
+	entry := &log.Entry{Level: log.ErrorLevel, Message: "loud", Data: log.Fields{CorrelationKey: "job-1"}}
+	if err := h.Fire(entry); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []EventType
+	timeout := time.After(time.Second)
+	for len(got) < 3 {
+		select {
+		case e := <-events:
+			got = append(got, e.Type)
+			if e.Type == EventSent && (e.Key != "job-1" || len(e.MessageIds) != 1) {
+				t.Errorf("Unexpected sent event: %+v", e)
+			}
+		case <-timeout:
+			t.Fatalf("Timed out, got events %v", got)
+		}
+	}
+	if got[0] != EventMuted || got[1] != EventEnqueued || got[2] != EventSent {
+		t.Errorf("Unexpected events: %v", got)
+	}
+
+	h.Close()
+	if _, ok := <-events; ok {
+		t.Error("Expected Close to close the events channel")
+	}
+}
+
+func TestEventsWithoutReader(t *testing.T) {
+	h := newTestHook(WithLevel(log.ErrorLevel))
+	events := h.Events()
+
+	for i := 0; i < eventBuffer+10; i++ {
+		if err := h.Fire(&log.Entry{Level: log.InfoLevel, Message: "quiet"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(events) != eventBuffer {
+		t.Errorf("Expected a full buffer of %d events, got %d", eventBuffer, len(events))
+	}
+}
@@ -0,0 +1,44 @@
+package telegramhook
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/andoma-go/logrus"
+)
+
+// fallback hands a message that could not be delivered, even after retries, to
+// the fallback writer and hook, so it is not lost.
+func (h *TelegramHook) fallback(cfg config, out outgoing) {
+	text := html.UnescapeString(stripTags(out.msg))
+
+	if cfg.fallbackWriter != nil {
+		line := fmt.Sprintf("%s chat=%s %s\n", time.Now().Format(time.RFC3339), cfg.chatId,
+			strings.ReplaceAll(text, "\n", "\n\t"))
+
+		h.fallbackMu.Lock()
+		_, err := io.WriteString(cfg.fallbackWriter, line)
+		h.fallbackMu.Unlock()
+		if err != nil {
+			h.handleError(fmt.Errorf("fallback: %w", err))
+		}
+	}
+
+	if hook := cfg.fallbackHook; hook != nil {
+		entry := out.entry
+		if entry == nil {
+			entry = &logrus.Entry{Level: out.level, Time: time.Now(), Message: text, Data: logrus.Fields{}}
+		}
+		for _, level := range hook.Levels() {
+			if level == entry.Level {
+				if err := hook.Fire(entry); err != nil {
+					h.handleError(fmt.Errorf("fallback hook: %w", err))
+				}
+				break
+			}
+		}
+	}
+}
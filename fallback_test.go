@@ -0,0 +1,54 @@
+package telegramhook
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	log "github.com/andoma-go/logrus"
+)
+
+// recordingHook is a logrus hook that records the entries it fires for.
+type recordingHook struct {
+	levels  []log.Level
+	entries []*log.Entry
+}
+
+func (r *recordingHook) Levels() []log.Level { return r.levels }
+
+func (r *recordingHook) Fire(entry *log.Entry) error {
+	r.entries = append(r.entries, entry)
+	return nil
+}
+
+func TestFallback(t *testing.T) {
+	var buf bytes.Buffer
+	other := &recordingHook{levels: []log.Level{log.ErrorLevel}}
+	h := newTestHook(WithFallback(&buf), WithFallbackHook(other), WithChatTarget(Chat("-100")))
+	h.client = &http.Client{Transport: roundTripFunc(func(*http.Request) (*http.Response, error) {
+		return nil, errors.New("unreachable")
+	})}
+
+	entry := &log.Entry{Level: log.ErrorLevel, Message: "disk <full>", Data: log.Fields{"host": "db2"}}
+	if err := h.Fire(entry); err == nil {
+		t.Fatal("Expected the delivery to fail")
+	}
+
+	line := buf.String()
+	if !strings.Contains(line, "chat=-100 ERROR@testing - disk <full>\n\t") || !strings.Contains(line, "host: db2") {
+		t.Errorf("Expected the message as plain text, got %q", line)
+	}
+	if len(other.entries) != 1 || other.entries[0] != entry {
+		t.Errorf("Expected the entry to be forwarded, got %v", other.entries)
+	}
+
+	h.SetLevel(log.WarnLevel)
+	if err := h.Fire(&log.Entry{Level: log.WarnLevel, Message: "slow"}); err == nil {
+		t.Fatal("Expected the delivery to fail")
+	}
+	if len(other.entries) != 1 {
+		t.Errorf("Expected the fallback hook to only get entries of its levels, got %d", len(other.entries))
+	}
+}
@@ -0,0 +1,61 @@
+package telegramhook
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/andoma-go/logrus"
+)
+
+// delivery is a message together with the configuration it is sent with.
+type delivery struct {
+	cfg config
+	out outgoing
+}
+
+// fanOut returns the deliveries of an entry to the chat of the hook, unless it
+// has none, and to every target set with WithChats. Messages are rendered for
+// each target, unless a signature handler rendered out already. Forum topics
+// are only created in the chat of the hook.
+func (h *TelegramHook) fanOut(cfg config, entry *logrus.Entry, out outgoing, handled bool) []delivery {
+	var deliveries []delivery
+	if cfg.chatId != "" {
+		deliveries = append(deliveries, delivery{cfg: cfg, out: out})
+	}
+
+	for _, t := range cfg.chats {
+		dcfg := cfg
+		t.apply(&dcfg)
+
+		dout := out
+		dout.topic = ""
+		if !handled {
+			dout.msg, dout.doc = dcfg.renderFor(entry, dcfg.chatId)
+		}
+		deliveries = append(deliveries, delivery{cfg: dcfg, out: dout})
+	}
+	return deliveries
+}
+
+// deliverAll sends the deliveries concurrently, so a slow or failing chat does
+// not hold up the others. The errors are returned as ChatErrors.
+func (h *TelegramHook) deliverAll(ctx context.Context, deliveries []delivery) error {
+	if len(deliveries) == 1 {
+		return h.deliver(ctx, deliveries[0].cfg, deliveries[0].out)
+	}
+
+	errs := make([]error, len(deliveries))
+	var wg sync.WaitGroup
+	for i, d := range deliveries {
+		wg.Add(1)
+		go func(i int, d delivery) {
+			defer wg.Done()
+			if err := h.deliver(ctx, d.cfg, d.out); err != nil {
+				errs[i] = &ChatError{ChatId: d.cfg.chatId, ThreadId: d.cfg.threadId, Err: err}
+			}
+		}(i, d)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
@@ -0,0 +1,54 @@
+package telegramhook
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"testing"
+
+	log "github.com/andoma-go/logrus"
+)
+
+func TestChatsFanOut(t *testing.T) {
+	api := &fakeAPI{respond: func(method string, body []byte) *http.Response {
+		if strings.Contains(string(body), `"chat_id":"-200"`) {
+			return jsonResponse(http.StatusBadRequest, `{"ok":false,"error_code":400,"description":"Bad Request: chat not found"}`)
+		}
+		return nil
+	}}
+	h := newTestHook(WithChatTarget(Chat("-100")), WithChats(Chat("-200"), GroupTopic(-300, 5)))
+	h.client = api.client()
+
+	err := h.Fire(&log.Entry{Level: log.ErrorLevel, Message: "broadcast"})
+	var chatErr *ChatError
+	if !errors.As(err, &chatErr) || chatErr.ChatId != "-200" {
+		t.Fatalf("Expected a ChatError for the failing chat, got %v", err)
+	}
+
+	var chats []string
+	for _, c := range api.calls {
+		var req apiRequest
+		_ = json.Unmarshal(c.body, &req)
+		chats = append(chats, req.ChatId+"/"+req.ThreadId)
+	}
+	sort.Strings(chats)
+	if got := strings.Join(chats, " "); got != "-100/ -200/ -300/5" {
+		t.Errorf("Expected a message to every chat, sent to %s", got)
+	}
+}
+
+func TestChatsOnly(t *testing.T) {
+	api := &fakeAPI{}
+	h := newTestHook(WithChats(Chat("-200")))
+	h.client = api.client()
+
+	if err := h.Fire(&log.Entry{Level: log.ErrorLevel, Message: "m"}); err != nil {
+		t.Fatal(err)
+	}
+	var req apiRequest
+	if len(api.calls) != 1 || json.Unmarshal(api.calls[0].body, &req) != nil || req.ChatId != "-200" {
+		t.Errorf("Expected a single message to the target without a hook chat, got %d", len(api.calls))
+	}
+}
@@ -0,0 +1,95 @@
+package telegramhook
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// FaultInjection simulates Telegram failures in tests and staging, to verify
+// that queue, retry and failover settings behave as intended. Rates are
+// fractions of requests between 0 and 1.
+type FaultInjection struct {
+	// Latency is added to every affected request.
+	Latency time.Duration
+	// RateLimited is the rate of requests answered with 429 Too Many Requests.
+	RateLimited float64
+	// RetryAfter is the retry_after of injected 429 errors, 1 second when zero.
+	RetryAfter time.Duration
+	// ServerErrors is the rate of requests answered with 500 Internal Server Error.
+	ServerErrors float64
+	// NetworkErrors is the rate of requests failing as if the connection was refused.
+	NetworkErrors float64
+	// Endpoints limits the faults to these API base URLs, e.g. to simulate
+	// the outage of one of several endpoints. All endpoints are affected when
+	// empty.
+	Endpoints []string
+}
+
+// errInjected is the network error of injected faults.
+var errInjected = errors.New("connection refused (injected fault)")
+
+// affects reports whether requests to u are subject to the faults.
+func (f *FaultInjection) affects(u *url.URL) bool {
+	if len(f.Endpoints) == 0 {
+		return true
+	}
+	for _, base := range f.Endpoints {
+		if b, err := url.Parse(base); err == nil && b.Host == u.Host {
+			return true
+		}
+	}
+	return false
+}
+
+// inject delays req and decides whether it fails. It reports whether a fault
+// was injected, with its response or error; otherwise req should be sent.
+func (f *FaultInjection) inject(req *http.Request) (*http.Response, bool, error) {
+	if !f.affects(req.URL) {
+		return nil, false, nil
+	}
+
+	if f.Latency > 0 {
+		timer := time.NewTimer(f.Latency)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, true, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+
+	p := rand.Float64()
+	switch {
+	case p < f.NetworkErrors:
+		return nil, true, errInjected
+	case p < f.NetworkErrors+f.ServerErrors:
+		return faultResponse(req, http.StatusInternalServerError,
+			`{"ok":false,"error_code":500,"description":"Internal Server Error (injected fault)"}`), true, nil
+	case p < f.NetworkErrors+f.ServerErrors+f.RateLimited:
+		retryAfter := int(f.RetryAfter / time.Second)
+		if retryAfter < 1 {
+			retryAfter = 1
+		}
+		return faultResponse(req, http.StatusTooManyRequests, fmt.Sprintf(
+			`{"ok":false,"error_code":429,"description":"Too Many Requests: retry after %d (injected fault)","parameters":{"retry_after":%d}}`,
+			retryAfter, retryAfter)), true, nil
+	}
+	return nil, false, nil
+}
+
+// faultResponse builds an injected response to req.
+func faultResponse(req *http.Request, status int, body string) *http.Response {
+	return &http.Response{
+		StatusCode: status,
+		Status:     fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Request:    req,
+	}
+}
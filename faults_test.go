@@ -0,0 +1,44 @@
+package telegramhook
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	log "github.com/andoma-go/logrus"
+)
+
+func TestFaultInjection(t *testing.T) {
+	api := &fakeAPI{}
+	h := newTestHook(WithFaultInjection(FaultInjection{RateLimited: 1}))
+	h.client = api.client()
+
+	err := h.Fire(&log.Entry{Level: log.ErrorLevel, Message: "m"})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != 429 || apiErr.RetryAfter != time.Second {
+		t.Errorf("Expected an injected 429 error, got %v", err)
+	}
+	if n := len(api.methods()); n != 0 {
+		t.Errorf("Expected no request to reach the API, got %d", n)
+	}
+}
+
+func TestFaultInjectionOutage(t *testing.T) {
+	api := &fakeAPI{}
+	h := newTestHook(
+		WithApiEndpoints("https://primary.example", "https://mirror.example"),
+		WithFailoverCooldown(time.Minute),
+		WithFaultInjection(FaultInjection{NetworkErrors: 1, Latency: time.Millisecond, Endpoints: []string{"https://primary.example"}}),
+	)
+	h.client = api.client()
+
+	if err := h.Fire(&log.Entry{Level: log.ErrorLevel, Message: "m"}); err != nil {
+		t.Fatalf("Expected failover to the mirror, got %v", err)
+	}
+	if n := len(api.methods()); n != 1 {
+		t.Errorf("Expected one request to reach the mirror, got %d", n)
+	}
+	if got := h.ApiEndpoint(); got != "https://mirror.example" {
+		t.Errorf("Expected the primary to be marked down, preferred endpoint is %s", got)
+	}
+}
@@ -0,0 +1,156 @@
+package telegramhook
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime/multipart"
+	"sort"
+	"time"
+
+	"github.com/andoma-go/logrus"
+)
+
+// document is a file sent alongside a message.
+type document struct {
+	name    string
+	content []byte
+}
+
+// fieldKeys returns the keys of fields in priority order: the keys pinned with
+// WithFieldOrder in that order, then the error field, then the remaining keys
+// sorted alphabetically.
+func (c *config) fieldKeys(fields logrus.Fields) []string {
+	keys := make([]string, 0, len(fields))
+	pinned := make(map[string]bool, len(c.fieldOrder))
+	for _, k := range c.fieldOrder {
+		if _, ok := fields[k]; ok && !pinned[k] {
+			keys = append(keys, k)
+			pinned[k] = true
+		}
+	}
+	if _, ok := fields[logrus.ErrorKey]; ok && !pinned[logrus.ErrorKey] {
+		keys = append(keys, logrus.ErrorKey)
+		pinned[logrus.ErrorKey] = true
+	}
+
+	rest := make([]string, 0, len(fields)-len(keys))
+	for k := range fields {
+		if !pinned[k] {
+			rest = append(rest, k)
+		}
+	}
+	sort.Strings(rest)
+
+	return append(keys, rest...)
+}
+
+// hideFields returns entry, or a copy without the fields hidden with
+// WithHiddenFields.
+func (c *config) hideFields(entry *logrus.Entry) *logrus.Entry {
+	hidden := false
+	for _, k := range c.hiddenFields {
+		if _, ok := entry.Data[k]; ok {
+			hidden = true
+			break
+		}
+	}
+	if !hidden {
+		return entry
+	}
+
+	shown := *entry
+	shown.Data = make(logrus.Fields, len(entry.Data))
+	for k, v := range entry.Data {
+		shown.Data[k] = v
+	}
+	for _, k := range c.hiddenFields {
+		delete(shown.Data, k)
+	}
+	return &shown
+}
+
+// messageFields returns the fields of the fields section of the message for
+// entry, without a promoted error field and with relative times and humanizing
+// applied, the keys shown inline and the keys folded by WithMaxFields, in
+// order, and the fields rendered as blocks.
+func (c *config) messageFields(entry *logrus.Entry) (logrus.Fields, []string, []string, []fieldBlock) {
+	fields := entry.Data
+	if _, ok := fields[logrus.ErrorKey]; ok && c.promoteErrorKey && c.encryptionKey == nil {
+		fields = make(logrus.Fields, len(entry.Data)-1)
+		for k, v := range entry.Data {
+			if k != logrus.ErrorKey {
+				fields[k] = v
+			}
+		}
+	}
+
+	fields, blocks := c.extractBlocks(fields)
+	if c.relativeTimes {
+		now := entry.Time
+		if now.IsZero() {
+			now = time.Now()
+		}
+		fields = c.relativeTimeFields(fields, now)
+	}
+	if c.humanize {
+		fields = c.humanizeFields(fields)
+	}
+
+	keys := c.fieldKeys(fields)
+	var folded []string
+	if n := c.maxFields; n > 0 && len(keys) > n {
+		keys, folded = keys[:n], keys[n:]
+	}
+	return fields, keys, folded, blocks
+}
+
+// createFieldsDocument renders the fields the message folded as a text
+// document when attaching is enabled.
+func (c *config) createFieldsDocument(entry *logrus.Entry) *document {
+	if !c.fieldsDocument || c.maxFields == 0 {
+		return nil
+	}
+	fields, _, folded, _ := c.messageFields(entry)
+	if len(folded) == 0 {
+		return nil
+	}
+
+	var b bytes.Buffer
+	for _, k := range folded {
+		fmt.Fprintf(&b, "%s: %s\n", k, formatValue(fields[k]))
+	}
+
+	if c.encryptionKey != nil {
+		return &document{name: "fields.txt.enc", content: []byte(c.encrypt(b.String()))}
+	}
+	return &document{name: "fields.txt", content: b.Bytes()}
+}
+
+// sendDocument uploads doc to the configured chat.
+func (h *TelegramHook) sendDocument(ctx context.Context, cfg config, doc document) error {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+
+	w.WriteField("chat_id", cfg.chatId)
+	if threadId := cfg.threadId; threadId != "" {
+		w.WriteField("message_thread_id", threadId)
+	}
+	if cfg.silent {
+		w.WriteField("disable_notification", "true")
+	}
+
+	part, err := w.CreateFormFile("document", doc.name)
+	if err != nil {
+		return err
+	}
+	if _, err := part.Write(doc.content); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	_, err = h.call(ctx, cfg, "sendDocument", w.FormDataContentType(), body.Bytes())
+	return err
+}
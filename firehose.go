@@ -0,0 +1,143 @@
+package telegramhook
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/andoma-go/logrus"
+)
+
+// firehoseInterval is how often entries collected in firehose mode are sent.
+const firehoseInterval = 5 * time.Second
+
+const (
+	// firehoseCommandDefault is how long /firehose without a duration enables
+	// firehose mode.
+	firehoseCommandDefault = 10 * time.Minute
+	// firehoseCommandMax bounds the duration of /firehose, so a forgotten
+	// command does not flood the chat for days.
+	firehoseCommandMax = time.Hour
+)
+
+// firehoseBuffer collects messages that are only sent because firehose mode is
+// active, so they are delivered in combined batches instead of one by one.
+type firehoseBuffer struct {
+	mu    sync.Mutex
+	msgs  []string
+	timer *time.Timer
+}
+
+// EnableFirehose temporarily sends everything down to DebugLevel for d. Entries
+// below the configured level are batched to protect the chat from flooding.
+// The configured level applies again once d has passed.
+func (h *TelegramHook) EnableFirehose(d time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.firehoseUntil = time.Now().Add(d)
+}
+
+// DisableFirehose ends firehose mode immediately.
+func (h *TelegramHook) DisableFirehose() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.firehoseUntil = time.Time{}
+}
+
+// FirehoseActive reports whether firehose mode is currently active.
+func (h *TelegramHook) FirehoseActive() bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.firehoseActive()
+}
+
+// firehoseActive reports whether firehose mode is active at the time of the call.
+func (c *config) firehoseActive() bool {
+	return time.Now().Before(c.firehoseUntil)
+}
+
+// enabled reports whether entries of level are sent at all, and whether they
+// are only sent because of firehose mode.
+func (c *config) enabled(level logrus.Level) (enabled, firehose bool) {
+	if level <= c.level {
+		return true, false
+	}
+	if level <= logrus.DebugLevel && c.firehoseActive() {
+		return true, true
+	}
+	return false, false
+}
+
+// bufferFirehose queues msg for the next firehose batch.
+func (h *TelegramHook) bufferFirehose(cfg config, msg string) {
+	b := &h.firehose
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.msgs = append(b.msgs, msg)
+	if b.timer == nil {
+		b.timer = time.AfterFunc(firehoseInterval, func() {
+			h.flushFirehose(cfg)
+		})
+	}
+}
+
+// flushFirehose sends all buffered firehose messages as one combined message.
+func (h *TelegramHook) flushFirehose(cfg config) {
+	b := &h.firehose
+	b.mu.Lock()
+	msgs := b.msgs
+	b.msgs, b.timer = nil, nil
+	b.mu.Unlock()
+
+	if len(msgs) == 0 {
+		return
+	}
+
+	if _, err := h.sendMessage(cfg, strings.Join(msgs, "\n\n")); err != nil {
+		h.handleError(err)
+	}
+}
+
+// handleCommand handles a /firehose command sent to the chat of cfg: "/firehose"
+// enables firehose mode for 10 minutes, "/firehose 30m" for the given duration
+// up to an hour, and "/firehose off" disables it. Messages from other chats and
+// other commands are ignored.
+func (h *TelegramHook) handleCommand(cfg config, m *apiIncoming) {
+	if strconv.FormatInt(m.Chat.Id, 10) != cfg.chatId && "@"+m.Chat.Username != cfg.chatId {
+		return
+	}
+	args := strings.Fields(m.Text)
+	if len(args) == 0 || args[0] != "/firehose" && !strings.HasPrefix(args[0], "/firehose@") {
+		return
+	}
+
+	var reply string
+	switch {
+	case len(args) > 1 && args[1] == "off":
+		h.DisableFirehose()
+		reply = "Firehose mode disabled"
+	default:
+		d := firehoseCommandDefault
+		if len(args) > 1 {
+			parsed, err := time.ParseDuration(args[1])
+			if err != nil || parsed <= 0 {
+				reply = "Usage: /firehose [duration|off], e.g. /firehose 30m"
+				break
+			}
+			d = parsed
+		}
+		if d > firehoseCommandMax {
+			d = firehoseCommandMax
+		}
+		h.EnableFirehose(d)
+		reply = fmt.Sprintf("Firehose mode enabled for %s", d)
+	}
+
+	if _, err := h.sendReply(context.Background(), cfg, reply, replyParameters{MessageId: m.MessageId}); err != nil {
+		h.handleError(err)
+	}
+}
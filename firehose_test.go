@@ -0,0 +1,79 @@
+package telegramhook
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	log "github.com/andoma-go/logrus"
+)
+
+func TestFirehose(t *testing.T) {
+	h := newTestHook()
+	entry := &log.Entry{Level: log.DebugLevel, Message: "details"}
+
+	cfg := h.snapshot()
+	if enabled, _ := cfg.enabled(entry.Level); enabled {
+		t.Fatal("Debug entry enabled without firehose")
+	}
+
+	h.EnableFirehose(time.Minute)
+	if err := h.Fire(entry); err != nil {
+		t.Fatal(err)
+	}
+
+	h.firehose.mu.Lock()
+	buffered := len(h.firehose.msgs)
+	h.firehose.timer.Stop()
+	h.firehose.mu.Unlock()
+	if buffered != 1 {
+		t.Errorf("Firehose buffered %d messages, want 1", buffered)
+	}
+
+	h.EnableFirehose(-time.Second)
+	if h.FirehoseActive() {
+		t.Error("Firehose did not expire")
+	}
+}
+
+func TestFirehoseCommand(t *testing.T) {
+	var updates string
+	api := &fakeAPI{respond: func(method string, body []byte) *http.Response {
+		if method == "getUpdates" {
+			return jsonResponse(http.StatusOK, `{"ok":true,"result":[`+updates+`]}`)
+		}
+		return nil
+	}}
+	h := newTestHook(WithFirehoseCommand(true))
+	h.chatId, h.client = "42", api.client()
+
+	poll := func(update string) {
+		t.Helper()
+		updates = update
+		if err := h.pollUpdates(context.Background(), h.snapshot()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	poll(`{"update_id":1,"message":{"message_id":3,"text":"/firehose","chat":{"id":7}}}`)
+	if h.FirehoseActive() {
+		t.Fatal("Firehose enabled by a message from another chat")
+	}
+
+	poll(`{"update_id":2,"message":{"message_id":4,"text":"/firehose@log_bot 5h","chat":{"id":42}}}`)
+	if until := h.snapshot().firehoseUntil; !h.FirehoseActive() || time.Until(until) > firehoseCommandMax {
+		t.Fatalf("Firehose not enabled for at most an hour, until %v", until)
+	}
+
+	poll(`{"update_id":3,"message":{"message_id":5,"text":"/firehose off","chat":{"id":42}}}`)
+	if h.FirehoseActive() {
+		t.Error("Firehose not disabled")
+	}
+
+	want := []string{"Firehose mode enabled for 1h0m0s", "Firehose mode disabled"}
+	if got := api.texts(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Unexpected replies %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,183 @@
+package telegramhook
+
+import (
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/andoma-go/logrus"
+)
+
+// FloodSummary replaces individual messages by a periodic summary while more
+// entries arrive than the threshold. Zero values use the defaults.
+type FloodSummary struct {
+	// Threshold is the number of entries per minute above which a flood
+	// starts, 30 when zero.
+	Threshold int
+	// Interval is how often a summary is sent during a flood, a minute when
+	// zero. The flood ends once an interval saw no more entries than the
+	// threshold allows for it.
+	Interval time.Duration
+	// Top is the number of most frequent messages listed, 3 when zero.
+	Top int
+}
+
+func (f *FloodSummary) threshold() int {
+	if f.Threshold > 0 {
+		return f.Threshold
+	}
+	return 30
+}
+
+func (f *FloodSummary) interval() time.Duration {
+	if f.Interval > 0 {
+		return f.Interval
+	}
+	return time.Minute
+}
+
+func (f *FloodSummary) top() int {
+	if f.Top > 0 {
+		return f.Top
+	}
+	return 3
+}
+
+// floodWindow is the window the entry rate is measured over.
+const floodWindow = time.Minute
+
+// floodGuard counts entries during a flood, see WithFloodSummary.
+type floodGuard struct {
+	mu         sync.Mutex
+	recent     []time.Time // latest entries within the flood window
+	active     bool
+	cfg        config
+	signatures map[string]int
+	levels     map[logrus.Level]int
+	total      int
+	timer      *time.Timer
+}
+
+// flooded counts entry and reports whether it is part of a flood and must not
+// be sent on its own.
+func (h *TelegramHook) flooded(cfg config, entry *logrus.Entry) bool {
+	f := &h.flooding
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if !f.active {
+		now := time.Now()
+		f.recent = append(f.recent, now)
+		if n := cfg.flood.threshold() + 1; len(f.recent) > n {
+			f.recent = append(f.recent[:0], f.recent[len(f.recent)-n:]...)
+		}
+		for len(f.recent) > 0 && now.Sub(f.recent[0]) >= floodWindow {
+			f.recent = f.recent[1:]
+		}
+		if len(f.recent) <= cfg.flood.threshold() {
+			return false
+		}
+
+		f.active, f.cfg, f.recent = true, cfg, nil
+		f.timer = time.AfterFunc(cfg.flood.interval(), h.reportFlood)
+	}
+
+	if f.signatures == nil {
+		f.signatures = map[string]int{}
+		f.levels = map[logrus.Level]int{}
+	}
+	f.signatures[cfg.signature(entry)]++
+	f.levels[entry.Level]++
+	f.total++
+	return true
+}
+
+// reportFlood sends the summary of the last interval and ends the flood if it
+// subsided.
+func (h *TelegramHook) reportFlood() {
+	f := &h.flooding
+	f.mu.Lock()
+	cfg := f.cfg
+	msg := f.summary()
+	limit := float64(cfg.flood.threshold()) * cfg.flood.interval().Seconds() / floodWindow.Seconds()
+	if float64(f.total) <= limit {
+		f.active, f.timer = false, nil
+	} else {
+		f.timer = time.AfterFunc(cfg.flood.interval(), h.reportFlood)
+	}
+	level := f.level()
+	f.signatures, f.levels, f.total = nil, nil, 0
+	f.mu.Unlock()
+
+	if msg != "" {
+		h.post(cfg, outgoing{level: level, msg: msg})
+	}
+}
+
+// flushFlood sends the summary of an ongoing flood right away.
+func (h *TelegramHook) flushFlood() {
+	f := &h.flooding
+	f.mu.Lock()
+	if !f.active || f.total == 0 {
+		f.mu.Unlock()
+		return
+	}
+	f.timer.Stop()
+	f.mu.Unlock()
+
+	h.reportFlood()
+}
+
+// level returns the most severe level counted. The caller must hold the lock.
+func (f *floodGuard) level() logrus.Level {
+	level := logrus.TraceLevel
+	for l := range f.levels {
+		if l < level {
+			level = l
+		}
+	}
+	return level
+}
+
+// summary describes the entries counted, empty if there were none. The caller
+// must hold the lock.
+func (f *floodGuard) summary() string {
+	if f.total == 0 {
+		return ""
+	}
+
+	levels := make([]logrus.Level, 0, len(f.levels))
+	for l := range f.levels {
+		levels = append(levels, l)
+	}
+	sort.Slice(levels, func(i, j int) bool { return levels[i] < levels[j] })
+	counts := make([]string, 0, len(levels))
+	for _, l := range levels {
+		counts = append(counts, fmt.Sprintf("%d %s", f.levels[l], l))
+	}
+
+	signatures := make([]string, 0, len(f.signatures))
+	for s := range f.signatures {
+		signatures = append(signatures, s)
+	}
+	sort.Slice(signatures, func(i, j int) bool {
+		a, b := signatures[i], signatures[j]
+		if f.signatures[a] != f.signatures[b] {
+			return f.signatures[a] > f.signatures[b]
+		}
+		return a < b
+	})
+	if n := f.cfg.flood.top(); len(signatures) > n {
+		signatures = signatures[:n]
+	}
+
+	lines := []string{fmt.Sprintf("<b>FLOOD</b>@%s - %d entries in the last %s (%s), top %d messages:",
+		html.EscapeString(f.cfg.appName), f.total, f.cfg.flood.interval(), strings.Join(counts, ", "), len(signatures))}
+	for i, s := range signatures {
+		lines = append(lines, fmt.Sprintf("%d. %d× %s", i+1, f.signatures[s], html.EscapeString(truncateText(s, 200))))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,39 @@
+package telegramhook
+
+import (
+	"testing"
+	"time"
+
+	log "github.com/andoma-go/logrus"
+)
+
+func TestFloodSummary(t *testing.T) {
+	api := &fakeAPI{}
+	h := newTestHook(WithFloodSummary(FloodSummary{Threshold: 2, Interval: 30 * time.Millisecond, Top: 2}))
+	h.client = api.client()
+
+	for _, msg := range []string{"a", "a", "b", "a", "c", "a"} {
+		if err := h.Fire(&log.Entry{Level: log.ErrorLevel, Message: msg}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := len(api.texts()); n != 2 {
+		t.Fatalf("Expected entries above the threshold to be held back, got %d messages", n)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	texts := api.texts()
+	want := "<b>FLOOD</b>@testing - 4 entries in the last 30ms (4 error), top 2 messages:\n1. 2× error: a\n2. 1× error: b"
+	if len(texts) != 3 || texts[2] != want {
+		t.Fatalf("Expected the summary %q, got %q", want, texts)
+	}
+
+	// An interval without entries ends the flood.
+	time.Sleep(50 * time.Millisecond)
+	if err := h.Fire(&log.Entry{Level: log.ErrorLevel, Message: "calm"}); err != nil {
+		t.Fatal(err)
+	}
+	if texts := api.texts(); len(texts) != 4 {
+		t.Errorf("Expected normal delivery after the flood, got %d messages", len(texts))
+	}
+}
@@ -0,0 +1,200 @@
+package telegramhook
+
+import (
+	"fmt"
+	"html"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/andoma-go/logrus"
+)
+
+// Formatter renders the text of a Telegram message for an entry as HTML, which
+// is converted to the configured ParseMode when sent. A Formatter must escape
+// the message and field values with html.EscapeString.
+type Formatter interface {
+	Format(entry *logrus.Entry) (string, error)
+}
+
+// FormatterFunc adapts a function to the Formatter interface.
+type FormatterFunc func(entry *logrus.Entry) (string, error)
+
+// Format calls f(entry).
+func (f FormatterFunc) Format(entry *logrus.Entry) (string, error) {
+	return f(entry)
+}
+
+// formatMessage renders entry with the configured Formatter. If there is none
+// or it fails, the built-in layout is used, noting the error, so the alert is
+// not lost. With WithFieldEncryption the Formatter only sees the encrypted
+// fields.
+func (c *config) formatMessage(entry *logrus.Entry) string {
+	if c.formatter == nil {
+		return c.createMessage(entry)
+	}
+
+	formatted := entry
+	if c.encryptionKey != nil {
+		formatted = c.encryptedEntry(entry)
+	}
+	msg, err := c.formatter.Format(formatted)
+	if err != nil {
+		return c.createMessage(entry) + "\n<i>formatter failed: " + html.EscapeString(err.Error()) + "</i>"
+	}
+	return msg
+}
+
+// defaultLevelLabels are the labels messages start with, by level.
+var defaultLevelLabels = map[logrus.Level]string{
+	logrus.PanicLevel: "PANIC",
+	logrus.FatalLevel: "FATAL",
+	logrus.ErrorLevel: "ERROR",
+	logrus.WarnLevel:  "WARNING",
+	logrus.InfoLevel:  "INFO",
+	logrus.DebugLevel: "DEBUG",
+	logrus.TraceLevel: "TRACE",
+}
+
+// levelLabel returns the bold label a message of level starts with, from
+// WithLevelLabels or the defaults, empty if WithLevelLabels sets an empty
+// label. Levels without a default are labeled with their name in upper case.
+func (c *config) levelLabel(level logrus.Level) string {
+	label, ok := c.levelLabels[level]
+	if !ok {
+		label, ok = defaultLevelLabels[level]
+	}
+	if !ok {
+		label = strings.ToUpper(level.String())
+	}
+	if label == "" {
+		return ""
+	}
+	return "<b>" + html.EscapeString(label) + "</b>"
+}
+
+// createMessage crafts an HTML-formatted message to send to the Telegram API.
+func (c *config) createMessage(entry *logrus.Entry) string {
+	msg := c.levelLabel(entry.Level)
+
+	headline := entry.Message
+	if !c.rawHTML {
+		headline = html.EscapeString(headline)
+	}
+	if headline == "" {
+		headline = html.EscapeString(c.emptyHeadline(entry))
+	}
+
+	if err, ok := entry.Data[logrus.ErrorKey]; ok && c.promoteErrorKey && c.encryptionKey == nil && entry.Message != "" {
+		headline = fmt.Sprintf("%s: %s", headline, html.EscapeString(fmt.Sprintf("%v", err)))
+	}
+	fields, keys, folded, blocks := c.messageFields(entry)
+
+	msg = strings.Join([]string{msg, html.EscapeString(c.appName)}, "@")
+	if c.timeLayout != "" && !entry.Time.IsZero() {
+		msg += " <i>" + html.EscapeString(c.timestamp(entry.Time)) + "</i>"
+	}
+	msg = strings.Join([]string{msg, headline}, " - ")
+	if c.caller && entry.Caller != nil {
+		msg += "\n<i>" + html.EscapeString(callerLine(entry.Caller)) + "</i>"
+	}
+
+	var details []string
+	if len(keys) > 0 {
+		details = append(details, "<pre>")
+		if layout := c.table; layout != nil {
+			for _, row := range renderTable(fields, keys, *layout) {
+				details = append(details, html.EscapeString(row))
+			}
+		} else {
+			for _, k := range keys {
+				details = append(details, html.EscapeString(fmt.Sprintf("\t%s: %s", k, formatValue(fields[k]))))
+			}
+		}
+		details = append(details, "</pre>")
+
+		if len(folded) > 0 {
+			details = append(details, fmt.Sprintf("<i>… and %d more fields</i>", len(folded)))
+		}
+	}
+
+	if len(blocks) > 0 {
+		details = append(details, renderBlocks(blocks))
+	}
+
+	if len(details) > 0 {
+		if c.encryptionKey != nil {
+			details = []string{"<pre>" + c.encrypt(html.UnescapeString(stripTags(strings.Join(details, "\n")))) + "</pre>"}
+		}
+		msg = strings.Join(append([]string{msg}, details...), "\n")
+	}
+
+	if entry.Level == logrus.FatalLevel {
+		msg += c.processFooter()
+	}
+
+	return msg
+}
+
+// timestamp formats t with the layout of WithTimestamp in its location, or
+// the configured time zone when it has none.
+func (c *config) timestamp(t time.Time) string {
+	if c.timeLocation != nil {
+		t = t.In(c.timeLocation)
+	} else {
+		t = c.inZone(t)
+	}
+	return t.Format(c.timeLayout)
+}
+
+// callerLine describes where an entry was logged, with the last three elements
+// of the file path and the bare function name, e.g.
+// "pkg/server/handler.go:42 (HandleRequest)".
+func callerLine(frame *runtime.Frame) string {
+	file := frame.File
+	parts := strings.Split(file, "/")
+	if len(parts) > 3 {
+		file = strings.Join(parts[len(parts)-3:], "/")
+	}
+
+	line := fmt.Sprintf("%s:%d", file, frame.Line)
+	if fn := frame.Function; fn != "" {
+		line += " (" + fn[strings.LastIndex(fn, ".")+1:] + ")"
+	}
+	return line
+}
+
+// emptyHeadline synthesizes a headline for an entry logged without a message,
+// preferring the error field and falling back to the first few fields in the
+// order they are shown. With WithFieldEncryption it only names the fields, as
+// their values are sent encrypted.
+func (c *config) emptyHeadline(entry *logrus.Entry) string {
+	keys := c.fieldKeys(entry.Data)
+	if n := c.headlineFields; len(keys) > n {
+		keys = keys[:n]
+	}
+	if c.encryptionKey != nil {
+		return strings.Join(keys, ", ")
+	}
+
+	if err, ok := entry.Data[logrus.ErrorKey]; ok {
+		return fmt.Sprintf("%v", err)
+	}
+
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, fmt.Sprintf("%s=%s", k, formatValue(entry.Data[k])))
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+// signature identifies entries that describe the same problem, used to group
+// and count them. The message is normalized by the configured normalizers.
+func (c *config) signature(entry *logrus.Entry) string {
+	msg := entry.Message
+	for _, normalize := range c.normalizers {
+		msg = normalize(msg)
+	}
+	return entry.Level.String() + ": " + msg
+}
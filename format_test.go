@@ -0,0 +1,111 @@
+package telegramhook
+
+import (
+	"errors"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	log "github.com/andoma-go/logrus"
+)
+
+func TestFormatter(t *testing.T) {
+	custom := FormatterFunc(func(entry *log.Entry) (string, error) {
+		if entry.Message == "" {
+			return "", errors.New("no message")
+		}
+		return "🔥 <b>" + entry.Message + "</b>", nil
+	})
+	cfg := newTestHook(WithFormatter(custom)).snapshot()
+
+	msg, _ := cfg.renderFor(&log.Entry{Level: log.ErrorLevel, Message: "disk full"}, cfg.chatId)
+	if msg != "🔥 <b>disk full</b>" {
+		t.Errorf("Unexpected message %q", msg)
+	}
+
+	msg, _ = cfg.renderFor(&log.Entry{Level: log.ErrorLevel, Data: log.Fields{"disk": "/dev/sda"}}, cfg.chatId)
+	if !strings.HasPrefix(msg, "<b>ERROR</b>@testing - disk=/dev/sda") || !strings.HasSuffix(msg, "<i>formatter failed: no message</i>") {
+		t.Errorf("Expected the built-in layout after a formatter error:\n%s", msg)
+	}
+}
+
+func TestEscapeMessage(t *testing.T) {
+	entry := &log.Entry{Level: log.ErrorLevel, Message: "a < b & <i>c</i>"}
+
+	h := newTestHook()
+	h.appName = "R&D"
+	if msg := createMessage(h, entry); msg != "<b>ERROR</b>@R&amp;D - a &lt; b &amp; &lt;i&gt;c&lt;/i&gt;" {
+		t.Errorf("Unexpected message %q", msg)
+	}
+
+	h.SetRawHTML(true)
+	if msg := createMessage(h, entry); msg != "<b>ERROR</b>@R&amp;D - a < b & <i>c</i>" {
+		t.Errorf("Unexpected raw message %q", msg)
+	}
+}
+
+func TestLevelLabels(t *testing.T) {
+	h := newTestHook(WithLevelLabels(map[log.Level]string{
+		log.PanicLevel: "🔥 PANIC",
+		log.WarnLevel:  "⚠️ <warn>",
+		log.InfoLevel:  "",
+	}))
+
+	tests := []struct {
+		level log.Level
+		want  string
+	}{
+		{log.PanicLevel, "<b>🔥 PANIC</b>@testing - m"},
+		{log.WarnLevel, "<b>⚠️ &lt;warn&gt;</b>@testing - m"},
+		{log.ErrorLevel, "<b>ERROR</b>@testing - m"},
+		{log.InfoLevel, "@testing - m"},
+		{log.TraceLevel, "<b>TRACE</b>@testing - m"},
+		{log.Level(9), "<b>UNKNOWN</b>@testing - m"},
+	}
+	for _, tt := range tests {
+		if msg := createMessage(h, &log.Entry{Level: tt.level, Message: "m"}); msg != tt.want {
+			t.Errorf("Message at %s = %q, want %q", tt.level, msg, tt.want)
+		}
+	}
+
+	for _, level := range log.AllLevels {
+		if defaultLevelLabels[level] == "" {
+			t.Errorf("No default label for %s", level)
+		}
+	}
+}
+
+func TestTimestamp(t *testing.T) {
+	berlin := time.FixedZone("CEST", 2*60*60)
+	entry := &log.Entry{Level: log.ErrorLevel, Message: "m", Time: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)}
+
+	h := newTestHook(WithTimestamp(time.DateTime, berlin))
+	if msg := createMessage(h, entry); msg != "<b>ERROR</b>@testing <i>2024-05-01 12:00:00</i> - m" {
+		t.Errorf("Unexpected message %q", msg)
+	}
+
+	h.SetTimestamp(time.TimeOnly, nil)
+	if msg := createMessage(h, entry); msg != "<b>ERROR</b>@testing <i>10:00:00</i> - m" {
+		t.Errorf("Expected the time in the zone it was logged in, got %q", msg)
+	}
+
+	if msg := createMessage(h, &log.Entry{Level: log.ErrorLevel, Message: "m"}); msg != "<b>ERROR</b>@testing - m" {
+		t.Errorf("Expected no time for an entry without one, got %q", msg)
+	}
+}
+
+func TestCaller(t *testing.T) {
+	entry := &log.Entry{Level: log.ErrorLevel, Message: "m", Caller: &runtime.Frame{
+		File:     "/home/ci/src/example.com/app/pkg/server/handler.go",
+		Line:     42,
+		Function: "example.com/app/pkg/server.(*Server).HandleRequest",
+	}}
+
+	if msg := createMessage(newTestHook(), entry); msg != "<b>ERROR</b>@testing - m" {
+		t.Errorf("Expected no caller by default, got %q", msg)
+	}
+	if msg := createMessage(newTestHook(WithCaller(true)), entry); msg != "<b>ERROR</b>@testing - m\n<i>pkg/server/handler.go:42 (HandleRequest)</i>" {
+		t.Errorf("Unexpected message %q", msg)
+	}
+}
@@ -0,0 +1,79 @@
+package telegramhook
+
+import "github.com/andoma-go/logrus"
+
+// Message is a message rendered by a SignatureHandler.
+type Message struct {
+	// Text is the HTML formatted text of the message.
+	Text string
+	// Document is uploaded after the message unless it is empty, e.g. a
+	// stack dump too long for a message.
+	Document []byte
+	// DocumentName is the file name of the document, "details.txt" when empty.
+	DocumentName string
+}
+
+// SignatureHandler renders well-known entries in a bespoke way, e.g. with a
+// link to the runbook for an error. entries are the entries sent together, a
+// single one per Fire. Returning false falls back to the default rendering.
+type SignatureHandler func(entries []*logrus.Entry) (Message, bool)
+
+// signatureHandler is a registered SignatureHandler with what it handles.
+type signatureHandler struct {
+	signature string                   // signature handled, see HandleSignature
+	match     func(*logrus.Entry) bool // rule handled, see HandleMatch
+	handle    SignatureHandler
+}
+
+// HandleSignature registers handler for entries with signature, the level and
+// the message normalized by the signature normalizers, e.g.
+// "error: connection refused". Handlers are tried in the order they were
+// registered.
+func (h *TelegramHook) HandleSignature(signature string, handler SignatureHandler) {
+	h.addHandler(signatureHandler{signature: signature, handle: handler})
+}
+
+// HandleMatch registers handler for entries match returns true for.
+// Handlers are tried in the order they were registered.
+func (h *TelegramHook) HandleMatch(match func(*logrus.Entry) bool, handler SignatureHandler) {
+	h.addHandler(signatureHandler{match: match, handle: handler})
+}
+
+// addHandler appends handler to a copy of the registry, so snapshots taken
+// before keep their handlers.
+func (h *TelegramHook) addHandler(handler signatureHandler) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.handlers = append(append([]signatureHandler(nil), h.handlers...), handler)
+}
+
+// handle renders entry with the first registered handler that matches and
+// accepts it, and reports whether one did.
+func (c *config) handle(entry *logrus.Entry) (string, *document, bool) {
+	if len(c.handlers) == 0 {
+		return "", nil, false
+	}
+
+	signature := c.signature(entry)
+	for _, handler := range c.handlers {
+		if handler.match != nil && !handler.match(entry) || handler.match == nil && handler.signature != signature {
+			continue
+		}
+
+		msg, ok := handler.handle([]*logrus.Entry{entry})
+		if !ok {
+			continue
+		}
+
+		var doc *document
+		if len(msg.Document) > 0 {
+			name := msg.DocumentName
+			if name == "" {
+				name = "details.txt"
+			}
+			doc = &document{name: name, content: msg.Document}
+		}
+		return msg.Text, doc, true
+	}
+	return "", nil, false
+}
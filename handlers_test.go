@@ -0,0 +1,43 @@
+package telegramhook
+
+import (
+	"strings"
+	"testing"
+
+	log "github.com/andoma-go/logrus"
+)
+
+func TestSignatureHandler(t *testing.T) {
+	api := &fakeAPI{}
+	runbook := func(entries []*log.Entry) (Message, bool) {
+		return Message{
+			Text:     `<b>DB DOWN</b> - see <a href="https://runbooks.example/db">runbook</a>`,
+			Document: []byte(entries[0].Message),
+		}, true
+	}
+	h := newTestHook(
+		WithSignatureNormalizers(DefaultNormalizers()...),
+		WithSignatureHandler("error: db pool <n>: connection refused", runbook),
+		WithMatchHandler(func(e *log.Entry) bool { return e.Data["skip"] == true }, func([]*log.Entry) (Message, bool) {
+			return Message{}, false
+		}),
+	)
+	h.client = api.client()
+
+	if err := h.Fire(&log.Entry{Level: log.ErrorLevel, Message: "db pool 3: connection refused"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := api.methods(); strings.Join(got, " ") != "sendMessage sendDocument" {
+		t.Fatalf("Expected the message and its document, got %v", got)
+	}
+	if text := api.texts()[0]; !strings.HasPrefix(text, "<b>DB DOWN</b>") {
+		t.Errorf("Message not rendered by the handler: %s", text)
+	}
+
+	if err := h.Fire(&log.Entry{Level: log.ErrorLevel, Message: "other", Data: log.Fields{"skip": true}}); err != nil {
+		t.Fatal(err)
+	}
+	if text := api.texts()[1]; !strings.HasPrefix(text, "<b>ERROR</b>@testing - other") {
+		t.Errorf("Expected the default rendering when the handler declines, got %s", text)
+	}
+}
@@ -0,0 +1,162 @@
+package telegramhook
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/andoma-go/logrus"
+)
+
+const (
+	// HTTPRequestKey is the field for an *http.Request rendered as a compact block.
+	HTTPRequestKey = "http_request"
+	// HTTPResponseKey is the field for an *http.Response rendered as a compact block.
+	HTTPResponseKey = "http_response"
+)
+
+// defaultHTTPBodyLimit is the default number of body bytes shown for requests.
+const defaultHTTPBodyLimit = 1024
+
+// sensitiveHeaders are masked in rendered requests and responses.
+var sensitiveHeaders = []string{"authorization", "cookie", "token", "secret", "password", "api-key", "apikey"}
+
+// renderHTTPBlocks renders the request and response fields as blocks.
+func renderHTTPBlocks(c *config, fields logrus.Fields) ([]fieldBlock, []string) {
+	var blocks []fieldBlock
+	var keys []string
+
+	if req, ok := fields[HTTPRequestKey].(*http.Request); ok && req != nil {
+		blocks = append(blocks, fieldBlock{title: "HTTP request", body: c.formatHTTPRequest(req)})
+		keys = append(keys, HTTPRequestKey)
+	}
+
+	if res, ok := fields[HTTPResponseKey].(*http.Response); ok && res != nil {
+		blocks = append(blocks, fieldBlock{title: "HTTP response", body: formatHTTPResponse(res)})
+		keys = append(keys, HTTPResponseKey)
+	}
+
+	return blocks, keys
+}
+
+// formatHTTPRequest renders the request line with the query values masked,
+// masked headers and the body, if it can be read again through GetBody,
+// truncated to the body limit. Sensitive and redacted fields of JSON and form
+// bodies are masked, and the redaction patterns are applied to the whole text.
+func (c *config) formatHTTPRequest(req *http.Request) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s %s\n", req.Method, maskQuery(req.URL), req.Proto)
+	if req.Host != "" {
+		fmt.Fprintf(&b, "Host: %s\n", req.Host)
+	}
+	writeHeaders(&b, req.Header)
+
+	if req.GetBody != nil && c.httpBodyLimit > 0 {
+		if body, err := req.GetBody(); err == nil {
+			defer body.Close()
+			data, _ := io.ReadAll(io.LimitReader(body, int64(c.httpBodyLimit)+1))
+			if len(data) > 0 {
+				data = []byte(redactText(maskBodyFields(string(data), c.redactKeys), c.redactPatterns))
+				b.WriteString("\n")
+				b.WriteString(truncateBody(data, c.httpBodyLimit, req.ContentLength))
+			}
+		}
+	}
+
+	return redactText(strings.TrimRight(b.String(), "\n"), c.redactPatterns)
+}
+
+// maskQuery returns the path and query of u with all query values masked, as
+// query strings often carry tokens and signatures.
+func maskQuery(u *url.URL) string {
+	if u.RawQuery == "" {
+		return u.EscapedPath()
+	}
+
+	params := strings.Split(u.RawQuery, "&")
+	for i, param := range params {
+		if name, _, ok := strings.Cut(param, "="); ok {
+			params[i] = name + "=***"
+		}
+	}
+	return u.EscapedPath() + "?" + strings.Join(params, "&")
+}
+
+// maskBodyFields masks the values of JSON members and form fields whose names
+// contain a sensitive word or equal one of keys.
+func maskBodyFields(body string, keys []string) string {
+	names := make([]string, 0, len(sensitiveHeaders)+len(keys))
+	for _, s := range sensitiveHeaders {
+		names = append(names, `[\w.-]*`+regexp.QuoteMeta(s)+`[\w.-]*`)
+	}
+	for _, k := range keys {
+		names = append(names, regexp.QuoteMeta(k))
+	}
+	name := "(?:" + strings.Join(names, "|") + ")"
+
+	// A value cut off by the body limit is masked up to the end
+	member := regexp.MustCompile(`(?i)("` + name + `"\s*:\s*)"(?:[^"\\]|\\.)*"?`)
+	form := regexp.MustCompile(`(?i)(^|&)(` + name + `=)[^&]*`)
+	body = member.ReplaceAllString(body, `$1"***"`)
+	return form.ReplaceAllString(body, `$1$2***`)
+}
+
+// formatHTTPResponse renders the status line and masked headers. The body is
+// not read since that would consume it for the application.
+func formatHTTPResponse(res *http.Response) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s\n", res.Proto, res.Status)
+	writeHeaders(&b, res.Header)
+	if res.ContentLength > 0 {
+		fmt.Fprintf(&b, "\n[body: %s]", formatBytes(res.ContentLength))
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// writeHeaders writes headers sorted by name with sensitive values masked.
+func writeHeaders(b *strings.Builder, header http.Header) {
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		value := strings.Join(header[name], ", ")
+		if isSensitiveHeader(name) {
+			value = "***"
+		}
+		fmt.Fprintf(b, "%s: %s\n", name, value)
+	}
+}
+
+func isSensitiveHeader(name string) bool {
+	name = strings.ToLower(name)
+	for _, s := range sensitiveHeaders {
+		if strings.Contains(name, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// truncateBody renders data, noting how much was cut off when it exceeds limit.
+func truncateBody(data []byte, limit int, total int64) string {
+	if len(data) <= limit {
+		return string(data)
+	}
+
+	// Do not cut a multi-byte character in half
+	for limit > 0 && !utf8.Valid(data[:limit]) {
+		limit--
+	}
+	if total > 0 {
+		return fmt.Sprintf("%s… [truncated, %s total]", data[:limit], formatBytes(total))
+	}
+	return fmt.Sprintf("%s… [truncated]", data[:limit])
+}
@@ -0,0 +1,51 @@
+package telegramhook
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	log "github.com/andoma-go/logrus"
+)
+
+func TestHTTPRequestBlock(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodPost, "https://shop.example/api/orders?id=7", strings.NewReader(`{"items":[1,2,3,4,5]}`))
+	req.Header.Set("Authorization", "Bearer secret")
+	req.Header.Set("Content-Type", "application/json")
+
+	h := newTestHook(WithHTTPBodyLimit(10))
+	msg := createMessage(h, &log.Entry{
+		Level:   log.ErrorLevel,
+		Message: "checkout failed",
+		Data:    log.Fields{HTTPRequestKey: req, "user": 1},
+	})
+
+	want := "<b>HTTP request</b>\n<pre>POST /api/orders?id=*** HTTP/1.1\nHost: shop.example\n" +
+		"Authorization: ***\nContent-Type: application/json\n\n{&#34;items&#34;:[… [truncated, 21 B total]</pre>"
+	if !strings.HasSuffix(msg, want) {
+		t.Errorf("Unexpected request block:\n%s", msg)
+	}
+	if strings.Contains(msg, "secret") || strings.Contains(msg, "http_request:") {
+		t.Errorf("Request leaked into the message: %q", msg)
+	}
+}
+
+func TestHTTPRequestMasking(t *testing.T) {
+	h := newTestHook(WithRedaction([]string{"card"}, nil), WithHTTPBodyLimit(defaultHTTPBodyLimit))
+	cfg := h.snapshot()
+
+	req, _ := http.NewRequest(http.MethodPost, "https://shop.example/login?access_token=abc&page=2",
+		strings.NewReader(`{"user":"bob","password":"hunter2","card":"4111","note":"a\"b"}`))
+	got := cfg.formatHTTPRequest(req)
+	want := "POST /login?access_token=***&page=*** HTTP/1.1\nHost: shop.example\n\n" +
+		`{"user":"bob","password":"***","card":"***","note":"a\"b"}`
+	if got != want {
+		t.Errorf("Unexpected JSON request:\n%s\nwant\n%s", got, want)
+	}
+
+	req, _ = http.NewRequest(http.MethodPost, "https://shop.example/login",
+		strings.NewReader("user=bob&client_secret=s3&pin=1"))
+	if got := cfg.formatHTTPRequest(req); !strings.HasSuffix(got, "\n\nuser=bob&client_secret=***&pin=1") {
+		t.Errorf("Unexpected form request:\n%s", got)
+	}
+}
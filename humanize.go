@@ -0,0 +1,153 @@
+package telegramhook
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andoma-go/logrus"
+)
+
+// bytesSuffix marks integer fields that hold a byte count.
+const bytesSuffix = "_bytes"
+
+// identifierSuffixes mark numeric fields that are identifiers rather than
+// quantities, e.g. "user_id", "orderID", "port" or "year". They never get
+// thousands separators.
+var identifierSuffixes = []string{"id", "ids", "pid", "port", "year", "code", "version", "zip"}
+
+// identifierKey reports whether the field k holds an identifier, compared
+// case-insensitively.
+func identifierKey(k string) bool {
+	k = strings.ToLower(k)
+	for _, suffix := range identifierSuffixes {
+		if strings.HasSuffix(k, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// humanizeFields returns a copy of fields with durations and byte counts
+// replaced by their human-readable form.
+func (c *config) humanizeFields(fields logrus.Fields) logrus.Fields {
+	humanized := make(logrus.Fields, len(fields))
+	for k, v := range fields {
+		humanized[k] = c.humanizeValue(k, v)
+	}
+	return humanized
+}
+
+// humanizeValue renders a duration as e.g. "1.2s" and an integer in a field
+// named *_bytes as e.g. "3.4 MiB". With a locale, other numbers get thousands
+// separators, unless the field holds an identifier such as an ID, port or
+// year, and times are written in the locale's layout and time zone.
+// Values with a renderer, see RegisterRenderer, and other values are returned
+// as they are.
+func (c *config) humanizeValue(key string, v interface{}) interface{} {
+	if _, ok := renderer(v); ok {
+		return v
+	}
+
+	l := c.locale()
+	switch v := v.(type) {
+	case time.Duration:
+		return l.decimals(humanizeDuration(v))
+	case time.Time:
+		if c.localeTag != "" || c.timezone != nil {
+			return l.dateTime(c.inZone(v))
+		}
+		return v
+	}
+
+	rv := reflect.ValueOf(v)
+	if strings.HasSuffix(key, bytesSuffix) {
+		switch rv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if n := rv.Int(); n >= 0 {
+				return l.decimals(formatBytes(n))
+			}
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			if n := rv.Uint(); n <= 1<<63-1 {
+				return l.decimals(formatBytes(int64(n)))
+			}
+		}
+	}
+
+	if c.localeTag == "" || identifierKey(key) {
+		return v
+	}
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return l.integer(rv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return l.number(strconv.FormatUint(rv.Uint(), 10))
+	case reflect.Float32, reflect.Float64:
+		return l.number(strconv.FormatFloat(rv.Float(), 'f', -1, rv.Type().Bits()))
+	}
+	return v
+}
+
+// relativeTimeFields returns a copy of fields with times rendered both as they
+// are and relative to now, e.g. "2024-05-01 10:00:00 +0000 UTC (3m ago)".
+func (c *config) relativeTimeFields(fields logrus.Fields, now time.Time) logrus.Fields {
+	relative := make(logrus.Fields, len(fields))
+	for k, v := range fields {
+		if t, ok := v.(time.Time); ok {
+			v = fmt.Sprintf("%v (%s)", c.humanizeValue(k, t.Round(0)), relativeTime(t, now))
+		}
+		relative[k] = v
+	}
+	return relative
+}
+
+// relativeTime describes t relative to now in the largest whole unit, e.g.
+// "3m ago" or "in 2h".
+func relativeTime(t, now time.Time) string {
+	d := now.Sub(t)
+	future := d < 0
+	if future {
+		d = -d
+	}
+
+	var s string
+	switch {
+	case d < time.Second:
+		return "now"
+	case d < time.Minute:
+		s = fmt.Sprintf("%ds", d/time.Second)
+	case d < time.Hour:
+		s = fmt.Sprintf("%dm", d/time.Minute)
+	case d < 48*time.Hour:
+		s = fmt.Sprintf("%dh", d/time.Hour)
+	default:
+		s = fmt.Sprintf("%dd", d/(24*time.Hour))
+	}
+
+	if future {
+		return "in " + s
+	}
+	return s + " ago"
+}
+
+// humanizeDuration rounds d to two significant digits in its largest unit.
+func humanizeDuration(d time.Duration) string {
+	abs := d
+	if abs < 0 {
+		abs = -abs
+	}
+
+	switch {
+	case abs >= time.Minute:
+		d = d.Round(time.Second)
+	case abs >= time.Second:
+		d = d.Round(100 * time.Millisecond)
+	case abs >= time.Millisecond:
+		d = d.Round(100 * time.Microsecond)
+	case abs >= time.Microsecond:
+		d = d.Round(100 * time.Nanosecond)
+	}
+	return d.String()
+}
@@ -0,0 +1,86 @@
+package telegramhook
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	log "github.com/andoma-go/logrus"
+)
+
+func TestHumanizeValue(t *testing.T) {
+	tests := []struct {
+		key  string
+		v    interface{}
+		want interface{}
+	}{
+		{"took", 1234567890 * time.Nanosecond, "1.2s"},
+		{"took", 90*time.Second + 400*time.Millisecond, "1m30s"},
+		{"took", 1520 * time.Microsecond, "1.5ms"},
+		{"body_bytes", 3565158, "3.4 MiB"},
+		{"body_bytes", uint32(512), "512 B"},
+		{"body_bytes", "n/a", "n/a"},
+		{"count", 3565158, 3565158},
+	}
+	var cfg config
+	for _, tt := range tests {
+		if got := cfg.humanizeValue(tt.key, tt.v); got != tt.want {
+			t.Errorf("humanizeValue(%q, %v) = %v, want %v", tt.key, tt.v, got, tt.want)
+		}
+	}
+}
+
+func TestHumanizeMessage(t *testing.T) {
+	entry := &log.Entry{
+		Level:   log.ErrorLevel,
+		Message: "slow upload",
+		Data:    log.Fields{"took": 1200 * time.Millisecond, "size_bytes": 2048},
+	}
+
+	if msg := createMessage(newTestHook(), entry); !strings.Contains(msg, "size_bytes: 2048") {
+		t.Errorf("Expected raw values without WithHumanize:\n%s", msg)
+	}
+
+	msg := createMessage(newTestHook(WithHumanize(true)), entry)
+	if !strings.Contains(msg, "size_bytes: 2.0 KiB") || !strings.Contains(msg, "took: 1.2s") {
+		t.Errorf("Expected humanized values:\n%s", msg)
+	}
+}
+
+func TestRelativeTime(t *testing.T) {
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{0, "now"},
+		{45 * time.Second, "45s ago"},
+		{3*time.Minute + 20*time.Second, "3m ago"},
+		{26 * time.Hour, "26h ago"},
+		{72 * time.Hour, "3d ago"},
+		{-5 * time.Minute, "in 5m"},
+	}
+	for _, tt := range tests {
+		if got := relativeTime(now.Add(-tt.d), now); got != tt.want {
+			t.Errorf("relativeTime(now - %s) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
+
+func TestRelativeTimesMessage(t *testing.T) {
+	logged := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	entry := &log.Entry{
+		Level:   log.ErrorLevel,
+		Message: "replica lagging",
+		Time:    logged,
+		Data:    log.Fields{"last_sync": logged.Add(-3 * time.Minute), "host": "db2"},
+	}
+
+	msg := createMessage(newTestHook(WithRelativeTimes(true)), entry)
+	if !strings.Contains(msg, "last_sync: 2024-05-01 09:57:00 +0000 UTC (3m ago)") {
+		t.Errorf("Expected the time with its age, got %q", msg)
+	}
+	if !strings.Contains(msg, "host: db2") {
+		t.Errorf("Expected other fields unchanged, got %q", msg)
+	}
+}
@@ -0,0 +1,227 @@
+package telegramhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"sync"
+	"time"
+
+	"github.com/andoma-go/logrus"
+)
+
+// IncidentKey is the field that threads an entry into the open incident with
+// the same key: it is sent as a reply to the incident's header message.
+const IncidentKey = "incident"
+
+// Incident groups related messages into one chat artifact: a pinned header
+// that shows the state of the incident, follow-up entries threaded as replies
+// and a resolution summary.
+type Incident struct {
+	h        *TelegramHook
+	cfg      config
+	key      string
+	title    string
+	opened   time.Time
+	headerId int64
+
+	mu       sync.Mutex
+	updates  int
+	resolved bool
+}
+
+// incidents holds the open incidents by key.
+type incidents struct {
+	mu   sync.Mutex
+	open map[string]*Incident
+	// opening holds the keys whose header is being posted, the channel is
+	// closed once it was posted or failed.
+	opening map[string]chan struct{}
+}
+
+// unpinMessageRequest is the payload of unpinChatMessage.
+type unpinMessageRequest struct {
+	ChatId    string `json:"chat_id"`
+	MessageId int64  `json:"message_id"`
+}
+
+// OpenIncident posts and pins the header message of a new incident. Entries
+// logged with an IncidentKey field equal to key are sent as replies to it until
+// the incident is resolved. Opening a key that is already open returns the
+// open incident.
+func (h *TelegramHook) OpenIncident(key, title string) (*Incident, error) {
+	in := &h.incidents
+	in.mu.Lock()
+	for in.opening[key] != nil {
+		opening := in.opening[key]
+		in.mu.Unlock()
+		<-opening
+		in.mu.Lock()
+	}
+	if inc, ok := in.open[key]; ok {
+		in.mu.Unlock()
+		return inc, nil
+	}
+	if in.opening == nil {
+		in.opening = map[string]chan struct{}{}
+	}
+	done := make(chan struct{})
+	in.opening[key] = done
+	in.mu.Unlock()
+
+	inc, err := h.postIncident(key, title)
+
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	delete(in.opening, key)
+	close(done)
+	if err != nil {
+		return nil, err
+	}
+
+	if in.open == nil {
+		in.open = map[string]*Incident{}
+	}
+	in.open[key] = inc
+	return inc, nil
+}
+
+// postIncident posts and pins the header message of a new incident.
+func (h *TelegramHook) postIncident(key, title string) (*Incident, error) {
+	inc := &Incident{
+		h:      h,
+		cfg:    h.snapshot(),
+		key:    key,
+		title:  title,
+		opened: time.Now(),
+	}
+
+	result, err := h.callJSON(inc.cfg, "sendMessage", apiRequest{
+		ChatId:    inc.cfg.chatId,
+		ThreadId:  inc.cfg.threadId,
+		Text:      inc.cfg.parseMode.convert(inc.header()),
+		ParseMode: inc.cfg.parseMode.apiValue(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var msg apiMessage
+	if err := json.Unmarshal(result, &msg); err != nil {
+		return nil, err
+	}
+	inc.headerId = msg.MessageId
+
+	_, err = h.callJSON(inc.cfg, "pinChatMessage", pinMessageRequest{
+		ChatId:              inc.cfg.chatId,
+		MessageId:           inc.headerId,
+		DisableNotification: true,
+	})
+	if err != nil {
+		h.handleError(err)
+	}
+	return inc, nil
+}
+
+// Update posts a status note as a reply to the incident and shows it in the header.
+func (inc *Incident) Update(status string) error {
+	inc.mu.Lock()
+	defer inc.mu.Unlock()
+
+	if inc.resolved {
+		return fmt.Errorf("incident %q is resolved", inc.key)
+	}
+
+	inc.updates++
+	if _, err := inc.h.sendReply(context.Background(), inc.cfg, "<b>UPDATE</b> "+html.EscapeString(status), inc.reply()); err != nil {
+		return err
+	}
+	return inc.h.editMessage(inc.cfg, inc.headerId, inc.header()+"\n"+html.EscapeString(status))
+}
+
+// Resolve marks the incident as resolved in the header, unpins it and posts a
+// resolution summary with the duration of the incident.
+func (inc *Incident) Resolve(summary string) error {
+	inc.mu.Lock()
+	defer inc.mu.Unlock()
+
+	if inc.resolved {
+		return nil
+	}
+	inc.resolved = true
+
+	h := inc.h
+	h.incidents.mu.Lock()
+	delete(h.incidents.open, inc.key)
+	h.incidents.mu.Unlock()
+
+	if err := h.editMessage(inc.cfg, inc.headerId, inc.header()); err != nil {
+		h.handleError(err)
+	}
+
+	_, err := h.callJSON(inc.cfg, "unpinChatMessage", unpinMessageRequest{
+		ChatId:    inc.cfg.chatId,
+		MessageId: inc.headerId,
+	})
+	if err != nil {
+		h.handleError(err)
+	}
+
+	msg := fmt.Sprintf("<b>RESOLVED</b> after %s", inc.duration())
+	if summary != "" {
+		msg += "\n" + html.EscapeString(summary)
+	}
+	_, err = h.sendReply(context.Background(), inc.cfg, msg, inc.reply())
+	return err
+}
+
+// reply returns the reply parameters of follow-ups, which quote the title of
+// the incident in its header.
+func (inc *Incident) reply() replyParameters {
+	return replyParameters{MessageId: inc.headerId, Quote: quoteOf(html.EscapeString(inc.title))}
+}
+
+// header renders the header message for the current state of the incident.
+// The caller must hold the incident lock once the incident is shared.
+func (inc *Incident) header() string {
+	state := "INCIDENT"
+	opened, layout := inc.opened.UTC(), "2006-01-02 15:04"
+	if inc.cfg.timezone != nil {
+		opened = inc.opened.In(inc.cfg.timezone)
+	}
+	if l := inc.cfg.locale(); inc.cfg.localeTag != "" {
+		layout = l.date + " " + l.clock
+	}
+	detail := fmt.Sprintf("open since %s", opened.Format(layout+" MST"))
+	if inc.resolved {
+		state = "RESOLVED"
+		detail = fmt.Sprintf("lasted %s", inc.duration())
+	}
+	switch {
+	case inc.updates == 1:
+		detail += " · 1 update"
+	case inc.updates > 1:
+		detail += fmt.Sprintf(" · %d updates", inc.updates)
+	}
+
+	return fmt.Sprintf("<b>%s</b>@%s - %s\n<i>%s</i>",
+		state, html.EscapeString(inc.cfg.appName), html.EscapeString(inc.title), detail)
+}
+
+// duration returns how long the incident has been open.
+func (inc *Incident) duration() time.Duration {
+	return time.Since(inc.opened).Round(time.Second)
+}
+
+// incidentFor returns the open incident entry belongs to, nil if there is none.
+func (h *TelegramHook) incidentFor(entry *logrus.Entry) *Incident {
+	v, ok := entry.Data[IncidentKey]
+	if !ok {
+		return nil
+	}
+
+	h.incidents.mu.Lock()
+	defer h.incidents.mu.Unlock()
+	return h.incidents.open[fmt.Sprintf("%v", v)]
+}
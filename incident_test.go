@@ -0,0 +1,122 @@
+package telegramhook
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	log "github.com/andoma-go/logrus"
+)
+
+func TestIncident(t *testing.T) {
+	api := &fakeAPI{respond: func(method string, body []byte) *http.Response {
+		if method == "sendMessage" && strings.Contains(string(body), "INCIDENT") {
+			return jsonResponse(http.StatusOK, `{"ok":true,"result":{"message_id":7}}`)
+		}
+		return nil
+	}}
+	h := newTestHook()
+	h.client = api.client()
+
+	inc, err := h.OpenIncident("db", "Database unreachable")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := h.OpenIncident("db", "Database unreachable"); again != inc {
+		t.Error("Expected opening an open incident to return it")
+	}
+
+	if err := h.Fire(&log.Entry{Level: log.ErrorLevel, Message: "connection refused", Data: log.Fields{IncidentKey: "db"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := inc.Update("failing over to replica"); err != nil {
+		t.Fatal(err)
+	}
+	if err := inc.Resolve("replica promoted"); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Fire(&log.Entry{Level: log.ErrorLevel, Message: "late", Data: log.Fields{IncidentKey: "db"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"sendMessage", "pinChatMessage",
+		"sendMessage",
+		"sendMessage", "editMessageText",
+		"editMessageText", "unpinChatMessage", "sendMessage",
+		"sendMessage",
+	}
+	if got := api.methods(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Unexpected calls %v, want %v", got, want)
+	}
+
+	var replies []int64
+	var quotes []string
+	api.mu.Lock()
+	for _, c := range api.calls {
+		if c.method != "sendMessage" {
+			continue
+		}
+		var req apiRequest
+		if err := json.Unmarshal(c.body, &req); err != nil {
+			t.Fatal(err)
+		}
+		var id int64
+		if req.Reply != nil {
+			id = req.Reply.MessageId
+			quotes = append(quotes, req.Reply.Quote)
+		}
+		replies = append(replies, id)
+	}
+	api.mu.Unlock()
+
+	if want := []int64{0, 7, 7, 7, 0}; !reflect.DeepEqual(replies, want) {
+		t.Errorf("Messages replied to %v, want %v", replies, want)
+	}
+	if want := []string{"Database unreachable", "Database unreachable", "Database unreachable"}; !reflect.DeepEqual(quotes, want) {
+		t.Errorf("Replies quoted %q, want the incident title", quotes)
+	}
+
+	texts := api.texts()
+	if resolved := texts[len(texts)-2]; !strings.HasPrefix(resolved, "<b>RESOLVED</b> after ") || !strings.HasSuffix(resolved, "\nreplica promoted") {
+		t.Errorf("Unexpected resolution summary %q", resolved)
+	}
+}
+
+func TestOpenIncidentUnlocked(t *testing.T) {
+	var h *TelegramHook
+	entry := &log.Entry{Level: log.ErrorLevel, Data: log.Fields{IncidentKey: "db"}}
+	api := &fakeAPI{respond: func(method string, body []byte) *http.Response {
+		if method == "sendMessage" {
+			// Fire must not wait for the header to be posted
+			if h.incidentFor(entry) != nil {
+				t.Error("Incident published before its header was posted")
+			}
+			return jsonResponse(http.StatusOK, `{"ok":true,"result":{"message_id":7}}`)
+		}
+		return nil
+	}}
+	h = newTestHook()
+	h.client = api.client()
+
+	var wg sync.WaitGroup
+	opened := make([]*Incident, 2)
+	for i := range opened {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			opened[i], _ = h.OpenIncident("db", "Database unreachable")
+		}(i)
+	}
+	wg.Wait()
+
+	if opened[0] == nil || opened[0] != opened[1] || h.incidentFor(entry) != opened[0] {
+		t.Errorf("Expected one incident, got %p and %p", opened[0], opened[1])
+	}
+	if got := strings.Count(strings.Join(api.methods(), " "), "sendMessage"); got != 1 {
+		t.Errorf("Expected one header, got %d", got)
+	}
+}
@@ -0,0 +1,78 @@
+package telegramhook
+
+import (
+	"html"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxMessageLength is the Telegram limit for the text of a single message,
+// measured in UTF-16 code units after entity parsing.
+const maxMessageLength = 4096
+
+// runeLen returns the number of UTF-16 code units needed to encode r.
+func runeLen(r rune) int {
+	if r > 0xFFFF {
+		return 2
+	}
+	return 1
+}
+
+// textLen returns the length of s in UTF-16 code units, the way Telegram counts it.
+func textLen(s string) int {
+	n := 0
+	for _, r := range s {
+		n += runeLen(r)
+	}
+	return n
+}
+
+// htmlTextLen returns the length Telegram assigns to an HTML-formatted message:
+// tags are not counted and entities count as the characters they decode to.
+func htmlTextLen(s string) int {
+	n := 0
+	for i := 0; i < len(s); {
+		switch s[i] {
+		case '<':
+			end := strings.IndexByte(s[i:], '>')
+			if end < 0 {
+				return n + textLen(s[i:])
+			}
+			i += end + 1
+		case '&':
+			end := strings.IndexByte(s[i:], ';')
+			if end < 0 {
+				n++
+				i++
+				continue
+			}
+			n += textLen(html.UnescapeString(s[i : i+end+1]))
+			i += end + 1
+		default:
+			r, size := utf8.DecodeRuneInString(s[i:])
+			n += runeLen(r)
+			i += size
+		}
+	}
+	return n
+}
+
+// truncateText cuts s to at most limit UTF-16 code units without splitting a
+// surrogate pair, appending an ellipsis when anything was removed.
+func truncateText(s string, limit int) string {
+	if textLen(s) <= limit {
+		return s
+	}
+	if limit <= 0 {
+		return ""
+	}
+
+	n := 0
+	for i, r := range s {
+		if n+runeLen(r) > limit-1 {
+			return s[:i] + "…"
+		}
+		n += runeLen(r)
+	}
+	return s
+}
@@ -0,0 +1,35 @@
+package telegramhook
+
+import "testing"
+
+func TestTextLen(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int
+	}{
+		{"hello", 5},
+		{"привет", 6},
+		{"日本語", 3},
+		{"🔥🔥", 4},
+	}
+	for _, tt := range tests {
+		if got := textLen(tt.in); got != tt.want {
+			t.Errorf("textLen(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestHTMLTextLen(t *testing.T) {
+	if got := htmlTextLen("<b>ERROR</b> &lt;🔥&gt;"); got != 10 {
+		t.Errorf("htmlTextLen = %d, want 10", got)
+	}
+}
+
+func TestTruncateText(t *testing.T) {
+	if got := truncateText("🔥🔥🔥", 4); got != "🔥…" {
+		t.Errorf("truncateText split a surrogate pair: %q", got)
+	}
+	if got := truncateText("short", 10); got != "short" {
+		t.Errorf("truncateText modified a short string: %q", got)
+	}
+}
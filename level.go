@@ -0,0 +1,17 @@
+package telegramhook
+
+import "github.com/andoma-go/logrus"
+
+// LevelProvider supplies the least severe level that is sent, consulted on
+// every Fire, e.g. from a feature flag system or a remote config service.
+type LevelProvider interface {
+	Level() logrus.Level
+}
+
+// LevelProviderFunc adapts a function to the LevelProvider interface.
+type LevelProviderFunc func() logrus.Level
+
+// Level calls f().
+func (f LevelProviderFunc) Level() logrus.Level {
+	return f()
+}
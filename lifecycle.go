@@ -0,0 +1,71 @@
+package telegramhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"time"
+)
+
+// announceStartup sends the startup announcement after a random delay within
+// the configured spread, so that replicas starting at the same time do not
+// post at once.
+func (h *TelegramHook) announceStartup() {
+	spread, enabled := h.StartupAnnouncement()
+	if !enabled {
+		return
+	}
+
+	timer := time.NewTimer(randDuration(spread))
+	go func() {
+		defer timer.Stop()
+		select {
+		case <-h.done:
+		case <-timer.C:
+			cfg := h.snapshot()
+			if _, err := h.sendMessage(cfg, cfg.startupMessage()); err != nil {
+				h.handleError(err)
+			}
+		}
+	}()
+}
+
+// startupMessage renders the startup announcement.
+func (c *config) startupMessage() string {
+	return fmt.Sprintf("<b>INFO</b>@%s - started", html.EscapeString(c.appName)) + c.processFooter()
+}
+
+// echoConfig posts the redacted effective configuration to the configured
+// chat, so misconfigurations are visible in the chat history.
+func (h *TelegramHook) echoConfig() {
+	target, enabled := h.ConfigEcho()
+	if !enabled {
+		return
+	}
+
+	cfg := h.snapshot()
+	if target.ChatId != "" {
+		target.apply(&cfg)
+	}
+	msg, err := cfg.configMessage(h.Config())
+	if err != nil {
+		h.handleError(err)
+		return
+	}
+
+	go func() {
+		if _, err := h.sendMessage(cfg, msg); err != nil {
+			h.handleError(err)
+		}
+	}()
+}
+
+// configMessage renders the configuration echo.
+func (c *config) configMessage(ec EffectiveConfig) (string, error) {
+	data, err := json.MarshalIndent(ec, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("<b>INFO</b>@%s - alerting configuration, telegramhook %s\n<pre>%s</pre>",
+		html.EscapeString(c.appName), Version, html.EscapeString(string(data))), nil
+}
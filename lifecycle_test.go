@@ -0,0 +1,70 @@
+package telegramhook
+
+import (
+	"html"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAnnounceStartup(t *testing.T) {
+	api := &fakeAPI{}
+	h := newTestHook(WithStartupAnnouncement(10 * time.Millisecond))
+	h.client = api.client()
+	h.done = make(chan struct{})
+	defer h.Close()
+
+	h.announceStartup()
+
+	deadline := time.Now().Add(time.Second)
+	for len(api.texts()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if got, want := api.texts(), []string{"<b>INFO</b>@testing - started"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Announced %q, want %q", got, want)
+	}
+}
+
+func TestAnnounceStartupClosed(t *testing.T) {
+	api := &fakeAPI{}
+	h := newTestHook(WithStartupAnnouncement(time.Hour))
+	h.client = api.client()
+	h.done = make(chan struct{})
+
+	h.announceStartup()
+	h.Close()
+
+	time.Sleep(10 * time.Millisecond)
+	if got := api.texts(); len(got) != 0 {
+		t.Errorf("Expected no announcement after Close, got %q", got)
+	}
+}
+
+func TestEchoConfig(t *testing.T) {
+	api := &fakeAPI{}
+	h := newTestHook(WithConfigEcho(Chat("-100admin")), WithRateLimit(RateLimit{PerChat: 2}))
+	h.authToken = "123:secret"
+	h.client = api.client()
+
+	h.echoConfig()
+
+	deadline := time.Now().Add(time.Second)
+	for len(api.texts()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	texts := api.texts()
+	if len(texts) != 1 || !strings.Contains(string(api.calls[0].body), `"chat_id":"-100admin"`) {
+		t.Fatalf("Expected one message to the admin chat, got %q", texts)
+	}
+	text := html.UnescapeString(texts[0])
+	for _, want := range []string{"alerting configuration, telegramhook " + Version, `"token": "123:***"`, `"PerChat": 2`} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Expected %q in %s", want, text)
+		}
+	}
+	if strings.Contains(text, "secret") {
+		t.Errorf("The token was not redacted: %s", text)
+	}
+}
@@ -0,0 +1,118 @@
+package telegramhook
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// Telegram's documented limits in messages per minute.
+const (
+	botLimit     = 30 * 60 // across all chats
+	groupLimit   = 20      // to a group or channel
+	privateLimit = 60      // to a private chat
+)
+
+// LimitWarnings reports when the send volume approaches Telegram's limits,
+// before requests start failing with 429 Too Many Requests.
+type LimitWarnings struct {
+	// Threshold is the share of a limit from which on warnings are reported,
+	// 0.8 when zero.
+	Threshold float64
+	// Handler is called with each warning, at most once a minute per chat and
+	// limit. It must not block.
+	Handler func(LimitWarning)
+}
+
+func (l *LimitWarnings) threshold() float64 {
+	if l.Threshold > 0 {
+		return l.Threshold
+	}
+	return 0.8
+}
+
+// LimitWarning describes a send volume close to a Telegram limit.
+type LimitWarning struct {
+	// Limit is "bot" for the limit across all chats, otherwise "group" or
+	// "private" for the limit of ChatId.
+	Limit  string
+	ChatId string
+	// Sent is the number of messages sent in the last minute.
+	Sent int
+	// Max is the number of messages Telegram allows per minute.
+	Max int
+}
+
+// sendVolume counts the messages sent in the last minute, across all chats and
+// per chat.
+type sendVolume struct {
+	mu     sync.Mutex
+	bot    []time.Time
+	chats  map[string][]time.Time
+	warned map[string]time.Time // last warning per limit and chat
+}
+
+// chatLimit returns the kind and per-minute limit of chatId. Groups and
+// channels have negative IDs or are addressed by @username.
+func chatLimit(chatId string) (string, int) {
+	if strings.HasPrefix(chatId, "-") || strings.HasPrefix(chatId, "@") {
+		return "group", groupLimit
+	}
+	return "private", privateLimit
+}
+
+// record counts a message sent to chatId and returns the warnings for the
+// limits reached, if any.
+func (v *sendVolume) record(now time.Time, chatId string, threshold float64) []LimitWarning {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.chats == nil {
+		v.chats = map[string][]time.Time{}
+		v.warned = map[string]time.Time{}
+	}
+	v.bot = countMinute(v.bot, now)
+	v.chats[chatId] = countMinute(v.chats[chatId], now)
+
+	kind, max := chatLimit(chatId)
+	var warnings []LimitWarning
+	for _, w := range []LimitWarning{
+		{Limit: "bot", Sent: len(v.bot), Max: botLimit},
+		{Limit: kind, ChatId: chatId, Sent: len(v.chats[chatId]), Max: max},
+	} {
+		key := w.Limit + "/" + w.ChatId
+		if float64(w.Sent) < threshold*float64(w.Max) || now.Sub(v.warned[key]) < time.Minute {
+			continue
+		}
+		v.warned[key] = now
+		warnings = append(warnings, w)
+	}
+	return warnings
+}
+
+// countMinute appends now to times and drops the times older than a minute.
+func countMinute(times []time.Time, now time.Time) []time.Time {
+	times = append(times, now)
+	i := 0
+	for i < len(times) && now.Sub(times[i]) >= time.Minute {
+		i++
+	}
+	if i > 0 {
+		times = append(times[:0], times[i:]...)
+	}
+	return times
+}
+
+// trackVolume counts a message sent with cfg and reports the warnings.
+func (h *TelegramHook) trackVolume(cfg config) {
+	if cfg.limitWarnings == nil {
+		return
+	}
+
+	for _, w := range h.volume.record(time.Now(), cfg.chatId, cfg.limitWarnings.threshold()) {
+		h.stats.warnings.Add(1)
+		if cfg.limitWarnings.Handler != nil {
+			cfg.limitWarnings.Handler(w)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package telegramhook
+
+import (
+	"testing"
+	"time"
+
+	log "github.com/andoma-go/logrus"
+)
+
+func TestSendVolume(t *testing.T) {
+	var v sendVolume
+	now := time.Now()
+
+	for i := 1; i < 16; i++ {
+		if w := v.record(now, "-100", 0.8); w != nil {
+			t.Fatalf("Unexpected warning after %d messages: %+v", i, w)
+		}
+	}
+	w := v.record(now, "-100", 0.8)
+	if len(w) != 1 || w[0] != (LimitWarning{Limit: "group", ChatId: "-100", Sent: 16, Max: groupLimit}) {
+		t.Fatalf("Expected a group warning at 80%%, got %+v", w)
+	}
+	if w := v.record(now, "-100", 0.8); w != nil {
+		t.Errorf("Expected a single warning per minute, got %+v", w)
+	}
+
+	later := now.Add(time.Minute)
+	if w := v.record(later, "-100", 0.8); w != nil {
+		t.Errorf("Expected old messages to expire, got %+v", w)
+	}
+}
+
+func TestLimitWarnings(t *testing.T) {
+	var warnings []LimitWarning
+	api := &fakeAPI{}
+	h := newTestHook(
+		WithChatTarget(User(42)),
+		WithLimitWarnings(LimitWarnings{Threshold: 0.1, Handler: func(w LimitWarning) {
+			warnings = append(warnings, w)
+		}}),
+	)
+	h.client = api.client()
+
+	for i := 0; i < 7; i++ {
+		if err := h.Fire(&log.Entry{Level: log.ErrorLevel, Message: "m"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(warnings) != 1 || warnings[0].Limit != "private" || warnings[0].Sent != 6 {
+		t.Errorf("Unexpected warnings %+v", warnings)
+	}
+	if n := h.Stats().LimitWarnings; n != 1 {
+		t.Errorf("Expected the warning to be counted, got %d", n)
+	}
+}
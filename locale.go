@@ -0,0 +1,112 @@
+package telegramhook
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// locale describes how numbers, dates and times are written for a language.
+type locale struct {
+	group   string // thousands separator, empty for no grouping
+	decimal string // decimal separator
+	date    string // date layout
+	clock   string // time of day layout
+}
+
+// defaultLocale keeps Go's formatting: no grouping, ISO dates and a 24h clock.
+var defaultLocale = locale{decimal: ".", date: "2006-01-02", clock: "15:04:05"}
+
+// locales maps lower-case language tags to their formatting. Tags with a
+// region fall back to the language, e.g. "de-AT" to "de".
+var locales = map[string]locale{
+	"en":    {group: ",", decimal: ".", date: "Jan 2, 2006", clock: "3:04:05 PM"},
+	"en-gb": {group: ",", decimal: ".", date: "2 Jan 2006", clock: "15:04:05"},
+	"de":    {group: ".", decimal: ",", date: "02.01.2006", clock: "15:04:05"},
+	"de-ch": {group: "\u2019", decimal: ".", date: "02.01.2006", clock: "15:04:05"},
+	"fr":    {group: "\u202f", decimal: ",", date: "02/01/2006", clock: "15:04:05"},
+	"es":    {group: ".", decimal: ",", date: "02/01/2006", clock: "15:04:05"},
+	"it":    {group: ".", decimal: ",", date: "02/01/2006", clock: "15:04:05"},
+	"pt":    {group: ".", decimal: ",", date: "02/01/2006", clock: "15:04:05"},
+	"nl":    {group: ".", decimal: ",", date: "02-01-2006", clock: "15:04:05"},
+	"pl":    {group: "\u00a0", decimal: ",", date: "02.01.2006", clock: "15:04:05"},
+	"ru":    {group: "\u00a0", decimal: ",", date: "02.01.2006", clock: "15:04:05"},
+	"uk":    {group: "\u00a0", decimal: ",", date: "02.01.2006", clock: "15:04:05"},
+	"sv":    {group: "\u00a0", decimal: ",", date: "2006-01-02", clock: "15:04:05"},
+	"ja":    {group: ",", decimal: ".", date: "2006/01/02", clock: "15:04:05"},
+	"zh":    {group: ",", decimal: ".", date: "2006/01/02", clock: "15:04:05"},
+}
+
+// lookupLocale returns the formatting for tag, e.g. "de", "en-GB" or "pt_BR".
+func lookupLocale(tag string) (locale, bool) {
+	tag = strings.ToLower(strings.ReplaceAll(tag, "_", "-"))
+	if l, ok := locales[tag]; ok {
+		return l, true
+	}
+	if i := strings.IndexByte(tag, '-'); i > 0 {
+		if l, ok := locales[tag[:i]]; ok {
+			return l, true
+		}
+	}
+	return defaultLocale, false
+}
+
+// locale returns the configured formatting, the default one when no or an
+// unknown locale is configured.
+func (c *config) locale() locale {
+	l, _ := lookupLocale(c.localeTag)
+	return l
+}
+
+// inZone converts t to the configured time zone, leaving it unchanged when
+// none is configured.
+func (c *config) inZone(t time.Time) time.Time {
+	if c.timezone == nil {
+		return t
+	}
+	return t.In(c.timezone)
+}
+
+// integer formats n with thousands separators, e.g. "1,234,567".
+func (l locale) integer(n int64) string {
+	return l.number(strconv.FormatInt(n, 10))
+}
+
+// number localizes a number formatted by Go, e.g. "-1234.5" becomes
+// "-1.234,5" for German.
+func (l locale) number(s string) string {
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+
+	whole, frac, hasFrac := strings.Cut(s, ".")
+	if l.group != "" {
+		var b strings.Builder
+		for i, r := range whole {
+			if i > 0 && (len(whole)-i)%3 == 0 {
+				b.WriteString(l.group)
+			}
+			b.WriteRune(r)
+		}
+		whole = b.String()
+	}
+
+	if hasFrac {
+		return sign + whole + l.decimal + frac
+	}
+	return sign + whole
+}
+
+// decimals replaces the decimal points in s, e.g. in "1.2s" or "3.4 MiB".
+func (l locale) decimals(s string) string {
+	if l.decimal == "." {
+		return s
+	}
+	return strings.ReplaceAll(s, ".", l.decimal)
+}
+
+// dateTime formats t as date and time of day.
+func (l locale) dateTime(t time.Time) string {
+	return t.Format(l.date + " " + l.clock)
+}
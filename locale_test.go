@@ -0,0 +1,64 @@
+package telegramhook
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	log "github.com/andoma-go/logrus"
+)
+
+func TestLocaleNumber(t *testing.T) {
+	tests := []struct {
+		tag  string
+		in   string
+		want string
+	}{
+		{"", "1234567.5", "1234567.5"},
+		{"en", "1234567.5", "1,234,567.5"},
+		{"de-AT", "-1234567.5", "-1.234.567,5"},
+		{"fr_FR", "1234", "1\u202f234"},
+		{"de", "123", "123"},
+	}
+	for _, tt := range tests {
+		l, _ := lookupLocale(tt.tag)
+		if got := l.number(tt.in); got != tt.want {
+			t.Errorf("number(%q) for %q = %q, want %q", tt.in, tt.tag, got, tt.want)
+		}
+	}
+}
+
+func TestLocaleMessage(t *testing.T) {
+	berlin := time.FixedZone("CET", 3600)
+	entry := &log.Entry{
+		Level:   log.ErrorLevel,
+		Message: "slow upload",
+		Data: log.Fields{
+			"took":       1200 * time.Millisecond,
+			"size_bytes": 2048,
+			"rows":       1234567,
+			"at":         time.Date(2024, 3, 9, 13, 4, 5, 0, time.UTC),
+			"user_id":    1234567,
+			"orderID":    uint64(9876543),
+			"port":       8443,
+			"year":       2024,
+		},
+	}
+
+	msg := createMessage(newTestHook(WithHumanize(true), WithLocale("de"), WithTimezone(berlin)), entry)
+	for _, want := range []string{"took: 1,2s", "size_bytes: 2,0 KiB", "rows: 1.234.567", "at: 09.03.2024 14:04:05",
+		"user_id: 1234567", "orderID: 9876543", "port: 8443", "year: 2024"} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("Expected %q in:\n%s", want, msg)
+		}
+	}
+}
+
+func TestLocaleUnsupported(t *testing.T) {
+	var cerr *ConfigError
+	_, err := NewTelegramHookWithClient("app", "123:abc", "1", "", (&fakeAPI{}).client(), WithLocale("tlh"))
+	if !errors.As(err, &cerr) || cerr.Field != "locale" {
+		t.Errorf("Expected a locale ConfigError, got %v", err)
+	}
+}
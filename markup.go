@@ -0,0 +1,171 @@
+package telegramhook
+
+import (
+	"html"
+	"strings"
+)
+
+// ParseMode selects how Telegram formats the text of messages. Messages are
+// rendered as HTML and converted to the parse mode when they are sent, so
+// formatters and templates always produce HTML.
+type ParseMode int
+
+const (
+	// ParseModeDefault is HTML for a hook; a ChatTarget with it keeps the
+	// parse mode of the hook.
+	ParseModeDefault ParseMode = iota
+	// ParseModeHTML sends messages with the HTML parse mode.
+	ParseModeHTML
+	// ParseModeMarkdownV2 sends messages with the MarkdownV2 parse mode.
+	ParseModeMarkdownV2
+	// ParseModePlain sends messages without any formatting.
+	ParseModePlain
+)
+
+func (m ParseMode) String() string {
+	switch m {
+	case ParseModeMarkdownV2:
+		return "MarkdownV2"
+	case ParseModePlain:
+		return "plain"
+	}
+	return "HTML"
+}
+
+// apiValue returns the parse_mode of API requests, empty for plain text.
+func (m ParseMode) apiValue() string {
+	if m == ParseModePlain {
+		return ""
+	}
+	return m.String()
+}
+
+// convert converts an HTML-formatted message to the parse mode.
+func (m ParseMode) convert(s string) string {
+	switch m {
+	case ParseModeMarkdownV2:
+		return htmlToMarkdownV2(s)
+	case ParseModePlain:
+		return html.UnescapeString(stripTags(s))
+	}
+	return s
+}
+
+// markdownV2Special are the characters that must be escaped in MarkdownV2 text.
+const markdownV2Special = "_*[]()~`>#+-=|{}.!\\"
+
+// escapeMarkdownV2 escapes s for MarkdownV2. Inside code and pre blocks only
+// backticks and backslashes need escaping.
+func escapeMarkdownV2(s string, code bool) string {
+	special := markdownV2Special
+	if code {
+		special = "`\\"
+	}
+
+	var b strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune(special, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// markdownV2Tags maps HTML tags to their MarkdownV2 markers.
+var markdownV2Tags = map[string]string{
+	"b": "*", "strong": "*",
+	"i": "_", "em": "_",
+	"u": "__", "ins": "__",
+	"s": "~", "strike": "~", "del": "~",
+	"code":       "`",
+	"tg-spoiler": "||",
+}
+
+// htmlToMarkdownV2 converts an HTML-formatted message to MarkdownV2. Text is
+// escaped, tags are replaced by their markers and unsupported tags dropped.
+func htmlToMarkdownV2(s string) string {
+	tokens := tokenizeHTML(s)
+
+	var b strings.Builder
+	var links []string
+	code, pre := 0, 0
+	for i, t := range tokens {
+		switch {
+		case t.tag == "":
+			b.WriteString(escapeMarkdownV2(html.UnescapeString(t.text), code > 0))
+
+		case t.tag == "pre":
+			if t.closing {
+				b.WriteString("```")
+				code--
+				pre--
+				continue
+			}
+			// The first line of a pre block names its language, taken from a
+			// <code class="language-..."> tag right inside it.
+			b.WriteString("```")
+			if i+1 < len(tokens) && tokens[i+1].tag == "code" && !tokens[i+1].closing {
+				b.WriteString(codeLanguage(tokens[i+1].text))
+				b.WriteString("\n")
+			} else if i+1 >= len(tokens) || tokens[i+1].text != "\n" {
+				b.WriteString("\n")
+			}
+			code++
+			pre++
+
+		case t.tag == "code" && pre > 0:
+			// The pre block already is a code block.
+
+		case t.tag == "a":
+			if !t.closing {
+				links = append(links, linkTarget(t.text))
+				b.WriteString("[")
+				continue
+			}
+			if n := len(links); n > 0 {
+				b.WriteString("](" + strings.NewReplacer(`\`, `\\`, ")", `\)`).Replace(links[n-1]) + ")")
+				links = links[:n-1]
+			}
+
+		default:
+			if marker, ok := markdownV2Tags[t.tag]; ok {
+				b.WriteString(marker)
+				if t.tag == "code" {
+					if t.closing {
+						code--
+					} else {
+						code++
+					}
+				}
+			}
+		}
+	}
+	return b.String()
+}
+
+// codeLanguage returns the language of an opening <code> tag from its
+// class="language-..." attribute, empty if it names none.
+func codeLanguage(tag string) string {
+	_, class, ok := strings.Cut(tag, `class="`)
+	if !ok {
+		return ""
+	}
+	class, _, _ = strings.Cut(class, `"`)
+	for _, name := range strings.Fields(class) {
+		if lang, ok := strings.CutPrefix(name, "language-"); ok {
+			return escapeMarkdownV2(html.UnescapeString(lang), true)
+		}
+	}
+	return ""
+}
+
+// linkTarget returns the unescaped href of an opening <a> tag.
+func linkTarget(tag string) string {
+	_, href, ok := strings.Cut(tag, `href="`)
+	if !ok {
+		return ""
+	}
+	href, _, _ = strings.Cut(href, `"`)
+	return html.UnescapeString(href)
+}
@@ -0,0 +1,51 @@
+package telegramhook
+
+import (
+	"encoding/json"
+	"testing"
+
+	log "github.com/andoma-go/logrus"
+)
+
+func TestParseModeConvert(t *testing.T) {
+	msg := "<b>ERROR</b>@my-app - 1.5 &lt; 2 &amp; <a href=\"https://x.example/a_(b)\">docs</a>\n<pre>\n\tpath: a_b`c\n</pre>"
+	tests := []struct {
+		mode ParseMode
+		want string
+	}{
+		{ParseModeHTML, msg},
+		{ParseModeMarkdownV2, "*ERROR*@my\\-app \\- 1\\.5 < 2 & [docs](https://x.example/a_(b\\))\n```\n\tpath: a_b\\`c\n```"},
+		{ParseModePlain, "ERROR@my-app - 1.5 < 2 & docs\n\n\tpath: a_b`c\n"},
+	}
+	for _, tt := range tests {
+		if got := tt.mode.convert(msg); got != tt.want {
+			t.Errorf("%s:\n got %q\nwant %q", tt.mode, got, tt.want)
+		}
+	}
+}
+
+func TestMarkdownV2CodeBlock(t *testing.T) {
+	msg := fieldBlock{title: "payload", body: "{\"a\": \"`x`\"}", lang: "json"}.html()
+	want := "*payload*\n```json\n{\"a\": \"\\`x\\`\"}```"
+	if got := htmlToMarkdownV2(msg); got != want {
+		t.Errorf("got %q\nwant %q", got, want)
+	}
+}
+
+func TestParseModeRequest(t *testing.T) {
+	api := &fakeAPI{}
+	h := newTestHook(WithParseMode(ParseModeMarkdownV2))
+	h.client = api.client()
+
+	if err := h.Fire(&log.Entry{Level: log.ErrorLevel, Message: "v1.2 failed!"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var req apiRequest
+	if err := json.Unmarshal(api.calls[0].body, &req); err != nil {
+		t.Fatal(err)
+	}
+	if req.ParseMode != "MarkdownV2" || req.Text != "*ERROR*@testing \\- v1\\.2 failed\\!" {
+		t.Errorf("Unexpected request %+v", req)
+	}
+}
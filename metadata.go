@@ -0,0 +1,49 @@
+package telegramhook
+
+import (
+	"os"
+	"sync"
+
+	"github.com/andoma-go/logrus"
+)
+
+// HostnameKey is the field WithHostname adds to every entry.
+const HostnameKey = "hostname"
+
+var (
+	hostnameOnce sync.Once
+	hostnameName string
+)
+
+// hostname returns the name of the host, looked up once.
+func hostname() string {
+	hostnameOnce.Do(func() {
+		name, err := os.Hostname()
+		if err != nil {
+			name = "unknown"
+		}
+		hostnameName = name
+	})
+	return hostnameName
+}
+
+// addMetadata returns entry, or a copy with the static fields and the
+// hostname added. Fields of the entry take precedence.
+func (c *config) addMetadata(entry *logrus.Entry) *logrus.Entry {
+	if !c.hostname && len(c.staticFields) == 0 {
+		return entry
+	}
+
+	e := *entry
+	e.Data = make(logrus.Fields, len(entry.Data)+len(c.staticFields)+1)
+	for k, v := range c.staticFields {
+		e.Data[k] = v
+	}
+	if c.hostname {
+		e.Data[HostnameKey] = hostname()
+	}
+	for k, v := range entry.Data {
+		e.Data[k] = v
+	}
+	return &e
+}
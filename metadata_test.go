@@ -0,0 +1,32 @@
+package telegramhook
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	log "github.com/andoma-go/logrus"
+)
+
+func TestStaticFields(t *testing.T) {
+	h := newTestHook(WithHostname(true), WithStaticFields(log.Fields{"env": "prod", "region": "eu-1"}))
+	cfg := h.snapshot()
+
+	entry := &log.Entry{Level: log.ErrorLevel, Message: "disk full", Data: log.Fields{"region": "eu-2"}}
+	msg := cfg.createMessage(cfg.addMetadata(entry))
+
+	host, _ := os.Hostname()
+	for _, want := range []string{"\thostname: " + host, "\tenv: prod", "\tregion: eu-2"} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("Expected %q in message %q", want, msg)
+		}
+	}
+	if len(entry.Data) != 1 {
+		t.Errorf("addMetadata modified the entry: %v", entry.Data)
+	}
+
+	plain := newTestHook().snapshot()
+	if plain.addMetadata(entry) != entry {
+		t.Error("addMetadata copied the entry without static fields or hostname")
+	}
+}
@@ -0,0 +1,106 @@
+package telegramhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/andoma-go/logrus"
+)
+
+// noiseFilter recognizes a notoriously noisy error that is usually not worth
+// an alert, e.g. a client that went away.
+type noiseFilter struct {
+	name   string
+	target error
+	text   []string // substrings of the message or error text
+}
+
+// commonNoise are the filters enabled by WithCommonNoiseFilters.
+var commonNoise = []noiseFilter{
+	{name: "context canceled", target: context.Canceled, text: []string{"context canceled"}},
+	{name: "deadline exceeded", target: context.DeadlineExceeded, text: []string{"context deadline exceeded"}},
+	{name: "broken pipe", target: syscall.EPIPE, text: []string{"broken pipe"}},
+	{name: "connection reset", target: syscall.ECONNRESET, text: []string{"connection reset by peer"}},
+	{name: "EOF", target: io.EOF, text: []string{": EOF", "unexpected EOF"}},
+}
+
+// noise returns the name of the common noise filter entry matches, empty if
+// it matches none. The error field is matched with errors.Is, the message and
+// the error text by substring.
+func noise(entry *logrus.Entry) string {
+	err, _ := entry.Data[logrus.ErrorKey].(error)
+	errText := ""
+	if err != nil {
+		errText = err.Error()
+	}
+
+	for _, f := range commonNoise {
+		if err != nil && errors.Is(err, f.target) {
+			return f.name
+		}
+		for _, text := range f.text {
+			if strings.Contains(entry.Message, text) || strings.Contains(errText, text) {
+				return f.name
+			}
+		}
+	}
+	return ""
+}
+
+// noiseCounts counts entries suppressed by the noise filters by filter name.
+type noiseCounts struct {
+	mu     sync.Mutex
+	counts map[string]uint64
+}
+
+// add counts an entry suppressed by filter name.
+func (n *noiseCounts) add(name string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.counts == nil {
+		n.counts = map[string]uint64{}
+	}
+	n.counts[name]++
+}
+
+// snapshot returns a copy of the counts.
+func (n *noiseCounts) snapshot() map[string]uint64 {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	counts := make(map[string]uint64, len(n.counts))
+	for k, v := range n.counts {
+		counts[k] = v
+	}
+	return counts
+}
+
+// noiseSummary describes suppressed entries for digests, e.g.
+// "suppressed: 12 context canceled · 3 broken pipe", empty if there are none.
+func noiseSummary(counts map[string]uint64) string {
+	if len(counts) == 0 {
+		return ""
+	}
+
+	names := make([]string, 0, len(counts))
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if counts[names[i]] != counts[names[j]] {
+			return counts[names[i]] > counts[names[j]]
+		}
+		return names[i] < names[j]
+	})
+
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		parts = append(parts, fmt.Sprintf("%d %s", counts[name], name))
+	}
+	return "suppressed: " + strings.Join(parts, " · ")
+}
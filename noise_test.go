@@ -0,0 +1,57 @@
+package telegramhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+
+	log "github.com/andoma-go/logrus"
+)
+
+func TestNoise(t *testing.T) {
+	pipe := &net.OpError{Op: "write", Net: "tcp", Err: os.NewSyscallError("write", syscall.EPIPE)}
+	tests := []struct {
+		entry *log.Entry
+		want  string
+	}{
+		{&log.Entry{Message: "request failed", Data: log.Fields{log.ErrorKey: fmt.Errorf("query: %w", context.Canceled)}}, "context canceled"},
+		{&log.Entry{Message: "write response", Data: log.Fields{log.ErrorKey: pipe}}, "broken pipe"},
+		{&log.Entry{Message: "read body: unexpected EOF"}, "EOF"},
+		{&log.Entry{Message: "upstream: context deadline exceeded"}, "deadline exceeded"},
+		{&log.Entry{Message: "disk full", Data: log.Fields{log.ErrorKey: errors.New("no space left on device")}}, ""},
+	}
+	for _, tt := range tests {
+		if got := noise(tt.entry); got != tt.want {
+			t.Errorf("noise(%q) = %q, want %q", tt.entry.Message, got, tt.want)
+		}
+	}
+}
+
+func TestCommonNoiseFilters(t *testing.T) {
+	api := &fakeAPI{}
+	h := newTestHook(WithCommonNoiseFilters(true))
+	h.client = api.client()
+
+	for i := 0; i < 2; i++ {
+		if err := h.Fire(&log.Entry{Level: log.ErrorLevel, Message: "request failed", Data: log.Fields{log.ErrorKey: context.Canceled}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := h.Fire(&log.Entry{Level: log.ErrorLevel, Message: "disk full"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Fire(&log.Entry{Level: log.FatalLevel, Message: "shutdown", Data: log.Fields{log.ErrorKey: context.Canceled}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := len(api.methods()); n != 2 {
+		t.Errorf("Expected the real error and the fatal entry to be sent, got %d messages", n)
+	}
+	if got := noiseSummary(h.Stats().Suppressed); got != "suppressed: 2 context canceled" {
+		t.Errorf("Unexpected summary %q", got)
+	}
+}
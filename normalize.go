@@ -0,0 +1,95 @@
+package telegramhook
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Normalizer rewrites a log message into a form with lower cardinality, so
+// that messages differing only in IDs or values share a signature.
+type Normalizer func(msg string) string
+
+var (
+	uuidPattern   = regexp.MustCompile(`\b[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}\b`)
+	hexPattern    = regexp.MustCompile(`\b(?:0[xX][0-9a-fA-F]+|[0-9a-fA-F]{8,})\b`)
+	numberPattern = regexp.MustCompile(`\d+(?:\.\d+)?`)
+)
+
+// NormalizeQuoted replaces quoted strings with "<str>". Quotes only count at
+// word boundaries, so apostrophes as in "can't find user's" are kept.
+func NormalizeQuoted(msg string) string {
+	return replaceQuoted(replaceQuoted(msg, '"'), '\'')
+}
+
+// replaceQuoted replaces the strings enclosed in q with "<str>", where the
+// opening q does not follow and the closing q is not followed by a letter or
+// digit.
+func replaceQuoted(msg string, q byte) string {
+	var b strings.Builder
+	last := 0
+	for i := 0; i < len(msg); i++ {
+		if msg[i] != q || wordBefore(msg, i) {
+			continue
+		}
+		end := -1
+		for j := i + 1; j < len(msg); j++ {
+			if msg[j] == q && !wordAfter(msg, j+1) {
+				end = j
+				break
+			}
+		}
+		if end < 0 {
+			break
+		}
+		b.WriteString(msg[last:i])
+		b.WriteString("<str>")
+		last, i = end+1, end
+	}
+	if last == 0 {
+		return msg
+	}
+	b.WriteString(msg[last:])
+	return b.String()
+}
+
+// wordBefore reports whether a letter or digit ends msg[:i].
+func wordBefore(msg string, i int) bool {
+	r, n := utf8.DecodeLastRuneInString(msg[:i])
+	return n > 0 && (unicode.IsLetter(r) || unicode.IsDigit(r))
+}
+
+// wordAfter reports whether a letter or digit starts msg[i:].
+func wordAfter(msg string, i int) bool {
+	r, n := utf8.DecodeRuneInString(msg[i:])
+	return n > 0 && (unicode.IsLetter(r) || unicode.IsDigit(r))
+}
+
+// NormalizeUUIDs replaces UUIDs with "<uuid>".
+func NormalizeUUIDs(msg string) string {
+	return uuidPattern.ReplaceAllString(msg, "<uuid>")
+}
+
+// NormalizeHexIDs replaces 0x-prefixed hex numbers and hex strings of at least
+// 8 characters that contain a digit, such as hashes and trace IDs, with "<hex>".
+func NormalizeHexIDs(msg string) string {
+	return hexPattern.ReplaceAllStringFunc(msg, func(s string) string {
+		if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") || strings.ContainsAny(s, "0123456789") {
+			return "<hex>"
+		}
+		return s
+	})
+}
+
+// NormalizeNumbers replaces integers and decimals with "<n>".
+func NormalizeNumbers(msg string) string {
+	return numberPattern.ReplaceAllString(msg, "<n>")
+}
+
+// DefaultNormalizers returns normalizers that strip quoted strings, UUIDs, hex
+// IDs and numbers, in that order, so that the more specific patterns match
+// first.
+func DefaultNormalizers() []Normalizer {
+	return []Normalizer{NormalizeQuoted, NormalizeUUIDs, NormalizeHexIDs, NormalizeNumbers}
+}
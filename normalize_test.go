@@ -0,0 +1,41 @@
+package telegramhook
+
+import (
+	"testing"
+
+	log "github.com/andoma-go/logrus"
+)
+
+func TestDefaultNormalizers(t *testing.T) {
+	tests := []struct {
+		msg, want string
+	}{
+		{"failed for user 123", "failed for user <n>"},
+		{"order 6f1c2a3e-9b7d-4e1f-8a2b-0c3d4e5f6a7b not found", "order <uuid> not found"},
+		{"trace 4bf92f3577b34da6 at 0x7ffd", "trace <hex> at <hex>"},
+		{`unknown key "user.name" after 2.5s`, "unknown key <str> after <n>s"},
+		{"accepted deadbeef", "accepted deadbeef"},
+		{"can't find user's cart 'c-1' or 'c-2'", "can't find user's cart <str> or <str>"},
+		{"key 'it's' missing", "key <str> missing"},
+		{"unterminated 'quote", "unterminated 'quote"},
+	}
+
+	h := newTestHook(WithSignatureNormalizers(DefaultNormalizers()...))
+	cfg := h.snapshot()
+	for _, tt := range tests {
+		if got := cfg.signature(&log.Entry{Level: log.ErrorLevel, Message: tt.msg}); got != "error: "+tt.want {
+			t.Errorf("signature(%q) = %q, want %q", tt.msg, got, "error: "+tt.want)
+		}
+	}
+}
+
+func TestCustomNormalizer(t *testing.T) {
+	h := newTestHook(WithSignatureNormalizers(func(msg string) string { return "same" }))
+	cfg := h.snapshot()
+
+	a := cfg.signature(&log.Entry{Level: log.ErrorLevel, Message: "a"})
+	b := cfg.signature(&log.Entry{Level: log.ErrorLevel, Message: "b"})
+	if a != b {
+		t.Errorf("Expected a custom normalizer to group signatures, got %q and %q", a, b)
+	}
+}
@@ -0,0 +1,190 @@
+package telegramhook
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/andoma-go/logrus"
+)
+
+// defaultOutboxInterval is how often the dispatcher polls the outbox.
+const defaultOutboxInterval = time.Second
+
+// outboxBatch is the number of messages the dispatcher fetches at once.
+const outboxBatch = 100
+
+// OutboxMessage is a rendered message stored in an Outbox, with everything
+// needed to deliver it as it would have been sent directly. All fields can be
+// stored as JSON.
+type OutboxMessage struct {
+	ID       string
+	ChatId   string
+	ThreadId string
+	Text     string
+	Created  time.Time
+
+	Level     logrus.Level
+	Key       string // correlation key, see CorrelationKey
+	Topic     string // forum topic name, see WithAutoTopics
+	ReplyTo   int64  // message the entry replies to, see IncidentKey
+	Quote     string // part of the replied message that is quoted
+	Silent    bool
+	ParseMode ParseMode
+	// Alarm reports whether the message is sent by the alarm bot, see
+	// WithAlarmBot. The token itself is not stored.
+	Alarm       bool
+	Document    string // name of the fields document, see WithFieldsDocument
+	Content     []byte // content of the fields document
+	Attachments []Attachment
+}
+
+// Outbox stores messages before they are sent, e.g. in a table written in the
+// same database transaction as the change that caused the alert. Put receives
+// the context of the log entry, so an implementation can pick up a
+// transaction from it.
+type Outbox interface {
+	// Put stores a message to be sent.
+	Put(ctx context.Context, msg OutboxMessage) error
+	// List returns up to limit unsent messages, oldest first.
+	List(ctx context.Context, limit int) ([]OutboxMessage, error)
+	// MarkSent records that the message with the given ID was sent.
+	MarkSent(ctx context.Context, id string) error
+	// MarkFailed records that Telegram rejected the message with the given
+	// ID, e.g. for an unknown chat. It is not listed again.
+	MarkFailed(ctx context.Context, id string, err error) error
+}
+
+// putOutbox stores a rendered message in the outbox instead of sending it.
+func (h *TelegramHook) putOutbox(ctx context.Context, cfg config, out outgoing) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return cfg.outbox.Put(ctx, outboxMessage(cfg, out))
+}
+
+// outboxMessage returns the storable form of out delivered with cfg.
+func outboxMessage(cfg config, out outgoing) OutboxMessage {
+	m := OutboxMessage{
+		ID:          newOutboxID(),
+		ChatId:      cfg.chatId,
+		ThreadId:    cfg.threadId,
+		Text:        out.msg,
+		Created:     time.Now(),
+		Level:       out.level,
+		Key:         out.key,
+		Topic:       out.topic,
+		ReplyTo:     out.replyTo,
+		Quote:       out.quote,
+		Silent:      cfg.silent,
+		ParseMode:   cfg.parseMode,
+		Alarm:       cfg.alarmToken != "" && cfg.authToken == cfg.alarmToken,
+		Attachments: out.attachments,
+	}
+	if out.doc != nil {
+		m.Document, m.Content = out.doc.name, out.doc.content
+	}
+	return m
+}
+
+// restore returns the configuration and message to deliver m with, based on
+// the current configuration cfg.
+func (m OutboxMessage) restore(cfg config) (config, outgoing) {
+	cfg.chatId, cfg.threadId = m.ChatId, m.ThreadId
+	cfg.silent, cfg.parseMode = m.Silent, m.ParseMode
+	if m.Alarm {
+		cfg.useAlarmBot(cfg.alarmLevel)
+	}
+
+	out := outgoing{
+		level:       m.Level,
+		key:         m.Key,
+		topic:       m.Topic,
+		replyTo:     m.ReplyTo,
+		quote:       m.Quote,
+		msg:         m.Text,
+		attachments: m.Attachments,
+	}
+	if m.Document != "" {
+		out.doc = &document{name: m.Document, content: m.Content}
+	}
+	return cfg, out
+}
+
+// newOutboxID returns a random message ID.
+func newOutboxID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// startOutbox runs the dispatcher that sends the messages stored in the outbox.
+func (h *TelegramHook) startOutbox() {
+	if h.Outbox() == nil {
+		return
+	}
+
+	// Close cancels a dispatch in progress
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-h.done
+		cancel()
+	}()
+
+	ticker := time.NewTicker(defaultOutboxInterval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-h.done:
+				return
+			case <-ticker.C:
+				h.dispatchOutbox(ctx, h.snapshot())
+			}
+		}
+	}()
+}
+
+// dispatchOutbox sends unsent messages from the outbox and marks them as sent.
+// A message is sent again when MarkSent fails, so delivery is at least once.
+// It stops at the first transient failure, so the remaining messages keep
+// their order. Messages Telegram rejects go to the fallback once and are
+// marked as failed.
+func (h *TelegramHook) dispatchOutbox(ctx context.Context, cfg config) {
+	if cfg.outbox == nil {
+		return
+	}
+
+	for ctx.Err() == nil {
+		msgs, err := cfg.outbox.List(ctx, outboxBatch)
+		if err != nil {
+			h.handleError(err)
+			return
+		}
+
+		for _, m := range msgs {
+			mcfg, out := m.restore(cfg)
+			out.outboxed = true
+			if err := h.deliver(ctx, mcfg, out); err != nil {
+				h.handleError(err)
+				if transient(err) {
+					return
+				}
+				if err := cfg.outbox.MarkFailed(ctx, m.ID, err); err != nil {
+					h.handleError(err)
+					return
+				}
+				continue
+			}
+			if err := cfg.outbox.MarkSent(ctx, m.ID); err != nil {
+				h.handleError(err)
+				return
+			}
+		}
+
+		if len(msgs) < outboxBatch {
+			return
+		}
+	}
+}
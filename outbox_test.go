@@ -0,0 +1,173 @@
+package telegramhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	log "github.com/andoma-go/logrus"
+)
+
+type ctxKey struct{}
+
+// memOutbox is an Outbox kept in memory.
+type memOutbox struct {
+	mu     sync.Mutex
+	msgs   []OutboxMessage
+	sent   map[string]bool
+	failed map[string]error
+	txs    []interface{}
+}
+
+func (o *memOutbox) Put(ctx context.Context, msg OutboxMessage) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.msgs = append(o.msgs, msg)
+	o.txs = append(o.txs, ctx.Value(ctxKey{}))
+	return nil
+}
+
+func (o *memOutbox) List(ctx context.Context, limit int) ([]OutboxMessage, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	var unsent []OutboxMessage
+	for _, m := range o.msgs {
+		if _, failed := o.failed[m.ID]; !o.sent[m.ID] && !failed && len(unsent) < limit {
+			unsent = append(unsent, m)
+		}
+	}
+	return unsent, nil
+}
+
+func (o *memOutbox) MarkSent(ctx context.Context, id string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.sent == nil {
+		o.sent = map[string]bool{}
+	}
+	o.sent[id] = true
+	return nil
+}
+
+func (o *memOutbox) MarkFailed(ctx context.Context, id string, err error) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.failed == nil {
+		o.failed = map[string]error{}
+	}
+	o.failed[id] = err
+	return nil
+}
+
+func TestOutbox(t *testing.T) {
+	api := &fakeAPI{}
+	outbox := &memOutbox{}
+	h := newTestHook(WithOutbox(outbox))
+	h.client = api.client()
+
+	ctx := context.WithValue(context.Background(), ctxKey{}, "tx1")
+	if err := h.Fire(&log.Entry{Level: log.ErrorLevel, Message: "payment failed", Context: ctx}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(api.methods()) != 0 {
+		t.Fatal("Expected the message to be stored, not sent")
+	}
+	if !reflect.DeepEqual(outbox.txs, []interface{}{"tx1"}) {
+		t.Errorf("Expected Put to receive the entry context, got %v", outbox.txs)
+	}
+
+	h.dispatchOutbox(context.Background(), h.snapshot())
+	h.dispatchOutbox(context.Background(), h.snapshot())
+
+	if got := api.texts(); len(got) != 1 || got[0] != "<b>ERROR</b>@testing - payment failed" {
+		t.Errorf("Expected the stored message to be sent once, got %q", got)
+	}
+}
+
+func TestOutboxRejected(t *testing.T) {
+	var down atomic.Bool
+	api := &fakeAPI{respond: func(method string, body []byte) *http.Response {
+		switch {
+		case down.Load():
+			return jsonResponse(http.StatusBadGateway, `{"ok":false,"error_code":502,"description":"Bad Gateway"}`)
+		case strings.Contains(string(body), "rejected"):
+			return jsonResponse(http.StatusBadRequest, `{"ok":false,"error_code":400,"description":"Bad Request: chat not found"}`)
+		}
+		return nil
+	}}
+	var fallback strings.Builder
+	outbox := &memOutbox{}
+	h := newTestHook(WithOutbox(outbox), WithFallback(&fallback))
+	h.client = api.client()
+
+	for _, msg := range []string{"rejected", "good"} {
+		if err := h.Fire(&log.Entry{Level: log.ErrorLevel, Message: msg}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	down.Store(true)
+	h.dispatchOutbox(context.Background(), h.snapshot())
+	if fallback.Len() != 0 {
+		t.Errorf("Expected transient failures to stay in the outbox, got fallback %q", fallback.String())
+	}
+
+	down.Store(false)
+	for i := 0; i < 3; i++ {
+		h.dispatchOutbox(context.Background(), h.snapshot())
+	}
+
+	texts := api.texts()
+	if n := strings.Count(strings.Join(texts, "\n"), "rejected"); n != 2 {
+		t.Errorf("Expected the rejected message to be tried once after the outage, got %d attempts", n)
+	}
+	if texts[len(texts)-1] != "<b>ERROR</b>@testing - good" || strings.Count(strings.Join(texts, "\n"), "good") != 1 {
+		t.Errorf("Expected the message behind the rejected one to be sent once, got %q", texts)
+	}
+	if n := strings.Count(fallback.String(), "rejected"); n != 1 || len(outbox.failed) != 1 {
+		t.Errorf("Expected the rejected message in the fallback once and marked failed, got %d, %v", n, outbox.failed)
+	}
+}
+
+func TestOutboxMessage(t *testing.T) {
+	h := newTestHook(WithAlarmBot("alarm", log.ErrorLevel), WithParseMode(ParseModeMarkdownV2))
+	cfg := h.snapshot()
+	cfg.silent = true
+	cfg.useAlarmBot(log.ErrorLevel)
+	out := outgoing{
+		level:       log.ErrorLevel,
+		key:         "order-7",
+		topic:       "payments",
+		replyTo:     3,
+		quote:       "Payments down",
+		msg:         "payment failed",
+		doc:         &document{name: "fields.txt", content: []byte("a: 1\n")},
+		attachments: []Attachment{{Name: "trace.txt", Content: []byte("trace")}},
+	}
+
+	b, err := json.Marshal(outboxMessage(cfg, out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m OutboxMessage
+	if err := json.Unmarshal(b, &m); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(b), `"alarm"`) {
+		t.Errorf("Expected the alarm token not to be stored: %s", b)
+	}
+
+	restored, got := m.restore(h.snapshot())
+	if !reflect.DeepEqual(got, out) {
+		t.Errorf("Unexpected restored message %+v, want %+v", got, out)
+	}
+	if !restored.silent || restored.parseMode != ParseModeMarkdownV2 || restored.authToken != "alarm" {
+		t.Errorf("Unexpected restored delivery: silent %v, parse mode %v, token %q", restored.silent, restored.parseMode, restored.authToken)
+	}
+}
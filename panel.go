@@ -0,0 +1,302 @@
+package telegramhook
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/andoma-go/logrus"
+)
+
+// LivePanel configures a single pinned message that is edited in place to show
+// the latest log lines and counters of the hook.
+type LivePanel struct {
+	// Lines is the number of log lines shown, 10 when zero and at most 50.
+	// Fewer lines are shown when they do not fit into a single message.
+	Lines int
+	// Interval is the minimum time between two edits, 5 seconds when zero.
+	Interval time.Duration
+	// Exclusive shows entries only on the panel instead of also sending them as messages.
+	Exclusive bool
+}
+
+// panelLineLength limits the length of a single panel line.
+const panelLineLength = 200
+
+// panelMaxLines bounds LivePanel.Lines, more lines would never fit into a
+// single message.
+const panelMaxLines = 50
+
+const (
+	// panelTopSignatures is the number of error signatures shown on the panel.
+	panelTopSignatures = 10
+	// panelMaxSignatures bounds the number of tracked signatures.
+	panelMaxSignatures = 1000
+)
+
+// signatureCounter counts occurrences of one error signature.
+type signatureCounter struct {
+	headline string
+	count    int
+	lastSeen time.Time
+}
+
+// livePanel is the state of the pinned panel message.
+type livePanel struct {
+	mu        sync.Mutex
+	messageId int64
+	lines     []string
+	lastEdit  time.Time
+	timer     *time.Timer
+	text      string
+	counters  map[string]*signatureCounter
+}
+
+// editMessageRequest edits the text of a previously sent message.
+type editMessageRequest struct {
+	ChatId    string `json:"chat_id"`
+	MessageId int64  `json:"message_id"`
+	Text      string `json:"text"`
+	ParseMode string `json:"parse_mode,omitempty"`
+}
+
+// pinMessageRequest pins a message in a chat.
+type pinMessageRequest struct {
+	ChatId              string `json:"chat_id"`
+	MessageId           int64  `json:"message_id"`
+	DisableNotification bool   `json:"disable_notification"`
+}
+
+// recordPanel adds entry to the panel and schedules an edit.
+func (h *TelegramHook) recordPanel(cfg config, entry *logrus.Entry) {
+	line := fmt.Sprintf("%s %-5s %s", cfg.inZone(entry.Time).Format(cfg.locale().clock), strings.ToUpper(entry.Level.String()), entry.Message)
+	line = truncateText(strings.ReplaceAll(line, "\n", " "), panelLineLength)
+
+	p := &h.panelState
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.lines = append(p.lines, line)
+	if n := cfg.panel.lines(); len(p.lines) > n {
+		p.lines = p.lines[len(p.lines)-n:]
+	}
+
+	if entry.Level <= logrus.ErrorLevel {
+		p.count(cfg.signature(entry), entry)
+	}
+
+	h.schedulePanel(cfg)
+}
+
+// schedulePanel arranges for the panel to be updated once the edit interval
+// allows it. The caller must hold the panel lock.
+func (h *TelegramHook) schedulePanel(cfg config) {
+	p := &h.panelState
+	if p.timer != nil {
+		return
+	}
+
+	delay := time.Until(p.lastEdit.Add(cfg.panel.interval()))
+	if delay < 0 {
+		delay = 0
+	}
+	p.timer = time.AfterFunc(delay, func() {
+		h.updatePanel(cfg)
+	})
+}
+
+// updatePanel renders the panel and sends, pins or edits its message.
+func (h *TelegramHook) updatePanel(cfg config) {
+	p := &h.panelState
+	p.mu.Lock()
+	text := h.renderPanel(cfg, p)
+	messageId, unchanged := p.messageId, text == p.text
+	p.mu.Unlock()
+
+	var err error
+	if !unchanged {
+		if messageId == 0 {
+			messageId, err = h.createPanel(cfg, text)
+		} else {
+			err = h.editMessage(cfg, messageId, text)
+		}
+	}
+	if err != nil {
+		h.handleError(err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.timer, p.lastEdit = nil, time.Now()
+	if err == nil {
+		p.messageId, p.text = messageId, text
+	}
+	if h.renderPanel(cfg, p) != p.text {
+		h.schedulePanel(cfg)
+	}
+}
+
+// count updates the counter of sig, the signature of entry, evicting the
+// least recently seen signature when too many are tracked. The caller must
+// hold the panel lock.
+func (p *livePanel) count(sig string, entry *logrus.Entry) {
+	if p.counters == nil {
+		p.counters = map[string]*signatureCounter{}
+	}
+
+	c, ok := p.counters[sig]
+	if !ok {
+		if len(p.counters) >= panelMaxSignatures {
+			oldest := ""
+			for k, v := range p.counters {
+				if oldest == "" || v.lastSeen.Before(p.counters[oldest].lastSeen) {
+					oldest = k
+				}
+			}
+			delete(p.counters, oldest)
+		}
+		c = &signatureCounter{headline: truncateText(strings.ReplaceAll(entry.Message, "\n", " "), panelLineLength)}
+		p.counters[sig] = c
+	}
+
+	c.count++
+	c.lastSeen = time.Now()
+}
+
+// topSignatures returns the most frequent signatures, most recent first on ties.
+// The caller must hold the panel lock.
+func (p *livePanel) topSignatures() []*signatureCounter {
+	top := make([]*signatureCounter, 0, len(p.counters))
+	for _, c := range p.counters {
+		top = append(top, c)
+	}
+	sort.Slice(top, func(i, j int) bool {
+		if top[i].count != top[j].count {
+			return top[i].count > top[j].count
+		}
+		return top[i].lastSeen.After(top[j].lastSeen)
+	})
+	if len(top) > panelTopSignatures {
+		top = top[:panelTopSignatures]
+	}
+	return top
+}
+
+// renderPanel renders the panel text from the latest lines and the counters.
+// The oldest lines, then the least frequent signatures are left out until the
+// panel fits into a single message. The caller must hold the panel lock.
+func (h *TelegramHook) renderPanel(cfg config, p *livePanel) string {
+	st := h.Stats()
+
+	skipped := uint64(0)
+	for _, n := range st.Skipped {
+		skipped += n
+	}
+
+	l := cfg.locale()
+	var top []string
+	for _, c := range p.topSignatures() {
+		top = append(top, fmt.Sprintf("%4s× %s %s", l.integer(int64(c.count)), cfg.inZone(c.lastSeen).Format(l.clock), c.headline))
+	}
+
+	var footer []string
+	if summary := noiseSummary(st.Suppressed); summary != "" {
+		footer = append(footer, "<i>"+html.EscapeString(summary)+"</i>")
+	}
+	footer = append(footer, fmt.Sprintf("<i>entries %s · sent %s · failed %s · skipped %s</i>",
+		l.integer(int64(st.Fired-skipped)), l.integer(int64(st.Sent)), l.integer(int64(st.Failed)), l.integer(int64(skipped))))
+
+	lines := p.lines
+	for {
+		msg := []string{fmt.Sprintf("<b>%s</b> live panel", html.EscapeString(cfg.appName))}
+		if len(lines) > 0 {
+			msg = append(msg, "<pre>"+html.EscapeString(strings.Join(lines, "\n"))+"</pre>")
+		}
+		if len(top) > 0 {
+			msg = append(msg, "<b>Top errors</b>", "<pre>"+html.EscapeString(strings.Join(top, "\n"))+"</pre>")
+		}
+		text := strings.Join(append(msg, footer...), "\n")
+
+		switch {
+		case htmlTextLen(text) <= maxMessageLength:
+			return text
+		case len(lines) > 0:
+			lines = lines[1:]
+		case len(top) > 0:
+			top = top[:len(top)-1]
+		default:
+			return text
+		}
+	}
+}
+
+// createPanel sends and pins the panel message.
+func (h *TelegramHook) createPanel(cfg config, text string) (int64, error) {
+	result, err := h.callJSON(cfg, "sendMessage", apiRequest{
+		ChatId:    cfg.chatId,
+		ThreadId:  cfg.threadId,
+		Text:      cfg.parseMode.convert(text),
+		ParseMode: cfg.parseMode.apiValue(),
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	var msg apiMessage
+	if err := json.Unmarshal(result, &msg); err != nil {
+		return 0, err
+	}
+
+	_, err = h.callJSON(cfg, "pinChatMessage", pinMessageRequest{
+		ChatId:              cfg.chatId,
+		MessageId:           msg.MessageId,
+		DisableNotification: true,
+	})
+	if err != nil {
+		h.handleError(err)
+	}
+
+	return msg.MessageId, nil
+}
+
+// editMessage replaces the text of a sent message. Edits that do not change
+// anything are not treated as errors.
+func (h *TelegramHook) editMessage(cfg config, messageId int64, text string) error {
+	_, err := h.callJSON(cfg, "editMessageText", editMessageRequest{
+		ChatId:    cfg.chatId,
+		MessageId: messageId,
+		Text:      cfg.parseMode.convert(text),
+		ParseMode: cfg.parseMode.apiValue(),
+	})
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && strings.Contains(apiErr.Description, "message is not modified") {
+		return nil
+	}
+	if err == nil {
+		h.emit(Event{Type: EventEdited, MessageIds: []int64{messageId}})
+	}
+	return err
+}
+
+func (p *LivePanel) lines() int {
+	switch {
+	case p.Lines > panelMaxLines:
+		return panelMaxLines
+	case p.Lines > 0:
+		return p.Lines
+	}
+	return 10
+}
+
+func (p *LivePanel) interval() time.Duration {
+	if p.Interval > 0 {
+		return p.Interval
+	}
+	return 5 * time.Second
+}
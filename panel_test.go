@@ -0,0 +1,72 @@
+package telegramhook
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	log "github.com/andoma-go/logrus"
+)
+
+func TestLivePanel(t *testing.T) {
+	api := &fakeAPI{}
+	h := newTestHook(WithLivePanel(LivePanel{Lines: 2, Interval: time.Millisecond, Exclusive: true}))
+	h.client = api.client()
+
+	for _, msg := range []string{"one", "two", "three"} {
+		if err := h.Fire(&log.Entry{Level: log.ErrorLevel, Message: msg}); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	methods := strings.Join(api.methods(), " ")
+	if !strings.HasPrefix(methods, "sendMessage pinChatMessage editMessageText") || strings.Count(methods, "sendMessage") != 1 {
+		t.Errorf("Unexpected API calls: %s", methods)
+	}
+
+	texts := api.texts()
+	last := texts[len(texts)-1]
+	if strings.Contains(last, "ERROR one") || !strings.Contains(last, "ERROR two") || !strings.Contains(last, "ERROR three") {
+		t.Errorf("Panel does not show the latest lines: %q", last)
+	}
+}
+
+func TestPanelTopSignatures(t *testing.T) {
+	var p livePanel
+	var cfg config
+	for _, msg := range []string{"a", "b", "b", "c", "b", "c"} {
+		entry := &log.Entry{Level: log.ErrorLevel, Message: msg}
+		p.count(cfg.signature(entry), entry)
+	}
+
+	top := p.topSignatures()
+	if len(top) != 3 || top[0].headline != "b" || top[0].count != 3 || top[1].headline != "c" {
+		t.Errorf("Unexpected top signatures: %+v", top)
+	}
+}
+
+func TestPanelMessageLimit(t *testing.T) {
+	h := newTestHook(WithLivePanel(LivePanel{Lines: 1000}))
+	cfg := h.snapshot()
+	if n := cfg.panel.lines(); n != panelMaxLines {
+		t.Errorf("Lines not clamped, got %d", n)
+	}
+
+	p := &h.panelState
+	for i := 0; i < panelMaxLines; i++ {
+		msg := strings.Repeat("<&>", 70) + fmt.Sprint(i)
+		entry := &log.Entry{Level: log.ErrorLevel, Message: msg}
+		p.lines = append(p.lines, msg)
+		p.count(cfg.signature(entry), entry)
+	}
+
+	text := h.renderPanel(cfg, p)
+	if n := htmlTextLen(text); n > maxMessageLength {
+		t.Errorf("Panel is %d characters long", n)
+	}
+	if !strings.Contains(text, "49</pre>") || strings.Contains(text, "&gt;0\n") {
+		t.Errorf("Expected the oldest lines to be left out: %q", text)
+	}
+}
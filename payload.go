@@ -0,0 +1,133 @@
+package telegramhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/andoma-go/logrus"
+)
+
+const (
+	// payloadMinLength is the length from which on a field value is checked
+	// for being a payload, shorter ones read well on a single line.
+	payloadMinLength = 40
+	// payloadLength limits the length of a rendered payload.
+	payloadLength = 2000
+)
+
+// renderPayloadBlocks renders string fields holding JSON, XML or YAML, e.g.
+// request and response dumps, pretty-printed in code blocks of that language.
+func renderPayloadBlocks(c *config, fields logrus.Fields) ([]fieldBlock, []string) {
+	var blocks []fieldBlock
+	var keys []string
+	for _, k := range c.fieldKeys(fields) {
+		var s string
+		switch v := fields[k].(type) {
+		case string:
+			s = v
+		case []byte:
+			s = string(v)
+		case json.RawMessage:
+			s = string(v)
+		default:
+			continue
+		}
+
+		body, lang := detectPayload(s)
+		if lang == "" {
+			continue
+		}
+		blocks = append(blocks, fieldBlock{title: k, body: truncateText(body, payloadLength), lang: lang})
+		keys = append(keys, k)
+	}
+	return blocks, keys
+}
+
+// detectPayload returns s pretty-printed and its language if it looks like
+// JSON, XML or YAML, an empty language otherwise. The checks are cheap
+// heuristics; only JSON and XML are reformatted.
+func detectPayload(s string) (string, string) {
+	s = strings.TrimSpace(s)
+	if len(s) < payloadMinLength {
+		return "", ""
+	}
+
+	switch s[0] {
+	case '{', '[':
+		var b bytes.Buffer
+		if err := json.Indent(&b, []byte(s), "", "  "); err == nil {
+			return b.String(), "json"
+		}
+	case '<':
+		if pretty, ok := indentXML(s); ok {
+			return pretty, "xml"
+		}
+	}
+
+	if looksLikeYAML(s) {
+		return s, "yaml"
+	}
+	return "", ""
+}
+
+// indentXML re-encodes the XML document s with indentation, reporting false if
+// s is not well-formed.
+func indentXML(s string) (string, bool) {
+	if !strings.HasSuffix(s, ">") {
+		return "", false
+	}
+
+	var b bytes.Buffer
+	dec := xml.NewDecoder(strings.NewReader(s))
+	enc := xml.NewEncoder(&b)
+	enc.Indent("", "  ")
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return "", false
+		}
+		if data, ok := tok.(xml.CharData); ok && len(bytes.TrimSpace(data)) == 0 {
+			continue
+		}
+		if err := enc.EncodeToken(xml.CopyToken(tok)); err != nil {
+			return "", false
+		}
+	}
+	if err := enc.Flush(); err != nil {
+		return "", false
+	}
+	return b.String(), true
+}
+
+// yamlLinePattern matches YAML mapping entries, list items and document markers.
+var yamlLinePattern = regexp.MustCompile(`^\s*(?:---|- |-$|[\w.\-"']+:(?: |$))`)
+
+// looksLikeYAML reports whether s spans several lines that are all YAML
+// mapping entries, list items, comments or document markers.
+func looksLikeYAML(s string) bool {
+	lines := strings.Split(s, "\n")
+	if len(lines) < 2 {
+		return false
+	}
+
+	mapping := false
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if !yamlLinePattern.MatchString(line) {
+			return false
+		}
+		mapping = mapping || strings.Contains(trimmed, ":")
+	}
+	return mapping
+}
@@ -0,0 +1,49 @@
+package telegramhook
+
+import (
+	"strings"
+	"testing"
+
+	log "github.com/andoma-go/logrus"
+)
+
+func TestDetectPayload(t *testing.T) {
+	for _, tc := range []struct {
+		in, lang, out string
+	}{
+		{`{"user":{"id":42,"name":"ada"},"roles":["admin"]}`, "json",
+			"{\n  \"user\": {\n    \"id\": 42,\n    \"name\": \"ada\"\n  },\n  \"roles\": [\n    \"admin\"\n  ]\n}"},
+		{`<order id="7"><item sku="a-1">2</item><note/></order>`, "xml",
+			"<order id=\"7\">\n  <item sku=\"a-1\">2</item>\n  <note></note>\n</order>"},
+		{"server:\n  host: example.com\n  ports:\n    - 80\n    - 443", "yaml",
+			"server:\n  host: example.com\n  ports:\n    - 80\n    - 443"},
+		{`{"user":{"id":42,"name":"ada"},"roles":["admin"]`, "", ""},
+		{`<b>not closed properly, just a message with a tag</i>`, "", ""},
+		{"panic: boom\n\ngoroutine 1 [running]:\nmain.main()", "", ""},
+		{`{"short":true}`, "", ""},
+	} {
+		out, lang := detectPayload(tc.in)
+		if lang != tc.lang || out != tc.out {
+			t.Errorf("detectPayload(%q) = %q, %q, want %q, %q", tc.in, out, lang, tc.out, tc.lang)
+		}
+	}
+}
+
+func TestCreateMessagePayload(t *testing.T) {
+	h := newTestHook()
+
+	msg := createMessage(h, &log.Entry{
+		Level:   log.ErrorLevel,
+		Message: "upstream failed",
+		Data: log.Fields{
+			"status":   502,
+			"response": []byte(`{"error":"bad gateway","retry":true,"upstream":"billing"}`),
+		},
+	})
+	if strings.Contains(msg, "response:") {
+		t.Errorf("Payload field listed with the other fields:\n%s", msg)
+	}
+	if !strings.Contains(msg, "<b>response</b>\n<pre><code class=\"language-json\">{\n  &#34;error&#34;: &#34;bad gateway&#34;,") {
+		t.Errorf("Payload not rendered as a JSON block:\n%s", msg)
+	}
+}
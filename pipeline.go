@@ -0,0 +1,177 @@
+package telegramhook
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Pipeline is a snapshot of the internal state of a hook, see
+// PipelineSnapshot.
+type Pipeline struct {
+	// Queued and QueuedBytes describe the messages waiting for a worker,
+	// Busy the messages taken by workers and not delivered yet.
+	Queued      int
+	QueuedBytes int
+	Busy        int
+	// Workers is the number of delivery workers, zero unless asynchronous.
+	Workers int
+	// Batched is the number of messages waiting to be sent as a batch.
+	Batched int
+	// Bursts is the number of entries held back by WithCoalescing.
+	Bursts int
+	// Repeats is the number of open deduplication windows.
+	Repeats int
+	// Flood reports whether a flood summary is in progress.
+	Flood bool
+	// EndpointsDown maps API endpoints that recently failed to when they are
+	// tried first again.
+	EndpointsDown map[string]time.Time
+	// BotTokens and ChatTokens are the tokens left in the rate limiter
+	// buckets of the bot and by chat, empty without WithRateLimit.
+	BotTokens  float64
+	ChatTokens map[string]float64
+	// Spooled is the number of messages in the spool directory.
+	Spooled int
+	// Stats are the counters of the hook.
+	Stats Stats
+}
+
+// PipelineSnapshot returns the internal state of the hook, e.g. to find out
+// where messages pile up during an incident.
+func (h *TelegramHook) PipelineSnapshot() Pipeline {
+	cfg := h.snapshot()
+	now := time.Now()
+	p := Pipeline{Stats: h.Stats()}
+
+	h.pending.mu.Lock()
+	p.Queued, p.QueuedBytes, p.Busy = len(h.pending.items), h.pending.bytes, h.pending.busy
+	h.pending.mu.Unlock()
+	if cfg.async {
+		p.Workers = cfg.workers
+		if p.Workers < 1 {
+			p.Workers = 1
+		}
+	}
+
+	h.batch.mu.Lock()
+	p.Batched = len(h.batch.msgs)
+	h.batch.mu.Unlock()
+
+	h.bursts.mu.Lock()
+	for _, b := range h.bursts.active {
+		p.Bursts += b.count
+	}
+	h.bursts.mu.Unlock()
+
+	h.dedup.mu.Lock()
+	p.Repeats = len(h.dedup.seen)
+	h.dedup.mu.Unlock()
+
+	h.flooding.mu.Lock()
+	p.Flood = h.flooding.active
+	h.flooding.mu.Unlock()
+
+	h.endpoints.mu.Lock()
+	for base, until := range h.endpoints.down {
+		if now.Before(until) {
+			if p.EndpointsDown == nil {
+				p.EndpointsDown = map[string]time.Time{}
+			}
+			p.EndpointsDown[base] = until
+		}
+	}
+	h.endpoints.mu.Unlock()
+
+	limit, limiter := cfg.rateLimit, &h.limiter
+	if cfg.sharedRateLimit {
+		if limit == nil {
+			limit = &RateLimit{}
+		}
+		limiter = sharedLimiter(cfg.authToken)
+	}
+	if limit != nil {
+		p.BotTokens, p.ChatTokens = limiter.tokens(now, limit)
+	}
+
+	h.spool.mu.Lock()
+	p.Spooled = h.spool.files
+	h.spool.mu.Unlock()
+
+	return p
+}
+
+// tokens returns the tokens left in the bucket of the bot and of every chat
+// without taking any.
+func (l *rateLimiter) tokens(now time.Time, limit *RateLimit) (float64, map[string]float64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	chats := make(map[string]float64, len(l.chats))
+	for chatId, b := range l.chats {
+		chats[chatId] = b.available(now, limit.perChat(), limit.burst())
+	}
+	return l.bot.available(now, limit.perSecond(), limit.perSecond()), chats
+}
+
+// available returns the tokens in the bucket at now, negative while tokens
+// are reserved ahead.
+func (b *tokenBucket) available(now time.Time, rate, burst float64) float64 {
+	if b.last.IsZero() {
+		return burst
+	}
+	tokens := b.tokens + now.Sub(b.last).Seconds()*rate
+	if tokens > burst {
+		tokens = burst
+	}
+	return tokens
+}
+
+// render formats the snapshot as an HTML message.
+func (p Pipeline) render(appName string) string {
+	rows := []string{
+		fmt.Sprintf("queue     %d messages, %s, %d in flight", p.Queued, formatBytes(int64(p.QueuedBytes)), p.Busy),
+		fmt.Sprintf("workers   %d", p.Workers),
+		fmt.Sprintf("held      %d batched, %d in bursts, %d repeat windows", p.Batched, p.Bursts, p.Repeats),
+		fmt.Sprintf("spool     %d messages", p.Spooled),
+	}
+	if p.Flood {
+		rows = append(rows, "flood     in progress")
+	}
+
+	if p.ChatTokens != nil {
+		rows = append(rows, fmt.Sprintf("tokens    bot %.1f", p.BotTokens))
+		chats := make([]string, 0, len(p.ChatTokens))
+		for chatId := range p.ChatTokens {
+			chats = append(chats, chatId)
+		}
+		sort.Strings(chats)
+		for _, chatId := range chats {
+			rows = append(rows, fmt.Sprintf("          %s %.1f", chatId, p.ChatTokens[chatId]))
+		}
+	}
+
+	bases := make([]string, 0, len(p.EndpointsDown))
+	for base := range p.EndpointsDown {
+		bases = append(bases, base)
+	}
+	sort.Strings(bases)
+	for _, base := range bases {
+		rows = append(rows, fmt.Sprintf("down      %s for %s", base, time.Until(p.EndpointsDown[base]).Round(time.Second)))
+	}
+
+	return fmt.Sprintf("<b>%s</b> pipeline\n<pre>%s</pre>\n<i>entries %d · sent %d · failed %d · dropped %d</i>",
+		html.EscapeString(appName), html.EscapeString(strings.Join(rows, "\n")),
+		p.Stats.Fired, p.Stats.Sent, p.Stats.Failed, p.Stats.Dropped)
+}
+
+// SendPipeline posts the PipelineSnapshot to the configured chat, bypassing
+// the queue so it arrives even while the queue is stuck, and returns the IDs
+// of the sent messages.
+func (h *TelegramHook) SendPipeline(ctx context.Context) ([]int64, error) {
+	cfg := h.snapshot()
+	return h.sendReply(ctx, cfg, h.PipelineSnapshot().render(cfg.appName), replyParameters{})
+}
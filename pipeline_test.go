@@ -0,0 +1,46 @@
+package telegramhook
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	log "github.com/andoma-go/logrus"
+)
+
+func TestPipelineSnapshot(t *testing.T) {
+	api := &fakeAPI{}
+	h := newTestHook(WithRateLimit(RateLimit{PerChat: 100, Burst: 5}), WithCoalescing(Coalescing{Quiet: time.Minute}))
+	h.chatId = "42"
+	h.client = api.client()
+	h.endpoints.markDown("https://blocked.example", time.Minute)
+
+	for i := 0; i < 3; i++ {
+		if err := h.Fire(&log.Entry{Level: log.ErrorLevel, Message: "loop"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	p := h.PipelineSnapshot()
+	if p.Bursts != 2 || p.Stats.Sent != 1 {
+		t.Errorf("Snapshot has %d entries in bursts and %d sent, want 2 and 1", p.Bursts, p.Stats.Sent)
+	}
+	if tokens := p.ChatTokens["42"]; tokens < 3.9 || tokens > 4.1 {
+		t.Errorf("Chat has %.2f tokens after one message, want about 4", tokens)
+	}
+	if _, ok := p.EndpointsDown["https://blocked.example"]; !ok {
+		t.Errorf("Expected the failed endpoint in %v", p.EndpointsDown)
+	}
+
+	if _, err := h.SendPipeline(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	texts := api.texts()
+	msg := texts[len(texts)-1]
+	for _, want := range []string{"<b>testing</b> pipeline", "held      0 batched, 2 in bursts", "tokens    bot", "down      https://blocked.example for "} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("Expected %q in %q", want, msg)
+		}
+	}
+}
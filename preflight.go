@@ -0,0 +1,160 @@
+package telegramhook
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// PreflightReport describes what the bot can do in the target chat.
+type PreflightReport struct {
+	// ChatType is "private", "group", "supergroup" or "channel".
+	ChatType string
+	// IsForum reports whether the chat is a supergroup with topics.
+	IsForum bool
+	// Status is the membership status of the bot, e.g. "member" or "administrator".
+	Status string
+
+	IsAdmin         bool
+	CanSend         bool
+	CanEdit         bool
+	CanPin          bool
+	CanDelete       bool
+	CanCreateTopics bool
+
+	// Problems explains for every enabled feature that lacks a right what
+	// needs to change.
+	Problems []string
+}
+
+// apiUser is the part of a Telegram user that the hook uses.
+type apiUser struct {
+	Id int64 `json:"id"`
+}
+
+// apiChat is the part of a Telegram chat that the hook uses.
+type apiChat struct {
+	Type    string `json:"type"`
+	IsForum bool   `json:"is_forum"`
+}
+
+// apiChatMember is the part of a Telegram chat member that the hook uses.
+type apiChatMember struct {
+	Status            string `json:"status"`
+	CanSendMessages   *bool  `json:"can_send_messages"`
+	CanPostMessages   bool   `json:"can_post_messages"`
+	CanEditMessages   bool   `json:"can_edit_messages"`
+	CanPinMessages    bool   `json:"can_pin_messages"`
+	CanDeleteMessages bool   `json:"can_delete_messages"`
+	CanManageTopics   bool   `json:"can_manage_topics"`
+}
+
+// chatRequest is the payload of getChat.
+type chatRequest struct {
+	ChatId string `json:"chat_id"`
+}
+
+// chatMemberRequest is the payload of getChatMember.
+type chatMemberRequest struct {
+	ChatId string `json:"chat_id"`
+	UserId int64  `json:"user_id"`
+}
+
+// Preflight checks the rights of the bot in the target chat and reports which
+// enabled features will not work, so they can be fixed before they fail at
+// runtime with an API error.
+func (h *TelegramHook) Preflight(ctx context.Context) (*PreflightReport, error) {
+	cfg := h.snapshot()
+
+	var me apiUser
+	if err := h.callInto(ctx, cfg, "getMe", nil, &me); err != nil {
+		return nil, err
+	}
+
+	var chat apiChat
+	if err := h.callInto(ctx, cfg, "getChat", chatRequest{ChatId: cfg.chatId}, &chat); err != nil {
+		return nil, err
+	}
+
+	report := &PreflightReport{ChatType: chat.Type, IsForum: chat.IsForum}
+
+	if chat.Type == "private" {
+		report.Status = "member"
+		report.CanSend, report.CanEdit, report.CanPin, report.CanDelete = true, true, true, true
+	} else {
+		var member apiChatMember
+		if err := h.callInto(ctx, cfg, "getChatMember", chatMemberRequest{ChatId: cfg.chatId, UserId: me.Id}, &member); err != nil {
+			return nil, err
+		}
+		report.Status = member.Status
+		report.rights(chat, member)
+	}
+
+	report.Problems = cfg.preflightProblems(report)
+	return report, nil
+}
+
+// rights derives the capabilities of the bot from its membership.
+func (r *PreflightReport) rights(chat apiChat, m apiChatMember) {
+	switch m.Status {
+	case "creator":
+		r.IsAdmin = true
+		r.CanSend, r.CanEdit, r.CanPin, r.CanDelete = true, true, true, true
+		r.CanCreateTopics = chat.IsForum
+		return
+	case "administrator":
+		r.IsAdmin = true
+	case "member":
+	case "restricted":
+		r.CanSend = m.CanSendMessages != nil && *m.CanSendMessages
+		r.CanEdit, r.CanDelete = r.CanSend, r.CanSend
+		return
+	default:
+		return
+	}
+
+	if chat.Type == "channel" {
+		r.CanSend = m.CanPostMessages
+		r.CanEdit = m.CanEditMessages || m.CanPostMessages
+		r.CanPin = m.CanEditMessages
+		r.CanDelete = m.CanDeleteMessages || m.CanPostMessages
+		return
+	}
+
+	// Bots can always edit and delete their own messages in groups.
+	r.CanSend, r.CanEdit, r.CanDelete = true, true, true
+	r.CanPin = m.CanPinMessages
+	r.CanCreateTopics = chat.IsForum && m.CanManageTopics
+}
+
+// preflightProblems lists what prevents enabled features from working.
+func (c *config) preflightProblems(r *PreflightReport) []string {
+	var problems []string
+	if !r.CanSend {
+		problems = append(problems, "the bot cannot send messages to the chat; add it as a member (or as an administrator that can post in channels)")
+	}
+	if c.panel != nil && !r.CanPin {
+		problems = append(problems, "the live panel cannot be pinned; grant the bot the \"Pin messages\" right")
+	}
+	if c.autoTopics != TopicsOff && !r.IsForum {
+		problems = append(problems, "automatic topics need a forum; enable topics in the group settings")
+	} else if c.autoTopics != TopicsOff && !r.CanCreateTopics {
+		problems = append(problems, "automatic topics cannot be created; grant the bot the \"Manage topics\" right")
+	}
+	return problems
+}
+
+// callInto issues the Bot API method and decodes its result into v. A nil
+// payload issues the method without a body.
+func (h *TelegramHook) callInto(ctx context.Context, cfg config, method string, payload, v interface{}) error {
+	var result json.RawMessage
+	var err error
+	if payload == nil {
+		result, err = h.call(ctx, cfg, method, "", nil)
+	} else {
+		result, err = h.callJSONContext(ctx, cfg, method, payload)
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(result, v)
+}
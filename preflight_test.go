@@ -0,0 +1,74 @@
+package telegramhook
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+func preflightAPI(chat, member string) *fakeAPI {
+	return &fakeAPI{respond: func(method string, body []byte) *http.Response {
+		switch method {
+		case "getMe":
+			return jsonResponse(http.StatusOK, `{"ok":true,"result":{"id":99,"is_bot":true}}`)
+		case "getChat":
+			return jsonResponse(http.StatusOK, `{"ok":true,"result":`+chat+`}`)
+		case "getChatMember":
+			return jsonResponse(http.StatusOK, `{"ok":true,"result":`+member+`}`)
+		}
+		return nil
+	}}
+}
+
+func TestPreflight(t *testing.T) {
+	api := preflightAPI(
+		`{"id":-100,"type":"supergroup","is_forum":true}`,
+		`{"status":"administrator","can_pin_messages":false,"can_manage_topics":false,"can_delete_messages":true}`,
+	)
+	h := newTestHook(WithLivePanel(LivePanel{}), WithAutoTopics(TopicPerSignature))
+	h.client = api.client()
+
+	r, err := h.Preflight(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.IsAdmin || !r.CanSend || r.CanPin || r.CanCreateTopics || !r.IsForum {
+		t.Errorf("Unexpected report %+v", r)
+	}
+	if len(r.Problems) != 2 {
+		t.Errorf("Expected problems for the panel and topics, got %q", r.Problems)
+	}
+}
+
+func TestPreflightPrivate(t *testing.T) {
+	api := preflightAPI(`{"id":42,"type":"private"}`, `{}`)
+	h := newTestHook(WithLivePanel(LivePanel{}))
+	h.client = api.client()
+
+	r, err := h.Preflight(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.CanSend || !r.CanPin || len(r.Problems) != 0 {
+		t.Errorf("Unexpected report %+v", r)
+	}
+	for _, m := range api.methods() {
+		if m == "getChatMember" {
+			t.Error("Unexpected getChatMember call for a private chat")
+		}
+	}
+}
+
+func TestPreflightRestricted(t *testing.T) {
+	api := preflightAPI(`{"id":-1,"type":"group"}`, `{"status":"restricted","can_send_messages":false}`)
+	h := newTestHook()
+	h.client = api.client()
+
+	r, err := h.Preflight(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.CanSend || len(r.Problems) != 1 {
+		t.Errorf("Unexpected report %+v", r)
+	}
+}
@@ -0,0 +1,35 @@
+package telegramhook
+
+import (
+	"fmt"
+	"html"
+	"os"
+	"sync"
+)
+
+var (
+	executableOnce sync.Once
+	executablePath string
+)
+
+// processInfo describes the running process, so that instances sharing an
+// app name on one host can be told apart.
+func processInfo() string {
+	executableOnce.Do(func() {
+		path, err := os.Executable()
+		if err != nil {
+			path = os.Args[0]
+		}
+		executablePath = path
+	})
+	return fmt.Sprintf("pid %d · ppid %d · %s", os.Getpid(), os.Getppid(), executablePath)
+}
+
+// processFooter returns the HTML line with process information appended to
+// fatal and lifecycle messages, empty unless enabled.
+func (c *config) processFooter() string {
+	if !c.processInfo {
+		return ""
+	}
+	return "\n<i>" + html.EscapeString(processInfo()) + "</i>"
+}
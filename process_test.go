@@ -0,0 +1,29 @@
+package telegramhook
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	log "github.com/andoma-go/logrus"
+)
+
+func TestProcessInfo(t *testing.T) {
+	pid := fmt.Sprintf("pid %d · ppid %d · ", os.Getpid(), os.Getppid())
+
+	msg := createMessage(newTestHook(WithProcessInfo(true)), &log.Entry{Level: log.FatalLevel, Message: "shutting down"})
+	if !strings.Contains(msg, "\n<i>"+pid) {
+		t.Errorf("Expected process information in fatal message:\n%s", msg)
+	}
+
+	msg = createMessage(newTestHook(WithProcessInfo(true)), &log.Entry{Level: log.ErrorLevel, Message: "failed"})
+	if strings.Contains(msg, pid) {
+		t.Errorf("Unexpected process information in error message:\n%s", msg)
+	}
+
+	msg = createMessage(newTestHook(), &log.Entry{Level: log.FatalLevel, Message: "shutting down"})
+	if strings.Contains(msg, pid) {
+		t.Errorf("Unexpected process information without WithProcessInfo:\n%s", msg)
+	}
+}
@@ -0,0 +1,60 @@
+package telegramhook
+
+import (
+	"strings"
+
+	"github.com/andoma-go/logrus"
+)
+
+// RenderProfile changes how messages are rendered for a particular chat, e.g.
+// terse plain text for an on-call DM and the full format for a team channel.
+type RenderProfile struct {
+	// PlainText drops all formatting.
+	PlainText bool
+	// Terse sends only the headline, without fields or attachments.
+	Terse bool
+}
+
+// renderFor renders entry for chatId using the profile configured for it,
+// without the hidden fields.
+func (c *config) renderFor(entry *logrus.Entry, chatId string) (string, *document) {
+	entry = c.hideFields(entry)
+	profile, ok := c.profiles[chatId]
+	if !ok {
+		return c.detachEncrypted(c.formatMessage(entry), c.createFieldsDocument(entry))
+	}
+
+	var doc *document
+	if profile.Terse {
+		terse := *entry
+		terse.Data = nil
+		if err, ok := entry.Data[logrus.ErrorKey]; ok && entry.Message == "" {
+			terse.Data = logrus.Fields{logrus.ErrorKey: err}
+		}
+		entry = &terse
+	} else {
+		doc = c.createFieldsDocument(entry)
+	}
+
+	msg := c.formatMessage(entry)
+	if profile.Terse {
+		msg, _, _ = strings.Cut(msg, "\n<pre>")
+	}
+	if profile.PlainText {
+		msg = stripTags(msg)
+	}
+
+	return c.detachEncrypted(msg, doc)
+}
+
+// stripTags removes all tags from an HTML-formatted message. Entities are
+// kept, so the result is still valid HTML without any formatting.
+func stripTags(s string) string {
+	var b strings.Builder
+	for _, t := range tokenizeHTML(s) {
+		if t.tag == "" {
+			b.WriteString(t.text)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,21 @@
+package telegramhook
+
+import (
+	"testing"
+
+	log "github.com/andoma-go/logrus"
+)
+
+func TestRenderProfile(t *testing.T) {
+	h := newTestHook(WithChatProfile("oncall", RenderProfile{PlainText: true, Terse: true}))
+	cfg := h.snapshot()
+	entry := &log.Entry{Level: log.ErrorLevel, Message: "disk full", Data: log.Fields{"host": "db1"}}
+
+	if msg, _ := cfg.renderFor(entry, "oncall"); msg != "ERROR@testing - disk full" {
+		t.Errorf("Unexpected terse plain message: %q", msg)
+	}
+
+	if msg, _ := cfg.renderFor(entry, "team"); msg != cfg.createMessage(entry) {
+		t.Errorf("Chat without profile not rendered with default format: %q", msg)
+	}
+}
@@ -0,0 +1,442 @@
+package telegramhook
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/andoma-go/logrus"
+)
+
+// pendingOverhead approximates the memory used by a queued message besides its text.
+const pendingOverhead = 256
+
+// pendingMessage is a rendered entry waiting for asynchronous delivery.
+type pendingMessage struct {
+	outgoing
+	cfg      config
+	size     int
+	enqueued time.Time
+}
+
+// QueuePolicy decides what happens when the async queue is full.
+type QueuePolicy int
+
+const (
+	// QueueDrop sheds the least severe, then newest messages and reports them
+	// in a summary once the queue has drained.
+	QueueDrop QueuePolicy = iota
+	// QueueBlock makes Fire wait until a worker took a message off the queue.
+	QueueBlock
+)
+
+func (p QueuePolicy) String() string {
+	if p == QueueBlock {
+		return "block"
+	}
+	return "drop"
+}
+
+// defaultQueueSize is the default maximum number of queued messages.
+const defaultQueueSize = 1000
+
+// pendingQueue holds messages waiting for asynchronous delivery. When it holds
+// too many messages or their approximate memory footprint exceeds the
+// configured maximum, the lowest priority (least severe, then newest) messages
+// are shed and counted so a summary can be sent once the pressure subsides.
+//
+// Messages with the same correlation key are handed to one worker at a time,
+// so a later message cannot overtake an earlier one that is being retried.
+type pendingQueue struct {
+	mu       sync.Mutex
+	items    []*pendingMessage
+	bytes    int
+	shed     map[logrus.Level]int
+	busy     int             // messages taken by workers and not delivered yet
+	keys     map[string]bool // correlation keys of the busy messages
+	closed   bool
+	notEmpty *sync.Cond
+	notFull  *sync.Cond
+}
+
+// init creates the conditions of the queue. The caller must hold the lock.
+func (q *pendingQueue) init() {
+	if q.notEmpty == nil {
+		q.notEmpty = sync.NewCond(&q.mu)
+		q.notFull = sync.NewCond(&q.mu)
+	}
+}
+
+// push queues m and returns the messages shed to keep the queue within
+// maxItems messages and maxBytes. Zero disables the respective limit.
+// With block, push waits for room instead of shedding for maxItems. Once the
+// queue is closed, also while waiting, m is not queued and push returns false.
+func (q *pendingQueue) push(m *pendingMessage, maxBytes, maxItems int, block bool) ([]*pendingMessage, bool) {
+	m.size = len(m.msg) + pendingOverhead
+	if m.doc != nil {
+		m.size += len(m.doc.content)
+	}
+	for _, a := range m.attachments {
+		m.size += len(a.Content)
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.init()
+
+	for block && maxItems > 0 && len(q.items) >= maxItems && !q.closed {
+		q.notFull.Wait()
+	}
+	if q.closed {
+		return nil, false
+	}
+
+	q.items = append(q.items, m)
+	q.bytes += m.size
+
+	var shed []*pendingMessage
+	for len(q.items) > 0 && (maxBytes > 0 && q.bytes > maxBytes || maxItems > 0 && len(q.items) > maxItems) {
+		victim := 0
+		for i, item := range q.items {
+			if item.level >= q.items[victim].level {
+				victim = i
+			}
+		}
+
+		if q.shed == nil {
+			q.shed = map[logrus.Level]int{}
+		}
+		q.shed[q.items[victim].level]++
+		q.bytes -= q.items[victim].size
+		shed = append(shed, q.items[victim])
+		q.items = append(q.items[:victim], q.items[victim+1:]...)
+	}
+
+	q.notEmpty.Signal()
+	return shed, true
+}
+
+// pop removes the oldest queued message, nil when the queue is empty.
+func (q *pendingQueue) pop() *pendingMessage {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.remove()
+}
+
+// wait removes the oldest queued message whose correlation key is not busy,
+// waiting for one if there is none. It returns nil once the queue is closed
+// and empty. The message counts as busy until done is called.
+func (q *pendingQueue) wait() *pendingMessage {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.init()
+
+	for {
+		for i, m := range q.items {
+			if m.key != "" && q.keys[m.key] {
+				continue
+			}
+
+			q.take(i)
+			q.busy++
+			if m.key != "" {
+				if q.keys == nil {
+					q.keys = map[string]bool{}
+				}
+				q.keys[m.key] = true
+			}
+			return m
+		}
+
+		if q.closed && len(q.items) == 0 {
+			return nil
+		}
+		q.notEmpty.Wait()
+	}
+}
+
+// done marks m, returned by wait, as delivered and lets the next message with
+// its correlation key be taken.
+func (q *pendingQueue) done(m *pendingMessage) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.busy--
+	if m.key != "" {
+		delete(q.keys, m.key)
+		if len(q.items) > 0 {
+			q.notEmpty.Broadcast()
+		}
+	}
+}
+
+// drained reports whether no message is queued or being delivered.
+func (q *pendingQueue) drained() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.items) == 0 && q.busy == 0
+}
+
+// remove takes the oldest message off the queue. The caller must hold the lock.
+func (q *pendingQueue) remove() *pendingMessage {
+	if len(q.items) == 0 {
+		return nil
+	}
+
+	m := q.items[0]
+	q.items[0] = nil
+	q.items = q.items[1:]
+	q.release(m)
+	return m
+}
+
+// take removes the message at index i. The caller must hold the lock.
+func (q *pendingQueue) take(i int) {
+	if i == 0 {
+		q.remove()
+		return
+	}
+
+	m := q.items[i]
+	q.items = append(q.items[:i], q.items[i+1:]...)
+	q.release(m)
+}
+
+// release accounts for m leaving the queue. The caller must hold the lock.
+func (q *pendingQueue) release(m *pendingMessage) {
+	q.bytes -= m.size
+	if q.notFull != nil {
+		q.notFull.Signal()
+	}
+}
+
+// close wakes up waiting workers and producers. Workers exit once the queue is empty.
+func (q *pendingQueue) close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.init()
+
+	q.closed = true
+	q.notEmpty.Broadcast()
+	q.notFull.Broadcast()
+}
+
+// oldest returns when the oldest queued message was enqueued and false when
+// the queue is empty.
+func (q *pendingQueue) oldest() (time.Time, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.items) == 0 {
+		return time.Time{}, false
+	}
+	return q.items[0].enqueued, true
+}
+
+// fullness returns how full the queue is relative to maxBytes and maxItems,
+// from 0 to 1. Zero disables the respective limit.
+func (q *pendingQueue) fullness(maxBytes, maxItems int) float64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	full := 0.0
+	if maxItems > 0 {
+		full = float64(len(q.items)) / float64(maxItems)
+	}
+	if maxBytes > 0 {
+		full = math.Max(full, float64(q.bytes)/float64(maxBytes))
+	}
+	return math.Min(full, 1)
+}
+
+// takeShed returns and resets the shed counters once the queue has drained
+// below half of maxBytes and maxItems, nil while under pressure or when
+// nothing was shed.
+func (q *pendingQueue) takeShed(maxBytes, maxItems int) map[logrus.Level]int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.shed) == 0 || maxBytes > 0 && q.bytes > maxBytes/2 || maxItems > 0 && len(q.items) > maxItems/2 {
+		return nil
+	}
+
+	shed := q.shed
+	q.shed = nil
+	return shed
+}
+
+// enqueue queues a message for asynchronous delivery by the workers. A message
+// arriving after Close, or while Fire waits for room when Close is called, is
+// dropped and handed to the fallback.
+func (h *TelegramHook) enqueue(cfg config, out outgoing) {
+	h.startWorkers(cfg.workers)
+	h.emit(Event{Type: EventEnqueued, Level: out.level, Key: out.key})
+
+	shed, queued := h.pending.push(&pendingMessage{
+		outgoing: out,
+		cfg:      cfg,
+		enqueued: time.Now(),
+	}, cfg.maxQueueBytes, cfg.queueSize, cfg.queuePolicy == QueueBlock)
+	if !queued {
+		h.stats.dropped.Add(1)
+		h.emit(Event{Type: EventDropped, Level: out.level, Key: out.key})
+		h.fallback(cfg, out)
+		return
+	}
+	h.stats.dropped.Add(uint64(len(shed)))
+	for _, m := range shed {
+		h.emit(Event{Type: EventDropped, Level: m.level, Key: m.key})
+	}
+}
+
+// post queues out in async mode and delivers it right away otherwise,
+// reporting a failure.
+func (h *TelegramHook) post(cfg config, out outgoing) {
+	if cfg.async {
+		h.enqueue(cfg, out)
+		return
+	}
+	if err := h.deliver(context.Background(), cfg, out); err != nil {
+		h.reportError(out.entry, err)
+	}
+}
+
+// startWorkers starts n workers delivering queued messages, at least one, on
+// first use.
+func (h *TelegramHook) startWorkers(n int) {
+	h.workersOnce.Do(func() {
+		if n < 1 {
+			n = 1
+		}
+		for i := 1; i <= n; i++ {
+			go h.work(i)
+		}
+	})
+}
+
+// work delivers queued messages until the queue is closed and empty.
+func (h *TelegramHook) work(worker int) {
+	for m := h.pending.wait(); m != nil; m = h.pending.wait() {
+		h.deliverPending(worker, m)
+		h.pending.done(m)
+	}
+}
+
+// Pressure returns how full the async queue is, from 0 (empty) to 1 (full),
+// so applications can reduce their own log volume while alerting is
+// saturated. It is 0 when the queue is unbounded.
+func (h *TelegramHook) Pressure() float64 {
+	cfg := h.snapshot()
+	return h.pending.fullness(cfg.maxQueueBytes, cfg.queueSize)
+}
+
+// gated reports whether an entry of level is rejected by the pressure gate.
+func (h *TelegramHook) gated(cfg config, level logrus.Level) bool {
+	return cfg.gate != nil && level > cfg.gate.level() &&
+		h.pending.fullness(cfg.maxQueueBytes, cfg.queueSize) >= cfg.gate.Threshold
+}
+
+// flushPoll is how often Flush checks whether the queue has drained.
+const flushPoll = 10 * time.Millisecond
+
+// Flush sends pending firehose and batched messages, the summaries of
+// repeated messages and floods and the trailing messages of bursts, and waits
+// until all queued messages were delivered or ctx is done. Applications using WithAsync should flush before
+// they exit, so the last messages are not lost.
+func (h *TelegramHook) Flush(ctx context.Context) error {
+	cfg := h.snapshot()
+	h.flushFirehose(cfg)
+	h.flushBatch(cfg)
+	h.flushDedup()
+	h.flushFlood()
+	h.flushBursts()
+
+	ticker := time.NewTicker(flushPoll)
+	defer ticker.Stop()
+
+	for !h.pending.drained() {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("flush: %w", ctx.Err())
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
+
+// deliverPending sends a queued message, followed by a summary of shed
+// messages once the pressure subsided.
+func (h *TelegramHook) deliverPending(worker int, m *pendingMessage) {
+	out := m.outgoing
+	if m.cfg.deliveryFooter {
+		out.msg = fmt.Sprintf("%s\n<i>worker %d · queued %s</i>",
+			out.msg, worker, time.Since(m.enqueued).Round(time.Millisecond))
+	}
+
+	if err := h.deliver(context.Background(), m.cfg, out); err != nil {
+		h.reportError(out.entry, err)
+	}
+
+	if shed := h.pending.takeShed(m.cfg.maxQueueBytes, m.cfg.queueSize); shed != nil {
+		if _, err := h.sendMessage(m.cfg, shedSummary(m.cfg.appName, shed)); err != nil {
+			h.handleError(err)
+		}
+	}
+}
+
+// FlushOnExit flushes queued messages for at most the timeout of
+// WithExitFlush. NewTelegramHook registers it as a logrus exit handler; it can
+// be registered with the exit handlers of other loggers, as the sirupsen
+// adapter does.
+func (h *TelegramHook) FlushOnExit() {
+	ctx, cancel := context.WithTimeout(context.Background(), h.ExitFlush())
+	defer cancel()
+
+	if err := h.Flush(ctx); err != nil {
+		h.handleError(err)
+	}
+}
+
+// shedSummary describes messages dropped under memory pressure.
+func shedSummary(appName string, shed map[logrus.Level]int) string {
+	levels := make([]logrus.Level, 0, len(shed))
+	total := 0
+	for level, n := range shed {
+		levels = append(levels, level)
+		total += n
+	}
+	sort.Slice(levels, func(i, j int) bool { return levels[i] < levels[j] })
+
+	counts := make([]string, 0, len(levels))
+	for _, level := range levels {
+		counts = append(counts, fmt.Sprintf("%d %s", shed[level], level))
+	}
+
+	return fmt.Sprintf("<b>WARNING</b>@%s - dropped %d messages under queue pressure (%s)",
+		html.EscapeString(appName), total, strings.Join(counts, ", "))
+}
+
+// PressureGate drops entries early while the async queue is saturated, before
+// they are rendered or queued.
+type PressureGate struct {
+	// Threshold is the queue fullness from which on entries are dropped, more
+	// than 0 and at most 1.
+	Threshold float64
+	// Level is the least severe level still let through, ErrorLevel when zero
+	// unless LevelSet is true.
+	Level logrus.Level
+	// LevelSet makes a zero Level mean PanicLevel, letting only panics
+	// through.
+	LevelSet bool
+}
+
+func (g *PressureGate) level() logrus.Level {
+	if g.Level > 0 || g.LevelSet {
+		return g.Level
+	}
+	return logrus.ErrorLevel
+}
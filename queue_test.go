@@ -0,0 +1,256 @@
+package telegramhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	log "github.com/andoma-go/logrus"
+)
+
+func TestPendingQueueShedding(t *testing.T) {
+	var q pendingQueue
+	limit := 3 * (pendingOverhead + 1)
+
+	q.push(&pendingMessage{outgoing: outgoing{level: log.ErrorLevel, msg: "e"}}, limit, 0, false)
+	q.push(&pendingMessage{outgoing: outgoing{level: log.InfoLevel, msg: "i"}}, limit, 0, false)
+	q.push(&pendingMessage{outgoing: outgoing{level: log.WarnLevel, msg: "w"}}, limit, 0, false)
+	q.push(&pendingMessage{outgoing: outgoing{level: log.ErrorLevel, msg: "f"}}, limit, 0, false)
+
+	var got []string
+	for m := q.pop(); m != nil; m = q.pop() {
+		got = append(got, m.msg)
+	}
+	if strings.Join(got, "") != "ewf" {
+		t.Errorf("Unexpected messages after shedding: %q", got)
+	}
+
+	shed := q.takeShed(limit, 0)
+	if shed[log.InfoLevel] != 1 || len(shed) != 1 {
+		t.Errorf("Unexpected shed counters: %v", shed)
+	}
+	if summary := shedSummary("app", shed); !strings.Contains(summary, "dropped 1 messages") {
+		t.Errorf("Unexpected summary: %q", summary)
+	}
+}
+
+func TestDeliveryFooter(t *testing.T) {
+	texts := make(chan string, 1)
+	h := newTestHook(WithAsync(true), WithDeliveryFooter(true))
+	h.client = &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		var req apiRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		texts <- req.Text
+		return jsonResponse(200, `{"ok":true,"result":{}}`), nil
+	})}
+
+	if err := h.Fire(&log.Entry{Level: log.ErrorLevel, Message: "m"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if text := <-texts; !strings.Contains(text, "\n<i>worker 1 · queued ") {
+		t.Errorf("Missing delivery footer: %q", text)
+	}
+}
+
+func TestPendingQueueSize(t *testing.T) {
+	var q pendingQueue
+	for _, level := range []log.Level{log.ErrorLevel, log.InfoLevel, log.ErrorLevel} {
+		q.push(&pendingMessage{outgoing: outgoing{level: level}}, 0, 2, false)
+	}
+
+	if len(q.items) != 2 || q.items[0].level != log.ErrorLevel || q.items[1].level != log.ErrorLevel {
+		t.Errorf("Expected the info message to be shed, queue holds %d", len(q.items))
+	}
+	if shed := q.takeShed(0, 2); shed != nil {
+		t.Errorf("Expected no summary while the queue is full, got %v", shed)
+	}
+	q.pop()
+	q.pop()
+	if shed := q.takeShed(0, 2); shed[log.InfoLevel] != 1 {
+		t.Errorf("Unexpected shed counters: %v", shed)
+	}
+}
+
+func TestPendingQueueBlock(t *testing.T) {
+	var q pendingQueue
+	q.push(&pendingMessage{outgoing: outgoing{msg: "first"}}, 0, 1, true)
+
+	pushed := make(chan struct{})
+	go func() {
+		q.push(&pendingMessage{outgoing: outgoing{msg: "second"}}, 0, 1, true)
+		close(pushed)
+	}()
+
+	select {
+	case <-pushed:
+		t.Fatal("Expected push to block while the queue is full")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	if m := q.wait(); m.msg != "first" {
+		t.Errorf("Unexpected message %q", m.msg)
+	}
+	<-pushed
+	if m := q.wait(); m.msg != "second" {
+		t.Errorf("Unexpected message %q", m.msg)
+	}
+}
+
+func TestPendingQueueBlockClosed(t *testing.T) {
+	var buf bytes.Buffer
+	h := newTestHook(WithAsync(true), WithQueueSize(1, QueueBlock), WithFallback(&buf))
+	h.workersOnce.Do(func() {}) // no worker makes room
+	h.pending.push(&pendingMessage{outgoing: outgoing{msg: "first"}}, 0, 1, true)
+
+	fired := make(chan struct{})
+	go func() {
+		_ = h.Fire(&log.Entry{Level: log.ErrorLevel, Message: "blocked"})
+		close(fired)
+	}()
+	time.Sleep(20 * time.Millisecond)
+	h.pending.close()
+	<-fired
+
+	if m := h.pending.pop(); m == nil || m.msg != "first" || h.pending.pop() != nil {
+		t.Errorf("Expected the blocked message not to be queued after close")
+	}
+	if h.Stats().Dropped != 1 || !strings.Contains(buf.String(), "blocked") {
+		t.Errorf("Expected the blocked message to be dropped to the fallback, dropped %d:\n%s", h.Stats().Dropped, buf.String())
+	}
+}
+
+func TestWorkers(t *testing.T) {
+	api := &fakeAPI{}
+	h := newTestHook(WithAsync(true), WithWorkers(3))
+	h.client = api.client()
+
+	for i := 0; i < 20; i++ {
+		if err := h.Fire(&log.Entry{Level: log.ErrorLevel, Message: "m"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for len(api.methods()) < 20 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if n := len(api.methods()); n != 20 {
+		t.Errorf("Expected 20 messages delivered by the workers, got %d", n)
+	}
+	h.Close()
+}
+
+func TestFlush(t *testing.T) {
+	api := &fakeAPI{respond: func(method string, body []byte) *http.Response {
+		time.Sleep(20 * time.Millisecond)
+		return nil
+	}}
+	h := newTestHook(WithAsync(true))
+	h.client = api.client()
+
+	for i := 0; i < 3; i++ {
+		if err := h.Fire(&log.Entry{Level: log.ErrorLevel, Message: "m"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := h.Flush(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the flush to time out, got %v", err)
+	}
+
+	if err := h.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(api.methods()); n != 3 {
+		t.Errorf("Expected Close to deliver all 3 messages, got %d", n)
+	}
+}
+
+func TestPressure(t *testing.T) {
+	api := &fakeAPI{}
+	h := newTestHook(WithLevel(log.InfoLevel), WithQueueSize(4, QueueDrop), WithPressureGate(PressureGate{Threshold: 0.5, Level: log.WarnLevel}))
+	h.client = api.client()
+
+	if p := h.Pressure(); p != 0 {
+		t.Errorf("Expected no pressure, got %v", p)
+	}
+	for i := 0; i < 2; i++ {
+		h.pending.push(&pendingMessage{outgoing: outgoing{msg: "queued"}}, 0, 4, false)
+	}
+	if p := h.Pressure(); p != 0.5 {
+		t.Errorf("Expected a pressure of 0.5, got %v", p)
+	}
+
+	for _, level := range []log.Level{log.InfoLevel, log.ErrorLevel} {
+		if err := h.Fire(&log.Entry{Level: level, Message: "m"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := len(api.methods()); n != 1 || h.Stats().Dropped != 1 {
+		t.Errorf("Expected the info entry to be dropped at the gate, sent %d, dropped %d", n, h.Stats().Dropped)
+	}
+}
+
+func TestPressureGateValidation(t *testing.T) {
+	for _, threshold := range []float64{0, -0.5, 1.5} {
+		var cerr *ConfigError
+		_, err := NewTelegramHookWithClient("app", "123:abc", "1", "", (&fakeAPI{}).client(),
+			WithPressureGate(PressureGate{Threshold: threshold}))
+		if !errors.As(err, &cerr) || cerr.Field != "pressureGate" {
+			t.Errorf("Expected a pressureGate ConfigError for threshold %v, got %v", threshold, err)
+		}
+	}
+
+	var cerr *ConfigError
+	_, err := NewTelegramHookWithClient("app", "123:abc", "1", "", (&fakeAPI{}).client(),
+		WithPressureGate(PressureGate{Threshold: 1, Level: log.TraceLevel + 1}))
+	if !errors.As(err, &cerr) || cerr.Field != "pressureGate" {
+		t.Errorf("Expected a pressureGate ConfigError for an unknown level, got %v", err)
+	}
+
+	gate := PressureGate{Threshold: 1}
+	if level := gate.level(); level != log.ErrorLevel {
+		t.Errorf("Expected a zero level to let errors through, got %s", level)
+	}
+	gate.LevelSet = true
+	if level := gate.level(); level != log.PanicLevel {
+		t.Errorf("Expected LevelSet to select the panic level, got %s", level)
+	}
+}
+
+func TestPendingQueueKeyOrder(t *testing.T) {
+	var q pendingQueue
+	for _, m := range []*pendingMessage{
+		{outgoing: outgoing{key: "job-1", msg: "first"}},
+		{outgoing: outgoing{key: "job-1", msg: "second"}},
+		{outgoing: outgoing{msg: "other"}},
+	} {
+		q.push(m, 0, 0, false)
+	}
+
+	first := q.wait()
+	if m := q.wait(); m.msg != "other" {
+		t.Fatalf("Expected the message of another key while job-1 is busy, got %q", m.msg)
+	}
+
+	next := make(chan *pendingMessage)
+	go func() { next <- q.wait() }()
+	select {
+	case m := <-next:
+		t.Fatalf("Got %q while the first message of its key was busy", m.msg)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	q.done(first)
+	if m := <-next; m.msg != "second" {
+		t.Errorf("Expected the second message of job-1, got %q", m.msg)
+	}
+}
@@ -0,0 +1,137 @@
+package telegramhook
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RateLimit paces sendMessage requests to stay within the Telegram limits
+// instead of failing with 429 errors. Zero values use the limits documented
+// by Telegram.
+type RateLimit struct {
+	// PerSecond is the number of messages per second across all chats, 30 when zero.
+	PerSecond float64
+	// PerChat is the number of messages per second to a single chat, 1 when zero.
+	PerChat float64
+	// Burst is the number of messages a chat may receive at once, 1 when zero.
+	Burst int
+}
+
+func (r *RateLimit) perSecond() float64 {
+	if r.PerSecond > 0 {
+		return r.PerSecond
+	}
+	return 30
+}
+
+func (r *RateLimit) perChat() float64 {
+	if r.PerChat > 0 {
+		return r.PerChat
+	}
+	return 1
+}
+
+func (r *RateLimit) burst() float64 {
+	if r.Burst > 0 {
+		return float64(r.Burst)
+	}
+	return 1
+}
+
+// tokenBucket allows a number of events per second with bursts of events.
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// reserve takes a token from a bucket refilled at rate tokens per second up to
+// burst tokens and returns how long to wait until the token is available.
+// Tokens may be reserved ahead, so waiting callers are served in order.
+func (b *tokenBucket) reserve(now time.Time, rate, burst float64) time.Duration {
+	if b.last.IsZero() {
+		b.tokens = burst
+	} else {
+		b.tokens += now.Sub(b.last).Seconds() * rate
+		if b.tokens > burst {
+			b.tokens = burst
+		}
+	}
+	b.last = now
+
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / rate * float64(time.Second))
+}
+
+// rateLimiter holds the buckets of the bot and of every chat.
+type rateLimiter struct {
+	mu    sync.Mutex
+	bot   tokenBucket
+	chats map[string]*tokenBucket
+}
+
+// wait blocks until a message to chatId may be sent under limit or ctx is done.
+func (l *rateLimiter) wait(ctx context.Context, limit *RateLimit, chatId string) error {
+	l.mu.Lock()
+	now := time.Now()
+	d := l.bot.reserve(now, limit.perSecond(), limit.perSecond())
+
+	if l.chats == nil {
+		l.chats = map[string]*tokenBucket{}
+	}
+	chat, ok := l.chats[chatId]
+	if !ok {
+		chat = &tokenBucket{}
+		l.chats[chatId] = chat
+	}
+	if cd := chat.reserve(now, limit.perChat(), limit.burst()); cd > d {
+		d = cd
+	}
+	l.mu.Unlock()
+
+	return sleepContext(ctx, d)
+}
+
+// sharedLimiters holds the rate limiters shared by the hooks of a process that
+// use WithSharedRateLimit, one per bot token.
+var sharedLimiters = struct {
+	mu   sync.Mutex
+	bots map[string]*rateLimiter
+}{}
+
+// sharedLimiter returns the process-wide rate limiter of the bot with token.
+func sharedLimiter(token string) *rateLimiter {
+	sharedLimiters.mu.Lock()
+	defer sharedLimiters.mu.Unlock()
+
+	if sharedLimiters.bots == nil {
+		sharedLimiters.bots = map[string]*rateLimiter{}
+	}
+	l, ok := sharedLimiters.bots[token]
+	if !ok {
+		l = &rateLimiter{}
+		sharedLimiters.bots[token] = l
+	}
+	return l
+}
+
+// pace waits until a message may be sent under the configured rate limit. With
+// a shared rate limit, all hooks of the process sending through the same bot
+// draw from one budget, retries included.
+func (h *TelegramHook) pace(ctx context.Context, cfg config) error {
+	limit := cfg.rateLimit
+	if !cfg.sharedRateLimit {
+		if limit == nil {
+			return nil
+		}
+		return h.limiter.wait(ctx, limit, cfg.chatId)
+	}
+
+	if limit == nil {
+		limit = &RateLimit{}
+	}
+	return sharedLimiter(cfg.authToken).wait(ctx, limit, cfg.chatId)
+}
@@ -0,0 +1,78 @@
+package telegramhook
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestTokenBucket(t *testing.T) {
+	var b tokenBucket
+	now := time.Now()
+
+	for i := 0; i < 3; i++ {
+		if d := b.reserve(now, 1, 3); d != 0 {
+			t.Fatalf("Expected burst token %d immediately, wait %v", i, d)
+		}
+	}
+	if d := b.reserve(now, 1, 3); d != time.Second {
+		t.Errorf("Expected to wait 1s for the next token, got %v", d)
+	}
+	if d := b.reserve(now, 1, 3); d != 2*time.Second {
+		t.Errorf("Expected to wait 2s behind the reserved token, got %v", d)
+	}
+	if d := b.reserve(now.Add(10*time.Second), 1, 3); d != 0 {
+		t.Errorf("Expected a refilled bucket, wait %v", d)
+	}
+}
+
+func TestRateLimitPerChat(t *testing.T) {
+	api := &fakeAPI{}
+	h := newTestHook(WithRateLimit(RateLimit{PerChat: 50}))
+	h.client = api.client()
+	cfg := h.snapshot()
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if _, err := h.sendMessage(cfg, "hello"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 35*time.Millisecond {
+		t.Errorf("Expected messages to one chat to be paced, took %v", elapsed)
+	}
+}
+
+func TestSharedRateLimit(t *testing.T) {
+	api := &fakeAPI{}
+	limit := RateLimit{PerSecond: 50, PerChat: 1000, Burst: 1000}
+	h1 := newTestHook(WithRateLimit(limit), WithSharedRateLimit(true))
+	h2 := newTestHook(WithRateLimit(limit), WithSharedRateLimit(true))
+	h1.authToken, h2.authToken = "shared:test", "shared:test"
+	h1.chatId, h2.chatId = "1", "2"
+	h1.client, h2.client = api.client(), api.client()
+
+	if sharedLimiter("shared:test") != sharedLimiter("shared:test") || sharedLimiter("shared:test") == sharedLimiter("other:test") {
+		t.Fatal("Expected one limiter per bot token")
+	}
+
+	// The bot bucket starts with 50 tokens, so the next messages of both hooks
+	// wait for each other.
+	for i := 0; i < 50; i++ {
+		if err := sharedLimiter("shared:test").wait(context.Background(), &limit, "warmup"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	start := time.Now()
+	for i := 0; i < 2; i++ {
+		for _, h := range []*TelegramHook{h1, h2} {
+			if _, err := h.sendMessage(h.snapshot(), "hello"); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 70*time.Millisecond {
+		t.Errorf("Expected the hooks to share the bot budget, took %v", elapsed)
+	}
+}
@@ -0,0 +1,89 @@
+package telegramhook
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"time"
+
+	"github.com/andoma-go/logrus"
+)
+
+// Level is the logger independent severity of a Record. Levels are numbered
+// like logrus levels, lower values are more severe.
+type Level uint32
+
+const (
+	LevelPanic Level = iota
+	LevelFatal
+	LevelError
+	LevelWarn
+	LevelInfo
+	LevelDebug
+	LevelTrace
+)
+
+// logrus returns the corresponding logrus level.
+func (l Level) logrus() logrus.Level {
+	return logrus.Level(l)
+}
+
+// Record is a minimal, logger independent log entry and the input of the
+// hook's pipeline. Adapters for other loggers or logrus flavours convert their
+// entries into a Record and pass it to FireRecord, sharing the formatting and
+// delivery of the hook; Fire does the same for logrus entries.
+type Record struct {
+	Time    time.Time
+	Level   Level
+	Message string
+	Fields  map[string]interface{}
+	Caller  *runtime.Frame
+	Context context.Context
+
+	// origin is the logrus entry the record was converted from. It is passed
+	// on as is, so formatters, error handlers and fallback hooks see the entry
+	// that was logged.
+	origin *logrus.Entry
+}
+
+// recordOf converts a logrus entry into a record.
+func recordOf(entry *logrus.Entry) Record {
+	return Record{
+		Time:    entry.Time,
+		Level:   Level(entry.Level),
+		Message: entry.Message,
+		Fields:  entry.Data,
+		Caller:  entry.Caller,
+		Context: entry.Context,
+		origin:  entry,
+	}
+}
+
+// entry converts the record into the logrus entry that rendering works on.
+func (r Record) entry() *logrus.Entry {
+	if r.origin != nil {
+		return r.origin
+	}
+	return &logrus.Entry{
+		Time:    r.Time,
+		Level:   r.Level.logrus(),
+		Message: r.Message,
+		Data:    logrus.Fields(r.Fields),
+		Caller:  r.Caller,
+		Context: r.Context,
+	}
+}
+
+// key returns the correlation key of the record, see CorrelationKey.
+func (r Record) key() string {
+	v, ok := r.Fields[CorrelationKey]
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+// FireRecord emits a record via the hook, like Fire does for logrus entries.
+func (h *TelegramHook) FireRecord(r Record) error {
+	return h.fire(r)
+}
@@ -0,0 +1,32 @@
+package telegramhook
+
+import (
+	"testing"
+
+	log "github.com/andoma-go/logrus"
+)
+
+func TestRecordPipeline(t *testing.T) {
+	entry := &log.Entry{Level: log.WarnLevel, Message: "m", Data: log.Fields{CorrelationKey: 7}}
+	r := recordOf(entry)
+	if r.Level != LevelWarn || r.key() != "7" || r.entry() != entry {
+		t.Errorf("Unexpected record %+v of entry", r)
+	}
+
+	converted := Record{Level: LevelError, Message: "m", Fields: map[string]interface{}{"id": 1}}.entry()
+	if converted.Level != log.ErrorLevel || converted.Data["id"] != 1 {
+		t.Errorf("Unexpected entry %+v of record", converted)
+	}
+
+	h := newTestHook()
+	events := h.Events()
+	if err := h.FireRecord(r); err != nil {
+		t.Fatal(err)
+	}
+	if st := h.Stats(); st.Fired != 1 || st.Skipped[log.WarnLevel] != 1 {
+		t.Errorf("Expected the record to be skipped by level, got %+v", st)
+	}
+	if e := <-events; e.Type != EventMuted || e.Key != "7" {
+		t.Errorf("Unexpected event %+v", e)
+	}
+}
@@ -0,0 +1,180 @@
+package telegramhook
+
+import (
+	"net/http"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/andoma-go/logrus"
+)
+
+// redacted replaces masked values and pattern matches.
+const redacted = "[REDACTED]"
+
+// DefaultRedactedFields are field names commonly holding secrets, for use
+// with WithRedaction.
+var DefaultRedactedFields = []string{"password", "passwd", "secret", "token", "authorization", "api_key", "apikey", "cookie"}
+
+// redactText replaces the matches of patterns in s.
+func redactText(s string, patterns []*regexp.Regexp) string {
+	for _, p := range patterns {
+		s = p.ReplaceAllString(s, redacted)
+	}
+	return s
+}
+
+// redactEntry returns entry, or a copy with the values of the redacted fields
+// masked and the matches of the patterns replaced in the message and in the
+// field values. Redacted keys are also masked inside maps and structs.
+func (c *config) redactEntry(entry *logrus.Entry) *logrus.Entry {
+	if len(c.redactKeys) == 0 && len(c.redactPatterns) == 0 {
+		return entry
+	}
+
+	e := *entry
+	e.Message = redactText(entry.Message, c.redactPatterns)
+	e.Data = make(logrus.Fields, len(entry.Data))
+	for k, v := range entry.Data {
+		if c.redactedKey(k) {
+			e.Data[k] = redacted
+			continue
+		}
+		e.Data[k] = c.redactValue(k, v)
+	}
+	return &e
+}
+
+// maxRedactDepth bounds how deep redactValue looks into nested values.
+const maxRedactDepth = 8
+
+// redactValue returns v of field k with redaction applied. Strings and errors
+// keep their type; other values that contain a redacted key or a match of the
+// patterns are replaced by their redacted text.
+func (c *config) redactValue(k string, v interface{}) interface{} {
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case string:
+		return redactText(v, c.redactPatterns)
+	case error:
+		if msg := v.Error(); redactText(msg, c.redactPatterns) != msg {
+			return &sanitizedError{err: v, msg: redactText(msg, c.redactPatterns)}
+		}
+		return v
+	case []byte:
+		if s := redactText(string(v), c.redactPatterns); s != string(v) {
+			return s
+		}
+		return v
+	case *http.Request:
+		if k == HTTPRequestKey {
+			return v // rendered masked, see formatHTTPRequest
+		}
+		return c.formatHTTPRequest(v)
+	case *http.Response:
+		if k == HTTPResponseKey {
+			return v
+		}
+		return redactText(formatHTTPResponse(v), c.redactPatterns)
+	}
+
+	text := formatValue(v)
+	masked := text
+	if m, changed := c.maskKeys(reflect.ValueOf(v), 0); changed {
+		masked = formatValue(m)
+	}
+	if masked = redactText(masked, c.redactPatterns); masked != text {
+		return masked
+	}
+	return v
+}
+
+// maskKeys returns a copy of maps and structs in v with the values of
+// redacted keys and struct fields masked, and whether any was masked. Maps and
+// structs become map[string]interface{} in the copy.
+func (c *config) maskKeys(v reflect.Value, depth int) (interface{}, bool) {
+	if !v.IsValid() || depth > maxRedactDepth {
+		return nil, false
+	}
+
+	switch v.Kind() {
+	case reflect.Interface, reflect.Pointer:
+		if v.IsNil() {
+			return nil, false
+		}
+		return c.maskKeys(v.Elem(), depth+1)
+
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			break
+		}
+		out := make(map[string]interface{}, v.Len())
+		changed := false
+		for iter := v.MapRange(); iter.Next(); {
+			name := iter.Key().String()
+			out[name], changed = c.redactMember(name, iter.Value(), depth, changed)
+		}
+		return out, changed
+
+	case reflect.Struct:
+		out := make(map[string]interface{}, v.NumField())
+		changed := false
+		for i := 0; i < v.NumField(); i++ {
+			f := v.Type().Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			name := f.Name
+			if tag, _, _ := strings.Cut(f.Tag.Get("json"), ","); c.redactedKey(tag) {
+				name = tag
+			}
+			out[f.Name], changed = c.redactMember(name, v.Field(i), depth, changed)
+		}
+		return out, changed
+
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			break
+		}
+		out := make([]interface{}, v.Len())
+		changed := false
+		for i := range out {
+			masked, ok := c.maskKeys(v.Index(i), depth+1)
+			if !ok && v.Index(i).CanInterface() {
+				masked = v.Index(i).Interface()
+			}
+			out[i], changed = masked, changed || ok
+		}
+		return out, changed
+	}
+
+	if v.CanInterface() {
+		return v.Interface(), false
+	}
+	return nil, false
+}
+
+// redactMember returns the value of the map key or struct field name, masked
+// if name is a redacted key, and whether anything was masked so far.
+func (c *config) redactMember(name string, v reflect.Value, depth int, changed bool) (interface{}, bool) {
+	if c.redactedKey(name) {
+		return redacted, true
+	}
+	masked, ok := c.maskKeys(v, depth+1)
+	if !ok && v.CanInterface() {
+		masked = v.Interface()
+	}
+	return masked, changed || ok
+}
+
+// redactedKey reports whether the value of field k is masked. Field names are
+// compared case-insensitively.
+func (c *config) redactedKey(k string) bool {
+	for _, key := range c.redactKeys {
+		if strings.EqualFold(k, key) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,91 @@
+package telegramhook
+
+import (
+	"errors"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	log "github.com/andoma-go/logrus"
+)
+
+func TestRedaction(t *testing.T) {
+	api := &fakeAPI{}
+	h := newTestHook(WithRedaction(DefaultRedactedFields, []*regexp.Regexp{regexp.MustCompile(`sk_live_\w+`)}))
+	h.client = api.client()
+
+	err := errors.New("auth failed for key sk_live_abc123")
+	entry := &log.Entry{Level: log.ErrorLevel, Message: "charge with sk_live_abc123 failed", Data: log.Fields{
+		"Authorization": "Bearer eyJhbGciOi",
+		"password":      42,
+		"user":          "bob",
+		log.ErrorKey:    err,
+	}}
+	if err := h.Fire(entry); err != nil {
+		t.Fatal(err)
+	}
+
+	msg := api.texts()[0]
+	for _, want := range []string{
+		"charge with [REDACTED] failed",
+		"\tAuthorization: [REDACTED]",
+		"\tpassword: [REDACTED]",
+		"\terror: auth failed for key [REDACTED]",
+		"\tuser: bob",
+	} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("Expected %q in %q", want, msg)
+		}
+	}
+	if strings.Contains(msg, "sk_live") || strings.Contains(msg, "eyJ") {
+		t.Errorf("Secret leaked into %q", msg)
+	}
+	if entry.Data["password"] != 42 {
+		t.Error("Redaction modified the entry")
+	}
+}
+
+type redactCredentials struct {
+	User     string
+	Password string
+	APIKey   string `json:"api_key"`
+}
+
+func TestRedactionNested(t *testing.T) {
+	api := &fakeAPI{}
+	h := newTestHook(WithRedaction(DefaultRedactedFields, []*regexp.Regexp{regexp.MustCompile(`sk_live_\w+`)}))
+	h.client = api.client()
+
+	req := httptest.NewRequest("GET", "https://x.example/charge/sk_live_path?token=t0ps3cret", nil)
+	entry := &log.Entry{Level: log.ErrorLevel, Message: "m", Data: log.Fields{
+		"config":  map[string]interface{}{"user": "bob", "token": "t0ps3cret"},
+		"creds":   &redactCredentials{User: "bob", Password: "hunter2", APIKey: "k3y"},
+		"payload": []byte("key=sk_live_bytes"),
+		"list":    []map[string]string{{"secret": "s3cret"}},
+		"req":     req,
+		"count":   42,
+	}}
+	if err := h.Fire(entry); err != nil {
+		t.Fatal(err)
+	}
+
+	msg := api.texts()[0]
+	for _, want := range []string{
+		"config: map[token:[REDACTED] user:bob]",
+		"creds: map[APIKey:[REDACTED] Password:[REDACTED] User:bob]",
+		"payload: key=[REDACTED]",
+		"list: [map[secret:[REDACTED]]]",
+		"GET /charge/[REDACTED]?token=***",
+		"count: 42",
+	} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("Expected %q in %q", want, msg)
+		}
+	}
+	for _, secret := range []string{"t0ps3cret", "hunter2", "k3y", "sk_live", "s3cret"} {
+		if strings.Contains(msg, secret) {
+			t.Errorf("Secret %q leaked into %q", secret, msg)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package telegramhook
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// renderers holds the functions registered with RegisterRenderer by type.
+var renderers sync.Map // reflect.Type -> func(interface{}) string
+
+// RegisterRenderer renders field values of type t with render wherever fields
+// are shown: in messages, tables, fields documents, headlines and templates,
+// e.g. to show a Money as "12.50 EUR" or an OrderID with its prefix. It takes
+// precedence over WithHumanize for that type. A nil render removes the
+// renderer of t. Renderers apply to all hooks and must be safe for concurrent
+// use.
+func RegisterRenderer(t reflect.Type, render func(v interface{}) string) {
+	if render == nil {
+		renderers.Delete(t)
+		return
+	}
+	renderers.Store(t, render)
+}
+
+// renderer returns the renderer registered for the type of v, if any.
+func renderer(v interface{}) (func(interface{}) string, bool) {
+	if v == nil {
+		return nil, false
+	}
+	render, ok := renderers.Load(reflect.TypeOf(v))
+	if !ok {
+		return nil, false
+	}
+	return render.(func(interface{}) string), true
+}
+
+// formatValue renders a field value with its registered renderer, or with
+// %+v when there is none.
+func formatValue(v interface{}) string {
+	if render, ok := renderer(v); ok {
+		return render(v)
+	}
+	return fmt.Sprintf("%+v", v)
+}
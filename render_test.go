@@ -0,0 +1,50 @@
+package telegramhook
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	log "github.com/andoma-go/logrus"
+)
+
+type testMoney struct {
+	Cents    int64
+	Currency string
+}
+
+func TestRegisterRenderer(t *testing.T) {
+	RegisterRenderer(reflect.TypeOf(testMoney{}), func(v interface{}) string {
+		m := v.(testMoney)
+		return fmt.Sprintf("%d.%02d %s", m.Cents/100, m.Cents%100, m.Currency)
+	})
+	RegisterRenderer(reflect.TypeOf(time.Duration(0)), func(v interface{}) string {
+		return fmt.Sprintf("%dms", v.(time.Duration).Milliseconds())
+	})
+	defer RegisterRenderer(reflect.TypeOf(testMoney{}), nil)
+	defer RegisterRenderer(reflect.TypeOf(time.Duration(0)), nil)
+
+	h := newTestHook(WithHumanize(true))
+	entry := &log.Entry{Level: log.ErrorLevel, Message: "refund failed", Data: log.Fields{
+		"amount":  testMoney{Cents: 1250, Currency: "EUR"},
+		"elapsed": 1500 * time.Millisecond,
+	}}
+	msg := createMessage(h, entry)
+	for _, want := range []string{"\tamount: 12.50 EUR", "\telapsed: 1500ms"} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("Expected %q in message %q", want, msg)
+		}
+	}
+
+	cfg := h.snapshot()
+	if got := cfg.emptyHeadline(&log.Entry{Data: entry.Data}); got != "amount=12.50 EUR, elapsed=1500ms" {
+		t.Errorf("Headline %q does not use the renderers", got)
+	}
+
+	RegisterRenderer(reflect.TypeOf(testMoney{}), nil)
+	if got := formatValue(testMoney{Cents: 5, Currency: "USD"}); got != "{Cents:5 Currency:USD}" {
+		t.Errorf("formatValue() = %q after removing the renderer", got)
+	}
+}
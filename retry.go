@@ -0,0 +1,163 @@
+package telegramhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+// Jitter selects how retry delays are randomized so that many hooks failing at
+// the same time do not retry in lockstep.
+type Jitter int
+
+const (
+	// JitterFull waits a random time between zero and the exponential delay.
+	JitterFull Jitter = iota
+	// JitterEqual waits half the exponential delay plus a random part of the other half.
+	JitterEqual
+	// JitterDecorrelated waits a random time between the base delay and three
+	// times the previous delay.
+	JitterDecorrelated
+	// JitterNone waits exactly the exponential delay.
+	JitterNone
+)
+
+func (j Jitter) String() string {
+	switch j {
+	case JitterEqual:
+		return "equal"
+	case JitterDecorrelated:
+		return "decorrelated"
+	case JitterNone:
+		return "none"
+	}
+	return "full"
+}
+
+const (
+	// defaultRetryDelay is the base delay of the first retry.
+	defaultRetryDelay = 500 * time.Millisecond
+	// maxRetryDelay caps the delay between two attempts.
+	maxRetryDelay = 30 * time.Second
+	// maxTrackedAttempts is the last bucket of the attempts histogram.
+	maxTrackedAttempts = 10
+)
+
+// backoff computes the delays between the attempts of a single request.
+type backoff struct {
+	base   time.Duration
+	jitter Jitter
+	prev   time.Duration
+}
+
+// next returns the delay before retry n, starting at 1.
+func (b *backoff) next(n int) time.Duration {
+	base := b.base
+	if base <= 0 {
+		base = defaultRetryDelay
+	}
+
+	exp := maxRetryDelay
+	if n < 32 && base<<(n-1) < maxRetryDelay {
+		exp = base << (n - 1)
+	}
+
+	var d time.Duration
+	switch b.jitter {
+	case JitterEqual:
+		d = exp/2 + randDuration(exp/2)
+	case JitterDecorrelated:
+		prev := b.prev
+		if prev < base {
+			prev = base
+		}
+		d = base + randDuration(3*prev-base)
+		if d > maxRetryDelay {
+			d = maxRetryDelay
+		}
+	case JitterNone:
+		d = exp
+	default:
+		d = randDuration(exp)
+	}
+
+	b.prev = d
+	return d
+}
+
+// randDuration returns a random duration in [0, d).
+func randDuration(d time.Duration) time.Duration {
+	if d <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(d)))
+}
+
+// transient reports whether a failed request may succeed when retried: network
+// errors, undecodable responses, server errors and 429 Too Many Requests are,
+// other API errors are not.
+func transient(err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Code >= 500 || apiErr.Code == http.StatusTooManyRequests
+	}
+	return true
+}
+
+// retryAfter returns the delay Telegram asked for with err, zero if none.
+func retryAfter(err error) time.Duration {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.RetryAfter
+	}
+	return 0
+}
+
+// retry runs fn until it succeeds, fails permanently or the configured number
+// of attempts is used up, and records the attempts it took. Retries wait at
+// least as long as Telegram asks for with retry_after. Only the error of the
+// last attempt is returned. Retrying stops early once ctx is done.
+func (h *TelegramHook) retry(ctx context.Context, cfg config, fn func() error) error {
+	b := backoff{base: cfg.retryDelay, jitter: cfg.jitter}
+
+	n := 1
+	err := fn()
+	for ; err != nil && n < cfg.retryAttempts && transient(err) && ctx.Err() == nil; n++ {
+		d := b.next(n)
+		if after := retryAfter(err); after > d {
+			d = after
+		}
+		h.emit(Event{Type: EventRetried, Attempt: n + 1, Err: err})
+		if err := sleepContext(ctx, d); err != nil {
+			break
+		}
+		err = fn()
+	}
+
+	h.stats.attempt(n)
+	if err != nil && n > 1 {
+		return fmt.Errorf("after %d attempts: %w", n, err)
+	}
+	return err
+}
+
+// sleepContext pauses for d or until ctx is done, returning the error of ctx
+// in the latter case.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
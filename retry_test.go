@@ -0,0 +1,132 @@
+package telegramhook
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	base := 100 * time.Millisecond
+	for _, jitter := range []Jitter{JitterFull, JitterEqual, JitterDecorrelated, JitterNone} {
+		b := backoff{base: base, jitter: jitter}
+		for n := 1; n <= 12; n++ {
+			exp := base << (n - 1)
+			if exp > maxRetryDelay {
+				exp = maxRetryDelay
+			}
+
+			d := b.next(n)
+			lo, hi := time.Duration(0), exp
+			switch jitter {
+			case JitterEqual:
+				lo = exp / 2
+			case JitterDecorrelated:
+				lo, hi = base, maxRetryDelay
+			case JitterNone:
+				lo = exp
+			}
+			if d < lo || d > hi {
+				t.Errorf("Jitter %d, retry %d: delay %v not in [%v, %v]", jitter, n, d, lo, hi)
+			}
+		}
+	}
+}
+
+func TestRetry(t *testing.T) {
+	failures := 2
+	api := &fakeAPI{respond: func(method string, body []byte) *http.Response {
+		if failures > 0 {
+			failures--
+			return jsonResponse(http.StatusBadGateway, `{"ok":false,"error_code":502,"description":"Bad Gateway"}`)
+		}
+		return nil
+	}}
+	h := newTestHook(WithRetry(3, time.Millisecond))
+	h.client = api.client()
+
+	if _, err := h.sendMessage(h.snapshot(), "hello"); err != nil {
+		t.Fatal(err)
+	}
+	if got := h.Stats().Attempts; !reflect.DeepEqual(got, map[int]uint64{3: 1}) {
+		t.Errorf("Unexpected attempts histogram %v", got)
+	}
+}
+
+func TestRetryPermanentError(t *testing.T) {
+	api := &fakeAPI{respond: func(method string, body []byte) *http.Response {
+		return jsonResponse(http.StatusBadRequest, `{"ok":false,"error_code":400,"description":"Bad Request: chat not found"}`)
+	}}
+	h := newTestHook(WithRetry(3, time.Millisecond))
+	h.client = api.client()
+
+	if _, err := h.sendMessage(h.snapshot(), "hello"); err == nil {
+		t.Fatal("Expected an error")
+	}
+	if n := len(api.methods()); n != 1 {
+		t.Errorf("Expected a single attempt for a permanent error, got %d", n)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	var attempts []time.Time
+	api := &fakeAPI{respond: func(method string, body []byte) *http.Response {
+		attempts = append(attempts, time.Now())
+		if len(attempts) == 1 {
+			return jsonResponse(http.StatusTooManyRequests, `{"ok":false,"error_code":429,"description":"Too Many Requests: retry after 1","parameters":{"retry_after":1}}`)
+		}
+		return nil
+	}}
+	h := newTestHook(WithRetry(2, time.Millisecond))
+	h.client = api.client()
+
+	if _, err := h.sendMessage(h.snapshot(), "hello"); err != nil {
+		t.Fatal(err)
+	}
+	if len(attempts) != 2 || attempts[1].Sub(attempts[0]) < time.Second {
+		t.Errorf("Expected a retry after the delay asked for by Telegram, attempts at %v", attempts)
+	}
+}
+
+func TestRetryExhausted(t *testing.T) {
+	api := &fakeAPI{respond: func(method string, body []byte) *http.Response {
+		return jsonResponse(http.StatusInternalServerError, `{"ok":false,"error_code":500,"description":"Internal Server Error"}`)
+	}}
+	h := newTestHook(WithRetry(3, time.Millisecond))
+	h.client = api.client()
+
+	_, err := h.sendMessage(h.snapshot(), "hello")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != 500 || !strings.HasPrefix(err.Error(), "after 3 attempts: ") {
+		t.Errorf("Expected the last API error after all attempts, got %v", err)
+	}
+	if n := len(api.methods()); n != 3 {
+		t.Errorf("Expected 3 attempts, got %d", n)
+	}
+}
+
+func TestRetryCancelled(t *testing.T) {
+	api := &fakeAPI{respond: func(method string, body []byte) *http.Response {
+		return jsonResponse(http.StatusBadGateway, `{"ok":false,"error_code":502,"description":"Bad Gateway"}`)
+	}}
+	h := newTestHook(WithRetry(3, time.Hour), WithJitter(JitterNone))
+	h.client = api.client()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if _, err := h.sendReply(ctx, h.snapshot(), "hello", replyParameters{}); err == nil {
+		t.Fatal("Expected an error")
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("Retry did not stop when the context was done, took %v", d)
+	}
+	if n := len(api.methods()); n != 1 {
+		t.Errorf("Expected a single attempt, got %d", n)
+	}
+}
@@ -0,0 +1,132 @@
+package telegramhook
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/andoma-go/logrus"
+)
+
+// ControlChars selects what happens to ANSI escape sequences and control
+// characters in messages and field values, e.g. colors of wrapped CLI tools.
+type ControlChars int
+
+const (
+	// ControlStrip removes ANSI escape sequences and control characters other
+	// than newlines and tabs.
+	ControlStrip ControlChars = iota
+	// ControlVisualize shows control characters as symbols, e.g. "␛[31m" for
+	// the escape sequence that turns text red.
+	ControlVisualize
+	// ControlKeep sends the text unchanged.
+	ControlKeep
+)
+
+func (m ControlChars) String() string {
+	switch m {
+	case ControlVisualize:
+		return "visualize"
+	case ControlKeep:
+		return "keep"
+	}
+	return "strip"
+}
+
+// ansiPattern matches CSI sequences such as colors and cursor movement, OSC
+// sequences such as hyperlinks and window titles, and two-character escapes.
+var ansiPattern = regexp.MustCompile(`\x1b\[[0-?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(?:\x07|\x1b\\)|\x1b[@-Z\\-_]`)
+
+// controlChar reports whether r is a control character shown as garbage by
+// Telegram. Newlines and tabs are kept.
+func controlChar(r rune) bool {
+	return r < 0x20 && r != '\n' && r != '\t' || r >= 0x7f && r < 0xa0
+}
+
+// sanitizeText applies mode to s.
+func sanitizeText(s string, mode ControlChars) string {
+	if mode == ControlKeep || strings.IndexFunc(s, controlChar) < 0 {
+		return s
+	}
+
+	if mode == ControlVisualize {
+		var b strings.Builder
+		for _, r := range s {
+			switch {
+			case !controlChar(r):
+				b.WriteRune(r)
+			case r < 0x20:
+				b.WriteRune(0x2400 + r) // Control Pictures block, e.g. ␛
+			case r == 0x7f:
+				b.WriteRune('␡')
+			default:
+				fmt.Fprintf(&b, `\x%02x`, r)
+			}
+		}
+		return b.String()
+	}
+
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	s = ansiPattern.ReplaceAllString(s, "")
+	return strings.Map(func(r rune) rune {
+		if controlChar(r) {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// sanitizedError is an error with a sanitized or redacted message, which still
+// unwraps to the original error.
+type sanitizedError struct {
+	err error
+	msg string
+}
+
+func (e *sanitizedError) Error() string { return e.msg }
+func (e *sanitizedError) Unwrap() error { return e.err }
+
+// sanitizeEntry returns entry, or a copy with mode applied to the message and
+// to string and error fields when they contain control characters.
+func sanitizeEntry(entry *logrus.Entry, mode ControlChars) *logrus.Entry {
+	if mode == ControlKeep {
+		return entry
+	}
+
+	var sanitized *logrus.Entry
+	copyEntry := func() {
+		if sanitized != nil {
+			return
+		}
+		e := *entry
+		e.Data = make(logrus.Fields, len(entry.Data))
+		for k, v := range entry.Data {
+			e.Data[k] = v
+		}
+		sanitized = &e
+	}
+
+	if msg := sanitizeText(entry.Message, mode); msg != entry.Message {
+		copyEntry()
+		sanitized.Message = msg
+	}
+	for k, v := range entry.Data {
+		switch v := v.(type) {
+		case string:
+			if s := sanitizeText(v, mode); s != v {
+				copyEntry()
+				sanitized.Data[k] = s
+			}
+		case error:
+			if msg := v.Error(); sanitizeText(msg, mode) != msg {
+				copyEntry()
+				sanitized.Data[k] = &sanitizedError{err: v, msg: sanitizeText(msg, mode)}
+			}
+		}
+	}
+
+	if sanitized == nil {
+		return entry
+	}
+	return sanitized
+}
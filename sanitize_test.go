@@ -0,0 +1,56 @@
+package telegramhook
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	log "github.com/andoma-go/logrus"
+)
+
+func TestSanitizeText(t *testing.T) {
+	tests := []struct {
+		in   string
+		mode ControlChars
+		want string
+	}{
+		{"plain\n\ttext", ControlStrip, "plain\n\ttext"},
+		{"\x1b[1;31mfailed\x1b[0m to start", ControlStrip, "failed to start"},
+		{"\x1b]8;;https://example.com\x07link\x1b]8;;\x07", ControlStrip, "link"},
+		{"line\r\nnext\x00\x07", ControlStrip, "line\nnext"},
+		{"\x1b[31mred\x1b[0m", ControlVisualize, "␛[31mred␛[0m"},
+		{"nul\x00 del\x7f c1\u0085", ControlVisualize, `nul␀ del␡ c1\x85`},
+		{"\x1b[31mred", ControlKeep, "\x1b[31mred"},
+	}
+
+	for _, test := range tests {
+		if got := sanitizeText(test.in, test.mode); got != test.want {
+			t.Errorf("sanitizeText(%q, %v) = %q, want %q", test.in, test.mode, got, test.want)
+		}
+	}
+}
+
+func TestSanitizeEntry(t *testing.T) {
+	err := errors.New("\x1b[33mconnection reset\x1b[0m")
+	entry := &log.Entry{Level: log.ErrorLevel, Message: "\x1b[31mdeploy failed\x1b[0m", Data: log.Fields{
+		"step":  "\x1b[1mmigrate\x1b[0m",
+		"error": err,
+		"code":  3,
+	}}
+
+	sanitized := sanitizeEntry(entry, ControlStrip)
+	if sanitized == entry || entry.Message != "\x1b[31mdeploy failed\x1b[0m" {
+		t.Fatal("sanitizeEntry modified the original entry")
+	}
+	if sanitized.Message != "deploy failed" || sanitized.Data["step"] != "migrate" || sanitized.Data["code"] != 3 {
+		t.Errorf("Sanitized entry %q %v", sanitized.Message, sanitized.Data)
+	}
+	if got := sanitized.Data["error"].(error); got.Error() != "connection reset" || !errors.Is(got, err) {
+		t.Errorf("Sanitized error %q does not unwrap to the original", got)
+	}
+
+	clean := &log.Entry{Message: "fine", Data: log.Fields{"error": io.EOF}}
+	if sanitizeEntry(clean, ControlStrip) != clean {
+		t.Error("sanitizeEntry copied an entry without control characters")
+	}
+}
@@ -0,0 +1,13 @@
+module github.com/andoma-go/logrus-hook-telegram/sirupsen
+
+go 1.21
+
+require (
+	github.com/andoma-go/logrus-hook-telegram v0.0.0-20261016113935-5714c55ba887
+	github.com/sirupsen/logrus v1.9.3
+)
+
+require (
+	github.com/andoma-go/logrus v0.0.0-20240115082234-306b2495b780 // indirect
+	golang.org/x/sys v0.0.0-20220715151400-c0bba94af5f8 // indirect
+)
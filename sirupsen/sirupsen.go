@@ -0,0 +1,49 @@
+// Package sirupsen adapts the Telegram hook to loggers of the upstream
+// github.com/sirupsen/logrus package.
+//
+// It lives in its own module so that users of the andoma-go/logrus fork do not
+// pull in the upstream package.
+package sirupsen
+
+import (
+	telegramhook "github.com/andoma-go/logrus-hook-telegram"
+	"github.com/sirupsen/logrus"
+)
+
+// Hook sends entries of sirupsen/logrus loggers via a TelegramHook.
+type Hook struct {
+	*telegramhook.TelegramHook
+}
+
+// New wraps hook for use with sirupsen/logrus loggers. With WithExitFlush it
+// registers a sirupsen/logrus exit handler, so queued messages are delivered
+// before Fatal exits the process.
+func New(hook *telegramhook.TelegramHook) *Hook {
+	if hook.ExitFlush() > 0 {
+		logrus.RegisterExitHandler(hook.FlushOnExit)
+	}
+	return &Hook{TelegramHook: hook}
+}
+
+// Levels returns all levels, filtering happens in the wrapped hook.
+func (h *Hook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire converts the entry and emits it via the wrapped hook.
+func (h *Hook) Fire(entry *logrus.Entry) error {
+	return h.TelegramHook.FireRecord(convertEntry(entry))
+}
+
+// convertEntry converts a sirupsen/logrus entry into a record. Hook levels are
+// numbered like logrus levels.
+func convertEntry(entry *logrus.Entry) telegramhook.Record {
+	return telegramhook.Record{
+		Time:    entry.Time,
+		Level:   telegramhook.Level(entry.Level),
+		Message: entry.Message,
+		Fields:  entry.Data,
+		Caller:  entry.Caller,
+		Context: entry.Context,
+	}
+}
@@ -0,0 +1,23 @@
+package sirupsen
+
+import (
+	"errors"
+	"testing"
+
+	telegramhook "github.com/andoma-go/logrus-hook-telegram"
+	"github.com/sirupsen/logrus"
+)
+
+func TestConvertEntry(t *testing.T) {
+	entry := logrus.NewEntry(logrus.New()).WithError(errors.New("boom")).WithField("id", 7)
+	entry.Level = logrus.WarnLevel
+	entry.Message = "m"
+
+	converted := convertEntry(entry)
+	if converted.Level != telegramhook.LevelWarn || converted.Message != "m" || converted.Fields["id"] != 7 {
+		t.Errorf("Unexpected converted entry: %+v", converted)
+	}
+	if _, ok := converted.Fields[logrus.ErrorKey]; !ok {
+		t.Error("Error field was not converted")
+	}
+}
@@ -0,0 +1,103 @@
+package telegramhook
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/andoma-go/logrus"
+)
+
+// defaultMaxEntrySize is the default limit for the estimated size of an entry.
+const defaultMaxEntrySize = 1 << 20
+
+// estimateSize cheaply approximates the rendered size of a field value in
+// bytes without formatting it.
+func estimateSize(v interface{}) int {
+	switch v := v.(type) {
+	case nil:
+		return 5
+	case string:
+		return len(v)
+	case []byte:
+		return len(v)
+	case error:
+		return len(v.Error())
+	case fmt.Stringer:
+		return 64
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.String:
+		return rv.Len()
+	case reflect.Slice, reflect.Array, reflect.Map, reflect.Chan:
+		return 16 * rv.Len()
+	case reflect.Ptr:
+		if rv.IsNil() {
+			return 5
+		}
+		return estimateSize(rv.Elem().Interface())
+	}
+	return 32
+}
+
+// limitEntry returns entry, or a copy with the largest fields replaced by a
+// short placeholder while the estimated size exceeds maxSize, so absurdly
+// large values are never formatted. A maxSize of zero disables the limit.
+func limitEntry(entry *logrus.Entry, maxSize int) *logrus.Entry {
+	if maxSize <= 0 {
+		return entry
+	}
+
+	sizes := make(map[string]int, len(entry.Data))
+	total := len(entry.Message)
+	for k, v := range entry.Data {
+		sizes[k] = estimateSize(v) + len(k)
+		total += sizes[k]
+	}
+
+	if total <= maxSize {
+		return entry
+	}
+
+	limited := *entry
+	limited.Data = make(logrus.Fields, len(entry.Data))
+	for k, v := range entry.Data {
+		limited.Data[k] = v
+	}
+
+	for total > maxSize && len(sizes) > 0 {
+		largest := ""
+		for k, n := range sizes {
+			if largest == "" || n > sizes[largest] {
+				largest = k
+			}
+		}
+
+		v := entry.Data[largest]
+		limited.Data[largest] = fmt.Sprintf("[%T omitted, ~%s]", v, formatBytes(int64(sizes[largest])))
+		total -= sizes[largest]
+		delete(sizes, largest)
+	}
+
+	if total > maxSize {
+		limited.Message = truncateText(limited.Message, maxMessageLength)
+	}
+
+	return &limited
+}
+
+// formatBytes formats n as a human readable binary size, e.g. "3.4 MiB".
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
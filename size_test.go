@@ -0,0 +1,33 @@
+package telegramhook
+
+import (
+	"strings"
+	"testing"
+
+	log "github.com/andoma-go/logrus"
+)
+
+func TestLimitEntry(t *testing.T) {
+	entry := &log.Entry{
+		Message: "upload failed",
+		Data: log.Fields{
+			"body": strings.Repeat("x", 3<<20),
+			"id":   42,
+		},
+	}
+
+	limited := limitEntry(entry, 1<<20)
+	if limited == entry {
+		t.Fatal("Oversized entry was not limited")
+	}
+	if got := limited.Data["body"]; got != "[string omitted, ~3.0 MiB]" {
+		t.Errorf("Unexpected placeholder: %v", got)
+	}
+	if limited.Data["id"] != 42 || len(entry.Data["body"].(string)) != 3<<20 {
+		t.Error("limitEntry modified the wrong fields or the original entry")
+	}
+
+	if limitEntry(&log.Entry{Message: "small"}, 1<<20).Message != "small" {
+		t.Error("Small entry was modified")
+	}
+}
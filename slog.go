@@ -0,0 +1,121 @@
+package telegramhook
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+)
+
+// SlogHandler is a slog.Handler that emits records via a TelegramHook.
+type SlogHandler struct {
+	hook   *TelegramHook
+	attrs  []groupedAttr
+	groups []string
+}
+
+// groupedAttr is an attribute added by WithAttrs with the prefix of the
+// groups opened before.
+type groupedAttr struct {
+	prefix string
+	attr   slog.Attr
+}
+
+// NewSlogHandler returns a slog.Handler that sends records via hook.
+func NewSlogHandler(hook *TelegramHook) *SlogHandler {
+	return &SlogHandler{hook: hook}
+}
+
+// Enabled reports whether the hook currently sends records of level.
+func (s *SlogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	cfg := s.hook.snapshot()
+	enabled, _ := cfg.enabled(slogLevel(level).logrus())
+	return enabled
+}
+
+// Handle converts r into a Record and fires it.
+func (s *SlogHandler) Handle(ctx context.Context, r slog.Record) error {
+	fields := make(map[string]interface{}, len(s.attrs)+r.NumAttrs())
+	for _, ga := range s.attrs {
+		addAttr(fields, ga.prefix, ga.attr)
+	}
+	prefix := groupPrefix(s.groups)
+	r.Attrs(func(a slog.Attr) bool {
+		addAttr(fields, prefix, a)
+		return true
+	})
+
+	var caller *runtime.Frame
+	if r.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		caller = &frame
+	}
+
+	return s.hook.FireRecord(Record{
+		Time:    r.Time,
+		Level:   slogLevel(r.Level),
+		Message: r.Message,
+		Fields:  fields,
+		Caller:  caller,
+		Context: ctx,
+	})
+}
+
+// WithAttrs returns a handler that adds attrs to every record.
+func (s *SlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	prefix := groupPrefix(s.groups)
+	next := *s
+	next.attrs = append([]groupedAttr(nil), s.attrs...)
+	for _, a := range attrs {
+		next.attrs = append(next.attrs, groupedAttr{prefix: prefix, attr: a})
+	}
+	return &next
+}
+
+// WithGroup returns a handler that qualifies attributes with name.
+func (s *SlogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return s
+	}
+	next := *s
+	next.groups = append(append([]string(nil), s.groups...), name)
+	return &next
+}
+
+// slogLevel maps slog levels onto hook levels.
+func slogLevel(level slog.Level) Level {
+	switch {
+	case level >= slog.LevelError:
+		return LevelError
+	case level >= slog.LevelWarn:
+		return LevelWarn
+	case level >= slog.LevelInfo:
+		return LevelInfo
+	}
+	return LevelDebug
+}
+
+// addAttr flattens a into fields, qualifying keys of groups with dots. The
+// attributes of a group without a key are inlined.
+func addAttr(fields map[string]interface{}, prefix string, a slog.Attr) {
+	v := a.Value.Resolve()
+	if v.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		for _, ga := range v.Group() {
+			addAttr(fields, prefix, ga)
+		}
+		return
+	}
+	if a.Key != "" {
+		fields[prefix+a.Key] = v.Any()
+	}
+}
+
+func groupPrefix(groups []string) string {
+	prefix := ""
+	for _, g := range groups {
+		prefix += g + "."
+	}
+	return prefix
+}
@@ -0,0 +1,68 @@
+package telegramhook
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+	"testing/slogtest"
+
+	log "github.com/andoma-go/logrus"
+)
+
+func TestSlogHandler(t *testing.T) {
+	var texts []string
+	h := newTestHook()
+	h.client = &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		var req apiRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		texts = append(texts, req.Text)
+		return jsonResponse(200, `{"ok":true,"result":{}}`), nil
+	})}
+
+	logger := slog.New(NewSlogHandler(h)).WithGroup("req")
+	logger.Info("ignored")
+	logger.Error("request failed", "id", 7)
+
+	if len(texts) != 1 || !strings.HasPrefix(texts[0], "<b>ERROR</b>@testing - request failed\n<pre>\n\treq.id: 7") {
+		t.Errorf("Unexpected messages: %q", texts)
+	}
+}
+
+func TestSlogHandlerConformance(t *testing.T) {
+	var entries []*log.Entry
+	h := newTestHook(WithLevel(log.InfoLevel), WithFormatter(FormatterFunc(func(entry *log.Entry) (string, error) {
+		entries = append(entries, entry)
+		return entry.Message, nil
+	})))
+	h.client = (&fakeAPI{}).client()
+
+	results := func() []map[string]any {
+		var ms []map[string]any
+		for _, entry := range entries {
+			m := map[string]any{slog.LevelKey: entry.Level, slog.MessageKey: entry.Message}
+			if !entry.Time.IsZero() {
+				m[slog.TimeKey] = entry.Time
+			}
+			// Nest the dotted keys of groups again.
+			for k, v := range entry.Data {
+				group, keys := m, strings.Split(k, ".")
+				for _, g := range keys[:len(keys)-1] {
+					sub, ok := group[g].(map[string]any)
+					if !ok {
+						sub = map[string]any{}
+						group[g] = sub
+					}
+					group = sub
+				}
+				group[keys[len(keys)-1]] = v
+			}
+			ms = append(ms, m)
+		}
+		return ms
+	}
+	if err := slogtest.TestHandler(NewSlogHandler(h), results); err != nil {
+		t.Error(err)
+	}
+}
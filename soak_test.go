@@ -0,0 +1,136 @@
+//go:build soak
+
+package telegramhook
+
+import (
+	"context"
+	"flag"
+	"io"
+	"net/http"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	log "github.com/andoma-go/logrus"
+)
+
+var (
+	soakEntries   = flag.Int("soak.entries", 1000000, "entries logged per profile")
+	soakLoggers   = flag.Int("soak.loggers", 8, "goroutines logging concurrently")
+	soakLatency   = flag.Duration("soak.latency", time.Millisecond, "latency of the mock Telegram API")
+	soakFlushWait = flag.Duration("soak.flush", 5*time.Minute, "how long to wait for the queue to drain")
+)
+
+// soakAPI is a mock Telegram API that only counts what it receives, so
+// millions of requests do not pile up in memory.
+type soakAPI struct {
+	latency  time.Duration
+	requests atomic.Int64
+	entries  atomic.Int64
+}
+
+func (a *soakAPI) client() *http.Client {
+	return &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		var body []byte
+		if r.Body != nil {
+			body, _ = io.ReadAll(r.Body)
+		}
+		time.Sleep(a.latency)
+		a.requests.Add(1)
+		a.entries.Add(int64(strings.Count(string(body), "@soak - entry")))
+		return jsonResponse(http.StatusOK, `{"ok":true,"result":{"message_id":1}}`), nil
+	})}
+}
+
+// TestSoak logs a large number of entries through every volume profile against
+// the mock API as fast as possible and checks that no message failed, that
+// every entry was delivered unless the queue had to shed messages, and that no
+// goroutines are left behind.
+func TestSoak(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		profile VolumeProfile
+	}{
+		{"LowVolume", LowVolume},
+		{"Standard", Standard},
+		{"HighVolume", HighVolume},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			goroutines := runtime.NumGoroutine()
+
+			api := &soakAPI{latency: *soakLatency}
+			h, err := NewTelegramHookWithClient("soak", "token", "-100123", "", api.client(),
+				WithVolumeProfile(tc.profile))
+			if err != nil {
+				t.Fatal(err)
+			}
+			api.requests.Store(0)
+
+			logger := log.New()
+			logger.Out = io.Discard
+			logger.AddHook(h)
+
+			entries := *soakEntries
+			if tc.profile == LowVolume && entries > 10000 {
+				// Synchronous delivery is bound by the API latency.
+				entries = 10000
+			}
+
+			var mem runtime.MemStats
+			runtime.ReadMemStats(&mem)
+			allocs := mem.Mallocs
+
+			start := time.Now()
+			var wg sync.WaitGroup
+			loggers := *soakLoggers
+			for l := 0; l < loggers; l++ {
+				wg.Add(1)
+				go func(l int) {
+					defer wg.Done()
+					for i := l; i < entries; i += loggers {
+						logger.WithField("i", i).Errorf("entry %d", i)
+					}
+				}(l)
+			}
+			wg.Wait()
+			logged := time.Since(start)
+
+			ctx, cancel := context.WithTimeout(context.Background(), *soakFlushWait)
+			defer cancel()
+			if err := h.Flush(ctx); err != nil {
+				t.Fatal(err)
+			}
+			elapsed := time.Since(start)
+
+			runtime.ReadMemStats(&mem)
+			stats := h.Stats()
+			t.Logf("%d entries logged in %v (%.0f/s), delivered in %v with %d requests",
+				entries, logged, float64(entries)/logged.Seconds(), elapsed, api.requests.Load())
+			t.Logf("delivered %d entries, dropped %d messages, failed %d, %.1f allocations per entry",
+				api.entries.Load(), stats.Dropped, stats.Failed, float64(mem.Mallocs-allocs)/float64(entries))
+
+			if stats.Failed != 0 {
+				t.Errorf("%d messages failed", stats.Failed)
+			}
+			// Shed messages may be batches of many entries, so only a run
+			// without shedding can be checked entry by entry.
+			if delivered := api.entries.Load(); stats.Dropped == 0 && delivered != int64(entries) {
+				t.Errorf("Delivered %d entries without shedding any, want %d", delivered, entries)
+			}
+
+			if err := h.Close(); err != nil {
+				t.Fatal(err)
+			}
+			deadline := time.Now().Add(5 * time.Second)
+			for runtime.NumGoroutine() > goroutines && time.Now().Before(deadline) {
+				time.Sleep(10 * time.Millisecond)
+			}
+			if n := runtime.NumGoroutine(); n > goroutines {
+				t.Errorf("%d goroutines left behind", n-goroutines)
+			}
+		})
+	}
+}
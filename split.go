@@ -0,0 +1,151 @@
+package telegramhook
+
+import (
+	"fmt"
+	"html"
+	"strings"
+	"unicode/utf8"
+)
+
+// htmlToken is a single unit of an HTML-formatted message: a tag, an entity or a rune.
+type htmlToken struct {
+	text    string
+	size    int    // length in UTF-16 code units as counted by Telegram, 0 for tags
+	tag     string // tag name for tags, empty otherwise
+	closing bool
+}
+
+// tokenizeHTML breaks an HTML-formatted message into tokens that must not be split.
+func tokenizeHTML(s string) []htmlToken {
+	tokens := make([]htmlToken, 0, len(s))
+	for i := 0; i < len(s); {
+		switch s[i] {
+		case '<':
+			if end := strings.IndexByte(s[i:], '>'); end >= 0 {
+				text := s[i : i+end+1]
+				name := strings.TrimPrefix(text[1:len(text)-1], "/")
+				if j := strings.IndexAny(name, " \t\n"); j >= 0 {
+					name = name[:j]
+				}
+				tokens = append(tokens, htmlToken{text: text, tag: name, closing: strings.HasPrefix(text, "</")})
+				i += end + 1
+				continue
+			}
+		case '&':
+			if end := strings.IndexByte(s[i:], ';'); end >= 0 {
+				text := s[i : i+end+1]
+				tokens = append(tokens, htmlToken{text: text, size: textLen(html.UnescapeString(text))})
+				i += end + 1
+				continue
+			}
+		}
+
+		r, size := utf8.DecodeRuneInString(s[i:])
+		tokens = append(tokens, htmlToken{text: s[i : i+size], size: runeLen(r)})
+		i += size
+	}
+	return tokens
+}
+
+// splitHTML splits an HTML-formatted message into parts of at most limit UTF-16
+// code units. Tags that are open at a boundary are closed at the end of one part
+// and reopened at the start of the next, so every part is valid Telegram HTML.
+// Parts are preferably cut at line breaks, then at spaces.
+func splitHTML(s string, limit int) []string {
+	if htmlTextLen(s) <= limit {
+		return []string{s}
+	}
+
+	tokens := tokenizeHTML(s)
+	parts := make([]string, 0, 2)
+
+	var open []htmlToken
+	for i := 0; i < len(tokens); {
+		stack := append([]htmlToken(nil), open...)
+		n := 0
+
+		cut, cutN := -1, 0
+		var cutStack []htmlToken
+		spaceCut, spaceN := -1, 0
+		var spaceStack []htmlToken
+
+		j := i
+		for ; j < len(tokens); j++ {
+			t := tokens[j]
+			if n+t.size > limit && j > i {
+				break
+			}
+			n += t.size
+			stack = applyTag(stack, t)
+
+			switch t.text {
+			case "\n":
+				cut, cutN, cutStack = j+1, n, append([]htmlToken(nil), stack...)
+			case " ":
+				spaceCut, spaceN, spaceStack = j+1, n, append([]htmlToken(nil), stack...)
+			}
+		}
+
+		end, endStack := j, stack
+		if j < len(tokens) {
+			switch {
+			case cut > i && cutN > limit/2:
+				end, endStack = cut, cutStack
+			case spaceCut > i && spaceN > limit/2:
+				end, endStack = spaceCut, spaceStack
+			}
+		}
+
+		var b strings.Builder
+		for _, t := range open {
+			b.WriteString(t.text)
+		}
+		for _, t := range tokens[i:end] {
+			b.WriteString(t.text)
+		}
+		for k := len(endStack) - 1; k >= 0; k-- {
+			b.WriteString("</" + endStack[k].tag + ">")
+		}
+
+		parts = append(parts, b.String())
+		open, i = endStack, end
+	}
+
+	return parts
+}
+
+// partIndicatorLength is the room reserved for a part indicator such as
+// "\n(12/34)".
+const partIndicatorLength = 16
+
+// splitMessage splits an HTML-formatted message into parts that fit into a
+// Telegram message. When more than one part is needed, every part ends with
+// an indicator such as "(2/3)".
+func splitMessage(s string) []string {
+	parts := splitHTML(s, maxMessageLength)
+	if len(parts) == 1 {
+		return parts
+	}
+
+	parts = splitHTML(s, maxMessageLength-partIndicatorLength)
+	for i := range parts {
+		parts[i] += fmt.Sprintf("\n<i>(%d/%d)</i>", i+1, len(parts))
+	}
+	return parts
+}
+
+// applyTag updates the stack of open tags with t.
+func applyTag(stack []htmlToken, t htmlToken) []htmlToken {
+	if t.tag == "" {
+		return stack
+	}
+	if !t.closing {
+		return append(stack, t)
+	}
+	for k := len(stack) - 1; k >= 0; k-- {
+		if stack[k].tag == t.tag {
+			return append(stack[:k], stack[k+1:]...)
+		}
+	}
+	return stack
+}
@@ -0,0 +1,66 @@
+package telegramhook
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestSplitHTMLShort(t *testing.T) {
+	parts := splitHTML("<b>ERROR</b>@app - short", maxMessageLength)
+	if len(parts) != 1 {
+		t.Fatalf("Short message was split into %d parts", len(parts))
+	}
+}
+
+func TestSplitHTMLReopensTags(t *testing.T) {
+	msg := "<b>ERROR</b>@app - x\n<pre>" + strings.Repeat("line &amp; more\n", 10) + "</pre>"
+
+	parts := splitHTML(msg, 40)
+	if len(parts) < 2 {
+		t.Fatalf("Long message was not split: %q", parts)
+	}
+
+	for i, p := range parts {
+		if htmlTextLen(p) > 40 {
+			t.Errorf("Part %d exceeds limit: %d", i, htmlTextLen(p))
+		}
+		if strings.Count(p, "<pre>") != strings.Count(p, "</pre>") {
+			t.Errorf("Part %d has unbalanced tags: %q", i, p)
+		}
+		if strings.Contains(p, "&amp") && !strings.Contains(p, "&amp;") {
+			t.Errorf("Part %d splits an entity: %q", i, p)
+		}
+	}
+
+	if i := len(parts) - 1; !strings.HasPrefix(parts[i], "<pre>") {
+		t.Errorf("Last part does not reopen <pre>: %q", parts[i])
+	}
+}
+
+func TestSplitHTMLEmoji(t *testing.T) {
+	parts := splitHTML(strings.Repeat("🔥", 5), 4)
+	if len(parts) != 3 || parts[0] != "🔥🔥" {
+		t.Errorf("Unexpected split of surrogate pairs: %q", parts)
+	}
+}
+
+func TestSplitMessageIndicators(t *testing.T) {
+	if parts := splitMessage("<b>ERROR</b>@app - short"); len(parts) != 1 || strings.Contains(parts[0], "(1/1)") {
+		t.Errorf("Unexpected parts for a short message: %q", parts)
+	}
+
+	msg := "<b>ERROR</b>@app - trace\n<pre>" + strings.Repeat("goroutine 1 [running]:\n", 400) + "</pre>"
+	parts := splitMessage(msg)
+	if len(parts) != 3 {
+		t.Fatalf("Expected 3 parts, got %d", len(parts))
+	}
+	for i, p := range parts {
+		if htmlTextLen(p) > maxMessageLength {
+			t.Errorf("Part %d exceeds the limit: %d", i, htmlTextLen(p))
+		}
+		if want := fmt.Sprintf("</pre>\n<i>(%d/3)</i>", i+1); !strings.HasSuffix(p, want) {
+			t.Errorf("Part %d does not end with %q: %q", i, want, p[len(p)-30:])
+		}
+	}
+}
@@ -0,0 +1,320 @@
+package telegramhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultSpoolInterval is how often spooled messages are retried.
+const defaultSpoolInterval = 10 * time.Second
+
+// spooledMessage is a message that could not be delivered, stored as a JSON
+// file in the spool directory.
+type spooledMessage struct {
+	Message OutboxMessage `json:"message"`
+	// Sent is the number of parts of the text already delivered, which are
+	// not sent again.
+	Sent   int       `json:"sent,omitempty"`
+	Logged time.Time `json:"logged"`
+}
+
+// spoolState counts the messages in the spool directory, so new messages
+// queue up behind them instead of overtaking them.
+type spoolState struct {
+	mu     sync.Mutex
+	files  int
+	seq    int
+	paused bool
+	replay sync.Mutex // held while the spool is replayed
+}
+
+// SpoolStats describes the messages waiting in the spool directory.
+type SpoolStats struct {
+	// Messages and Bytes are the number and total size of spooled messages.
+	Messages int
+	Bytes    int64
+	// Oldest is when the oldest message was spooled, zero when the spool is
+	// empty.
+	Oldest time.Time
+	// Paused reports whether replay is paused, see PauseReplay.
+	Paused bool
+}
+
+// backlog reports whether messages are waiting in the spool.
+func (s *spoolState) backlog() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.files > 0
+}
+
+// spoolMessage stores out in the spool directory to be sent once Telegram is
+// reachable again. Files are named by the time they were spooled, so sorting
+// them restores the order of the messages.
+func (h *TelegramHook) spoolMessage(cfg config, out outgoing) error {
+	h.spool.mu.Lock()
+	defer h.spool.mu.Unlock()
+
+	h.spool.seq++
+	name := filepath.Join(cfg.spoolDir, fmt.Sprintf("%020d-%06d.json", time.Now().UnixNano(), h.spool.seq))
+	if err := writeSpooled(name, cfg, out); err != nil {
+		return err
+	}
+	h.spool.files++
+	return nil
+}
+
+// writeSpooled writes out delivered with cfg to the spool file name, replacing
+// it atomically if it exists.
+func writeSpooled(name string, cfg config, out outgoing) error {
+	m := spooledMessage{Message: outboxMessage(cfg, out), Sent: out.sent, Logged: out.logged}
+	if m.Logged.IsZero() && out.entry != nil {
+		m.Logged = out.entry.Time
+	}
+	if m.Logged.IsZero() {
+		m.Logged = time.Now()
+	}
+
+	b, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(name+".tmp", b, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(name+".tmp", name)
+}
+
+// setAside renames a spooled message that cannot be delivered to *.bad, so it
+// does not block the spool forever.
+func (h *TelegramHook) setAside(name string) {
+	_ = os.Rename(name, strings.TrimSuffix(name, ".json")+".bad")
+	h.removedSpooled()
+}
+
+// spooledFiles returns the messages in the spool directory, oldest first.
+func spooledFiles(dir string) ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// SpoolStats returns the number, size and age of the spooled messages, e.g. to
+// decide whether to drop a large backlog after an extended outage.
+func (h *TelegramHook) SpoolStats() (SpoolStats, error) {
+	h.spool.mu.Lock()
+	st := SpoolStats{Paused: h.spool.paused}
+	h.spool.mu.Unlock()
+
+	dir := h.SpoolDir()
+	if dir == "" {
+		return st, nil
+	}
+	files, err := spooledFiles(dir)
+	if err != nil {
+		return st, err
+	}
+	for _, name := range files {
+		info, err := os.Stat(name)
+		if err != nil {
+			continue // delivered or dropped meanwhile
+		}
+		st.Messages++
+		st.Bytes += info.Size()
+		if st.Oldest.IsZero() || info.ModTime().Before(st.Oldest) {
+			st.Oldest = info.ModTime()
+		}
+	}
+	return st, nil
+}
+
+// PauseReplay stops sending spooled messages until ResumeReplay is called. New
+// messages keep queuing up behind the spooled ones, so nothing overtakes them.
+func (h *TelegramHook) PauseReplay() {
+	h.spool.mu.Lock()
+	defer h.spool.mu.Unlock()
+	h.spool.paused = true
+}
+
+// ResumeReplay sends the spooled messages again, starting with the next retry.
+func (h *TelegramHook) ResumeReplay() {
+	h.spool.mu.Lock()
+	defer h.spool.mu.Unlock()
+	h.spool.paused = false
+}
+
+// replayPaused reports whether replay is paused.
+func (s *spoolState) replayPaused() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.paused
+}
+
+// DropSpool deletes the messages spooled more than olderThan ago, all of them
+// when olderThan is not positive, and returns how many were deleted. Stale
+// alerts of a long outage can be dropped this way instead of flooding the chat
+// once Telegram is reachable again.
+func (h *TelegramHook) DropSpool(olderThan time.Duration) (int, error) {
+	dir := h.SpoolDir()
+	if dir == "" {
+		return 0, nil
+	}
+	files, err := spooledFiles(dir)
+	if err != nil {
+		return 0, err
+	}
+
+	dropped := 0
+	for _, name := range files {
+		if olderThan > 0 {
+			info, err := os.Stat(name)
+			if err != nil || time.Since(info.ModTime()) <= olderThan {
+				continue
+			}
+		}
+		if err := os.Remove(name); err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue // delivered meanwhile
+			}
+			return dropped, err
+		}
+		dropped++
+		h.removedSpooled()
+	}
+	return dropped, nil
+}
+
+// replaySpool sends the spooled messages in order, noting when they were
+// logged, and removes them once delivered. It stops at the first transient
+// failure, so the remaining messages keep their order, and when replay is
+// paused. Messages Telegram rejects are set aside. With a replay rate it waits
+// between messages.
+func (h *TelegramHook) replaySpool(cfg config) {
+	h.spool.replay.Lock()
+	defer h.spool.replay.Unlock()
+
+	files, err := spooledFiles(cfg.spoolDir)
+	if err != nil {
+		h.handleError(fmt.Errorf("spool: %w", err))
+		return
+	}
+
+	for i, name := range files {
+		if h.spool.replayPaused() {
+			return
+		}
+		if i > 0 && cfg.replayRate > 0 {
+			select {
+			case <-h.done:
+				return
+			case <-time.After(time.Minute / time.Duration(cfg.replayRate)):
+			}
+		}
+
+		var m spooledMessage
+		b, err := os.ReadFile(name)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue // dropped meanwhile
+		}
+		if err == nil {
+			err = json.Unmarshal(b, &m)
+		}
+		if err != nil {
+			h.handleError(fmt.Errorf("spool: %s: %w", name, err))
+			h.setAside(name)
+			continue
+		}
+
+		mcfg, out := m.Message.restore(cfg)
+		out.spoolFile, out.sent, out.logged = name, m.Sent, m.Logged
+		if m.Sent == 0 {
+			// Parts already sent keep the text they were split from.
+			out.msg += spoolFooter(cfg, m.Logged)
+		}
+
+		if err := h.deliver(context.Background(), mcfg, out); err != nil {
+			if transient(err) {
+				h.handleError(err)
+				return
+			}
+			h.handleError(fmt.Errorf("spool: %s: %w", name, err))
+			h.setAside(name)
+			continue
+		}
+		if err := os.Remove(name); err != nil {
+			h.handleError(fmt.Errorf("spool: %w", err))
+			return
+		}
+		h.removedSpooled()
+	}
+}
+
+// removedSpooled accounts for a message that left the spool.
+func (h *TelegramHook) removedSpooled() {
+	h.spool.mu.Lock()
+	defer h.spool.mu.Unlock()
+	if h.spool.files > 0 {
+		h.spool.files--
+	}
+}
+
+// spoolFooter notes when a spooled message was logged and how late it is.
+func spoolFooter(cfg config, logged time.Time) string {
+	return fmt.Sprintf("\n<i>logged %s, delayed by %s</i>",
+		cfg.inZone(logged).Format("2006-01-02 15:04:05 MST"), humanizeDuration(time.Since(logged).Round(time.Second)))
+}
+
+// openSpool creates the spool directory and counts the messages left from a
+// previous run.
+func (h *TelegramHook) openSpool() error {
+	dir := h.SpoolDir()
+	if dir == "" {
+		return nil
+	}
+
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return &ConfigError{Field: "spoolDir", Reason: fmt.Sprintf("is not usable: %v", err)}
+	}
+	files, err := spooledFiles(dir)
+	if err != nil {
+		return &ConfigError{Field: "spoolDir", Reason: fmt.Sprintf("is not usable: %v", err)}
+	}
+
+	h.spool.mu.Lock()
+	defer h.spool.mu.Unlock()
+	h.spool.files = len(files)
+	return nil
+}
+
+// startSpool replays the spooled messages periodically.
+func (h *TelegramHook) startSpool() {
+	if h.SpoolDir() == "" {
+		return
+	}
+
+	ticker := time.NewTicker(defaultSpoolInterval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-h.done:
+				return
+			case <-ticker.C:
+				if h.spool.backlog() && !h.spool.replayPaused() {
+					h.replaySpool(h.snapshot())
+				}
+			}
+		}
+	}()
+}
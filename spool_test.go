@@ -0,0 +1,179 @@
+package telegramhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	log "github.com/andoma-go/logrus"
+)
+
+func TestSpool(t *testing.T) {
+	var down atomic.Bool
+	down.Store(true)
+	api := &fakeAPI{respond: func(method string, body []byte) *http.Response {
+		if down.Load() {
+			return jsonResponse(http.StatusBadGateway, `{"ok":false,"error_code":502,"description":"Bad Gateway"}`)
+		}
+		return nil
+	}}
+	dir := t.TempDir()
+	h := newTestHook(WithSpoolDir(dir))
+	h.chatId = "42"
+	h.client = api.client()
+	if err := h.openSpool(); err != nil {
+		t.Fatal(err)
+	}
+
+	logged := time.Now().Add(-5 * time.Minute)
+	for _, msg := range []string{"first", "second"} {
+		if err := h.Fire(&log.Entry{Level: log.ErrorLevel, Message: msg, Time: logged}); err != nil {
+			t.Fatalf("Expected a spooled message not to fail, got %v", err)
+		}
+	}
+	if n := len(api.methods()); n != 1 {
+		t.Errorf("Expected the second message to queue up behind the spooled one, got %d calls", n)
+	}
+	if files, _ := spooledFiles(dir); len(files) != 2 {
+		t.Fatalf("Expected 2 spooled messages, got %v", files)
+	}
+
+	down.Store(false)
+	h.replaySpool(h.snapshot())
+
+	texts := api.texts()[1:]
+	if len(texts) != 2 || !strings.HasPrefix(texts[0], "<b>ERROR</b>@testing - first") || !strings.HasPrefix(texts[1], "<b>ERROR</b>@testing - second") {
+		t.Fatalf("Expected the spooled messages in order, got %q", texts)
+	}
+	if !strings.Contains(texts[0], "logged "+logged.Format("2006-01-02 15:04:05")) || !strings.Contains(texts[0], "delayed by 5m0s") {
+		t.Errorf("Expected the original time to be noted, got %q", texts[0])
+	}
+	if files, _ := spooledFiles(dir); len(files) != 0 || h.spool.backlog() {
+		t.Errorf("Expected the spool to be empty, got %v", files)
+	}
+}
+
+func TestSpoolCorruptFile(t *testing.T) {
+	api := &fakeAPI{}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "1.json"), []byte("{"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	h := newTestHook(WithSpoolDir(dir))
+	h.client = api.client()
+	if err := h.openSpool(); err != nil {
+		t.Fatal(err)
+	}
+
+	h.replaySpool(h.snapshot())
+
+	if files, _ := filepath.Glob(filepath.Join(dir, "*")); !reflect.DeepEqual(files, []string{filepath.Join(dir, "1.bad")}) {
+		t.Errorf("Expected the corrupt file to be set aside, got %v", files)
+	}
+	if h.spool.backlog() {
+		t.Error("Expected no backlog")
+	}
+}
+
+func TestSpoolControls(t *testing.T) {
+	api := &fakeAPI{}
+	dir := t.TempDir()
+	h := newTestHook(WithSpoolDir(dir))
+	h.client = api.client()
+
+	for i, age := range []time.Duration{3 * time.Hour, 2 * time.Hour, 0} {
+		if err := h.spoolMessage(h.snapshot(), outgoing{msg: fmt.Sprint(i)}); err != nil {
+			t.Fatal(err)
+		}
+		files, _ := spooledFiles(dir)
+		spooled := time.Now().Add(-age)
+		if err := os.Chtimes(files[len(files)-1], spooled, spooled); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	st, err := h.SpoolStats()
+	if err != nil || st.Messages != 3 || st.Bytes == 0 || time.Since(st.Oldest) < 3*time.Hour {
+		t.Errorf("Unexpected spool stats %+v (%v)", st, err)
+	}
+
+	h.PauseReplay()
+	h.replaySpool(h.snapshot())
+	if calls := api.methods(); len(calls) != 0 {
+		t.Errorf("Expected no messages while paused, got %v", calls)
+	}
+
+	if n, err := h.DropSpool(time.Hour); n != 2 || err != nil {
+		t.Errorf("Expected 2 stale messages to be dropped, got %d (%v)", n, err)
+	}
+
+	h.ResumeReplay()
+	h.replaySpool(h.snapshot())
+	if texts := api.texts(); len(texts) != 1 || !strings.HasPrefix(texts[0], "2\n") {
+		t.Errorf("Expected only the recent message to be sent, got %q", texts)
+	}
+	if st, _ := h.SpoolStats(); st.Messages != 0 || h.spool.backlog() {
+		t.Errorf("Expected an empty spool, got %+v", st)
+	}
+}
+
+func TestSpoolFailures(t *testing.T) {
+	var calls atomic.Int32
+	var down, rejected atomic.Bool
+	api := &fakeAPI{respond: func(method string, body []byte) *http.Response {
+		switch {
+		case rejected.Load():
+			return jsonResponse(http.StatusBadRequest, `{"ok":false,"error_code":400,"description":"Bad Request: chat not found"}`)
+		case down.Load() && calls.Add(1) > 1:
+			return jsonResponse(http.StatusBadGateway, `{"ok":false,"error_code":502,"description":"Bad Gateway"}`)
+		}
+		return nil
+	}}
+	dir := t.TempDir()
+	h := newTestHook(WithSpoolDir(dir))
+	h.client = api.client()
+
+	// Rejected messages are not spooled.
+	rejected.Store(true)
+	if err := h.Fire(&log.Entry{Level: log.ErrorLevel, Message: "rejected"}); err == nil {
+		t.Error("Expected the rejection to be returned")
+	}
+	if files, _ := spooledFiles(dir); len(files) != 0 {
+		t.Errorf("Expected a rejected message not to be spooled, got %v", files)
+	}
+	rejected.Store(false)
+
+	// Only the parts not sent yet are replayed.
+	down.Store(true)
+	if err := h.Fire(&log.Entry{Level: log.ErrorLevel, Message: strings.Repeat("long line\n", 600)}); err != nil {
+		t.Fatal(err)
+	}
+	down.Store(false)
+	h.replaySpool(h.snapshot())
+	texts := api.texts()[1:]
+	if len(texts) != 3 || texts[1] != texts[2] {
+		t.Errorf("Expected the failed part to be sent once more, got %d messages", len(texts))
+	}
+
+	// Spooled messages keep their topic and are set aside once rejected.
+	if err := h.spoolMessage(h.snapshot(), outgoing{msg: "m", topic: "db"}); err != nil {
+		t.Fatal(err)
+	}
+	files, _ := spooledFiles(dir)
+	var m spooledMessage
+	if b, err := os.ReadFile(files[0]); err != nil || json.Unmarshal(b, &m) != nil || m.Message.Topic != "db" {
+		t.Errorf("Expected the topic to be spooled, got %+v (%v)", m.Message, err)
+	}
+	rejected.Store(true)
+	h.replaySpool(h.snapshot())
+	if bad, _ := filepath.Glob(filepath.Join(dir, "*.bad")); len(bad) != 1 || h.spool.backlog() {
+		t.Errorf("Expected the rejected message to be set aside, got %v", bad)
+	}
+}
@@ -0,0 +1,128 @@
+package telegramhook
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andoma-go/logrus"
+)
+
+const (
+	// SQLKey is the field for a SQL query rendered as a code block.
+	SQLKey = "sql"
+	// SQLArgsKey is the field for the arguments bound to the placeholders of SQLKey.
+	SQLArgsKey = "args"
+)
+
+const (
+	// sqlValueLength limits the length of a single substituted argument.
+	sqlValueLength = 64
+	// sqlQueryLength limits the length of the rendered query.
+	sqlQueryLength = 2000
+)
+
+// renderSQLBlock renders the query field with its arguments substituted.
+func renderSQLBlock(_ *config, fields logrus.Fields) ([]fieldBlock, []string) {
+	query, ok := fields[SQLKey].(string)
+	if !ok {
+		return nil, nil
+	}
+
+	keys := []string{SQLKey}
+	var args []interface{}
+	if v, ok := fields[SQLArgsKey]; ok {
+		args = sqlArgs(v)
+		keys = append(keys, SQLArgsKey)
+	}
+
+	query = truncateText(bindSQL(query, args), sqlQueryLength)
+	return []fieldBlock{{title: "SQL", body: query, lang: "sql"}}, keys
+}
+
+// sqlArgs converts a slice of any element type into arguments.
+func sqlArgs(v interface{}) []interface{} {
+	if args, ok := v.([]interface{}); ok {
+		return args
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return []interface{}{v}
+	}
+
+	args := make([]interface{}, rv.Len())
+	for i := range args {
+		args[i] = rv.Index(i).Interface()
+	}
+	return args
+}
+
+// bindSQL substitutes "?" and "$N" placeholders outside of quoted strings
+// with args rendered as SQL literals. Placeholders without an argument are kept.
+func bindSQL(query string, args []interface{}) string {
+	if len(args) == 0 {
+		return query
+	}
+
+	var b strings.Builder
+	next := 0
+	var quote byte
+	for i := 0; i < len(query); i++ {
+		ch := query[i]
+
+		switch {
+		case quote != 0:
+			if ch == quote {
+				quote = 0
+			}
+		case ch == '\'' || ch == '"' || ch == '`':
+			quote = ch
+		case ch == '?':
+			if next < len(args) {
+				b.WriteString(sqlLiteral(args[next]))
+				next++
+				continue
+			}
+		case ch == '$':
+			j := i + 1
+			for j < len(query) && query[j] >= '0' && query[j] <= '9' {
+				j++
+			}
+			if n, err := strconv.Atoi(query[i+1 : j]); err == nil && n >= 1 && n <= len(args) {
+				b.WriteString(sqlLiteral(args[n-1]))
+				i = j - 1
+				continue
+			}
+		}
+
+		b.WriteByte(ch)
+	}
+
+	return b.String()
+}
+
+// sqlLiteral renders v as a SQL literal, truncating long values.
+func sqlLiteral(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "NULL"
+	case string:
+		return quoteSQL(v)
+	case []byte:
+		return fmt.Sprintf("'<%d bytes>'", len(v))
+	case time.Time:
+		return quoteSQL(v.Format(time.RFC3339Nano))
+	case bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return fmt.Sprint(v)
+	case fmt.Stringer:
+		return quoteSQL(v.String())
+	}
+	return quoteSQL(fmt.Sprintf("%v", v))
+}
+
+func quoteSQL(s string) string {
+	return "'" + strings.ReplaceAll(truncateText(s, sqlValueLength), "'", "''") + "'"
+}
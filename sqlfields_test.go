@@ -0,0 +1,38 @@
+package telegramhook
+
+import (
+	"strings"
+	"testing"
+
+	log "github.com/andoma-go/logrus"
+)
+
+func TestBindSQL(t *testing.T) {
+	tests := []struct {
+		query string
+		args  []interface{}
+		want  string
+	}{
+		{"SELECT * FROM t WHERE a = ? AND b = ?", []interface{}{1, "x'y"}, "SELECT * FROM t WHERE a = 1 AND b = 'x''y'"},
+		{"UPDATE t SET a = $2 WHERE id = $1 AND c = '$1?'", []interface{}{7, nil}, "UPDATE t SET a = NULL WHERE id = 7 AND c = '$1?'"},
+		{"SELECT ?, ?", []interface{}{true}, "SELECT true, ?"},
+	}
+	for _, tt := range tests {
+		if got := bindSQL(tt.query, tt.args); got != tt.want {
+			t.Errorf("bindSQL(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestSQLBlock(t *testing.T) {
+	msg := createMessage(newTestHook(), &log.Entry{
+		Level:   log.ErrorLevel,
+		Message: "query failed",
+		Data:    log.Fields{SQLKey: "SELECT * FROM users WHERE id < ?", SQLArgsKey: []int{5}},
+	})
+
+	want := "<b>SQL</b>\n<pre><code class=\"language-sql\">SELECT * FROM users WHERE id &lt; 5</code></pre>"
+	if !strings.HasSuffix(msg, want) || strings.Contains(msg, "args:") {
+		t.Errorf("Unexpected SQL block:\n%s", msg)
+	}
+}
@@ -0,0 +1,88 @@
+package telegramhook
+
+import (
+	"sync/atomic"
+
+	"github.com/andoma-go/logrus"
+)
+
+// Stats is a snapshot of the counters of a hook.
+type Stats struct {
+	// Fired is the number of entries passed to the hook by logrus.
+	Fired uint64
+	// Skipped counts entries per level that were below the hook's level.
+	Skipped map[logrus.Level]uint64
+	// Sent is the number of messages delivered to Telegram.
+	Sent uint64
+	// Failed is the number of messages that could not be delivered.
+	Failed uint64
+	// Dropped is the number of messages shed under queue memory pressure.
+	Dropped uint64
+	// Suppressed counts entries suppressed by the common noise filters by
+	// filter name, see WithCommonNoiseFilters.
+	Suppressed map[string]uint64
+	// LimitWarnings is the number of warnings that the send volume approached
+	// a Telegram limit, see WithLimitWarnings.
+	LimitWarnings uint64
+	// Attempts counts API requests by the number of attempts they took; the
+	// last bucket also holds requests that took more attempts.
+	Attempts map[int]uint64
+}
+
+// stats holds the live counters of a hook.
+type stats struct {
+	fired    atomic.Uint64
+	skipped  [logrus.TraceLevel + 1]atomic.Uint64
+	sent     atomic.Uint64
+	failed   atomic.Uint64
+	dropped  atomic.Uint64
+	warnings atomic.Uint64
+	attempts [maxTrackedAttempts]atomic.Uint64
+}
+
+// skip counts an entry below the hook's level.
+func (s *stats) skip(level logrus.Level) {
+	if level <= logrus.TraceLevel {
+		s.skipped[level].Add(1)
+	}
+}
+
+// attempt records a request that took n attempts.
+func (s *stats) attempt(n int) {
+	if n > maxTrackedAttempts {
+		n = maxTrackedAttempts
+	}
+	if n > 0 {
+		s.attempts[n-1].Add(1)
+	}
+}
+
+// Stats returns a snapshot of the hook's counters.
+func (h *TelegramHook) Stats() Stats {
+	st := Stats{
+		Fired:   h.stats.fired.Load(),
+		Skipped: map[logrus.Level]uint64{},
+		Sent:    h.stats.sent.Load(),
+		Failed:  h.stats.failed.Load(),
+		Dropped: h.stats.dropped.Load(),
+
+		LimitWarnings: h.stats.warnings.Load(),
+
+		Suppressed: h.noise.snapshot(),
+		Attempts:   map[int]uint64{},
+	}
+
+	for level := range h.stats.skipped {
+		if n := h.stats.skipped[level].Load(); n > 0 {
+			st.Skipped[logrus.Level(level)] = n
+		}
+	}
+
+	for i := range h.stats.attempts {
+		if n := h.stats.attempts[i].Load(); n > 0 {
+			st.Attempts[i+1] = n
+		}
+	}
+
+	return st
+}
@@ -0,0 +1,22 @@
+package telegramhook
+
+import (
+	"testing"
+
+	log "github.com/andoma-go/logrus"
+)
+
+func TestStatsSkipped(t *testing.T) {
+	h := newTestHook(WithLevel(log.WarnLevel))
+
+	for _, level := range []log.Level{log.InfoLevel, log.DebugLevel, log.DebugLevel} {
+		if err := h.Fire(&log.Entry{Level: level, Message: "m"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	st := h.Stats()
+	if st.Fired != 3 || st.Skipped[log.InfoLevel] != 1 || st.Skipped[log.DebugLevel] != 2 {
+		t.Errorf("Unexpected stats: %+v", st)
+	}
+}
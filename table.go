@@ -0,0 +1,64 @@
+package telegramhook
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/andoma-go/logrus"
+)
+
+// TableLayout configures rendering of fields as an aligned two-column table.
+// A width of zero leaves the column unbounded.
+type TableLayout struct {
+	KeyWidth   int
+	ValueWidth int
+}
+
+// renderTable renders the given fields as aligned "key  value" rows in the order
+// of keys. Keys wider than the layout allows are shortened, long values are
+// wrapped onto indented continuation lines.
+func renderTable(fields logrus.Fields, keys []string, layout TableLayout) []string {
+	width := 0
+	for _, k := range keys {
+		if n := utf8.RuneCountInString(k); n > width {
+			width = n
+		}
+	}
+
+	if layout.KeyWidth > 0 && width > layout.KeyWidth {
+		width = layout.KeyWidth
+	}
+	indent := strings.Repeat(" ", width+2)
+
+	rows := make([]string, 0, len(keys))
+	for _, k := range keys {
+		key := k
+		if utf8.RuneCountInString(key) > width {
+			key = string([]rune(key)[:width-1]) + "…"
+		}
+		key += strings.Repeat(" ", width-utf8.RuneCountInString(key))
+
+		lines := wrapLines(formatValue(fields[k]), layout.ValueWidth)
+		rows = append(rows, key+"  "+lines[0])
+		for _, line := range lines[1:] {
+			rows = append(rows, indent+line)
+		}
+	}
+
+	return rows
+}
+
+// wrapLines splits s into lines of at most width runes. A width of zero only
+// splits on existing line breaks.
+func wrapLines(s string, width int) []string {
+	var lines []string
+	for _, line := range strings.Split(s, "\n") {
+		r := []rune(line)
+		for width > 0 && len(r) > width {
+			lines = append(lines, string(r[:width]))
+			r = r[width:]
+		}
+		lines = append(lines, string(r))
+	}
+	return lines
+}
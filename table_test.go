@@ -0,0 +1,27 @@
+package telegramhook
+
+import (
+	"reflect"
+	"testing"
+
+	log "github.com/andoma-go/logrus"
+)
+
+func TestRenderTable(t *testing.T) {
+	rows := renderTable(log.Fields{
+		"id":            7,
+		"status":        "failed",
+		"very_long_key": "abcdefgh",
+	}, []string{"id", "status", "very_long_key"}, TableLayout{KeyWidth: 6, ValueWidth: 5})
+
+	want := []string{
+		"id      7",
+		"status  faile",
+		"        d",
+		"very_…  abcde",
+		"        fgh",
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("renderTable() = %q, want %q", rows, want)
+	}
+}
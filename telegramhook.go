@@ -1,15 +1,16 @@
 package telegramhook
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"fmt"
-	"html"
+	"io"
 	"net/http"
 	"net/url"
 	"os"
+	"regexp"
 	"strings"
 	"sync"
+	"text/template"
 	"time"
 
 	"github.com/andoma-go/logrus"
@@ -17,16 +18,148 @@ import (
 
 // TelegramHook to send logs via the Telegram API.
 type TelegramHook struct {
-	client    *http.Client
-	mu        sync.RWMutex
+	client *http.Client
+	mu     sync.RWMutex
+	config
+
+	firehose   firehoseBuffer
+	endpoints  endpointHealth
+	pending    pendingQueue
+	stats      stats
+	panelState livePanel
+	budget     budgetState
+	sent       sentMessages
+	topics     forumTopics
+	incidents  incidents
+	limiter    rateLimiter
+	acks       acknowledgements
+	batch      batchBuffer
+	dedup      dedupWindow
+	volume     sendVolume
+	flooding   floodGuard
+	bursts     bursts
+	recent     recentAlerts
+	noise      noiseCounts
+	events     eventStream
+	spool      spoolState
+
+	workersOnce sync.Once
+	fallbackMu  sync.Mutex // serializes writes to the fallback writer
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// config holds the settings of a hook. Messages are built from a copy taken
+// under a single lock, so every message reflects one coherent configuration
+// even while setters run concurrently. Setters replace reference values
+// instead of mutating them so copies stay unaffected.
+type config struct {
 	appName   string
 	authToken string
 	chatId    string
 	threadId  string
 	level     logrus.Level
 	async     bool
+
+	levelProvider LevelProvider
+
+	skipEmpty       bool
+	headlineFields  int
+	promoteErrorKey bool
+	table           *TableLayout
+	maxFields       int
+	fieldOrder      []string
+	hiddenFields    []string
+	fieldsDocument  bool
+	softFail        bool
+	errorHandler    ErrorHandler
+	fallbackWriter  io.Writer
+	fallbackHook    logrus.Hook
+	spoolDir        string
+	replayRate      int
+	customUA        string
+	anonymous       bool
+	firehoseUntil   time.Time
+	firehoseCommand bool
+
+	baseURLs         []string
+	failoverCooldown time.Duration
+	dnsRefresh       time.Duration
+	ipPreference     IPPreference
+	localAddr        string
+	maxQueueBytes    int
+	maxEntrySize     int
+	deliveryFooter   bool
+	panel            *LivePanel
+	profiles         map[string]RenderProfile
+	httpBodyLimit    int
+	errorBudget      *ErrorBudget
+	retryAttempts    int
+	retryDelay       time.Duration
+	jitter           Jitter
+	humanize         bool
+	relativeTimes    bool
+	caller           bool
+	levelLabels      map[logrus.Level]string
+	timeLayout       string
+	timeLocation     *time.Location
+	processInfo      bool
+	hostname         bool
+	staticFields     logrus.Fields
+	watchdogMaxAge   time.Duration
+	autoTopics       TopicMode
+	topicArchive     time.Duration
+	startupSpread    time.Duration
+	startup          bool
+	normalizers      []Normalizer
+	handlers         []signatureHandler
+	chats            []ChatTarget
+	levelRoutes      map[logrus.Level]ChatTarget
+	silentLevels     []logrus.Level
+	routeField       string
+	fieldRoutes      map[string]ChatTarget
+	outbox           Outbox
+	encryptionKey    []byte
+	redactKeys       []string
+	redactPatterns   []*regexp.Regexp
+	rateLimit        *RateLimit
+	sharedRateLimit  bool
+	ack              *Acknowledgement
+	queueSize        int
+	queuePolicy      QueuePolicy
+	workers          int
+	localeTag        string
+	timezone         *time.Location
+	exitFlush        time.Duration
+	batching         *AdaptiveBatching
+	batchInterval    time.Duration
+	alarmToken       string
+	alarmLevel       logrus.Level
+	formatter        Formatter
+	noiseFilters     bool
+	configEcho       bool
+	echoTarget       ChatTarget
+	silent           bool
+	templateErr      error
+	faults           *FaultInjection
+	parseMode        ParseMode
+	controlChars     ControlChars
+	rawHTML          bool
+	gate             *PressureGate
+	skipVerification bool
+	confirmDelay     time.Duration
+	dedupWindow      time.Duration
+	limitWarnings    *LimitWarnings
+	archive          Blob
+	flood            *FloodSummary
+	coalesce         *Coalescing
 }
 
+// Version of the hook, reported in the User-Agent header and error reports
+// unless disabled with WithUserAgentVersion(false).
+const Version = "0.1.0"
+
 // Option defines a method for additional configuration when instantiating TelegramHook
 type Option func(*TelegramHook)
 
@@ -53,269 +186,2471 @@ func WithLevel(level logrus.Level) Option {
 	}
 }
 
-// New creates a new instance of a hook targeting the Telegram API.
-func NewTelegramHook(appName, authToken, chatId, threadId string, options ...Option) (*TelegramHook, error) {
-	client := &http.Client{}
-	return NewTelegramHookWithClient(appName, authToken, chatId, threadId, client, options...)
+// WithLevelProvider takes the level from provider on every entry instead of the configured level
+func WithLevelProvider(provider LevelProvider) Option {
+	return func(h *TelegramHook) {
+		h.SetLevelProvider(provider)
+	}
 }
 
-// NewTelegramHookWithClient creates a new instance of a hook targeting the Telegram API with custom http.Client.
-func NewTelegramHookWithClient(appName, authToken, chatId, threadId string, client *http.Client, options ...Option) (*TelegramHook, error) {
-	h := TelegramHook{
-		client:    client,
-		appName:   appName,
-		authToken: authToken,
-		chatId:    chatId,
-		threadId:  threadId,
-		level:     logrus.ErrorLevel,
-		async:     false,
+// WithSkipEmpty drops entries that have neither a message nor fields
+func WithSkipEmpty(skip bool) Option {
+	return func(h *TelegramHook) {
+		h.SetSkipEmpty(skip)
 	}
+}
 
-	for _, opt := range options {
-		opt(&h)
+// WithHeadlineFields sets how many fields are used to build a headline for entries without a message
+func WithHeadlineFields(n int) Option {
+	return func(h *TelegramHook) {
+		h.SetHeadlineFields(n)
 	}
+}
 
-	// Verify the API token is valid and correct before continuing
-	if err := h.verifyToken(); err != nil {
-		return nil, err
+// WithErrorKeyPromotion moves the error field into the headline instead of the fields block
+func WithErrorKeyPromotion(promote bool) Option {
+	return func(h *TelegramHook) {
+		h.SetErrorKeyPromotion(promote)
 	}
+}
 
-	return &h, nil
+// WithFieldsTable renders fields as an aligned table with the given column widths
+func WithFieldsTable(layout TableLayout) Option {
+	return func(h *TelegramHook) {
+		h.SetFieldsTable(&layout)
+	}
 }
 
-// apiRequest encapsulates the request structure we are sending to the Telegram API.
-type apiRequest struct {
-	ChatId    string `json:"chat_id"`
-	ThreadId  string `json:"message_thread_id,omitempty"`
-	Text      string `json:"text"`
-	ParseMode string `json:"parse_mode,omitempty"`
+// WithMaxFields limits the number of rendered fields, the rest is folded into a summary line
+func WithMaxFields(n int) Option {
+	return func(h *TelegramHook) {
+		h.SetMaxFields(n)
+	}
 }
 
-// apiResponse encapsulates the response structure received from the Telegram API.
-type apiResponse struct {
-	Ok        bool         `json:"ok"`
-	ErrorCode *int         `json:"error_code,omitempty"`
-	Desc      *string      `json:"description,omitempty"`
-	Result    *interface{} `json:"result,omitempty"`
+// WithFieldOrder shows the given fields first, in that order, e.g. error and request_id
+func WithFieldOrder(keys []string) Option {
+	return func(h *TelegramHook) {
+		h.SetFieldOrder(keys)
+	}
 }
 
-// verifyToken issues a test request to the Telegram API to ensure the provided token is correct and valid.
-func (h *TelegramHook) verifyToken() error {
-	endpoint, _ := url.JoinPath(h.ApiEndpoint(), "getMe")
+// WithHiddenFields leaves the given fields out of messages
+func WithHiddenFields(keys []string) Option {
+	return func(h *TelegramHook) {
+		h.SetHiddenFields(keys)
+	}
+}
 
-	res, err := h.client.Get(endpoint)
-	if err != nil {
-		return err
+// WithFieldsDocument attaches the fields folded by WithMaxFields as a document
+func WithFieldsDocument(attach bool) Option {
+	return func(h *TelegramHook) {
+		h.SetFieldsDocument(attach)
 	}
-	defer res.Body.Close()
+}
 
-	apiRes := apiResponse{}
-	if err := json.NewDecoder(res.Body).Decode(&apiRes); err != nil {
-		return err
+// WithErrorHandler passes failures to handler instead of printing them to stderr
+func WithErrorHandler(handler ErrorHandler) Option {
+	return func(h *TelegramHook) {
+		h.SetErrorHandler(handler)
 	}
+}
 
-	if !apiRes.Ok {
-		// Received an error from the Telegram API
-		msg := "Received error response from Telegram API"
+// WithFallback writes messages that could not be delivered to w, e.g. a local file
+func WithFallback(w io.Writer) Option {
+	return func(h *TelegramHook) {
+		h.SetFallback(w)
+	}
+}
 
-		if apiRes.ErrorCode != nil {
-			msg = fmt.Sprintf("%s (error code %d)", msg, *apiRes.ErrorCode)
-		}
+// WithFallbackHook passes entries whose message could not be delivered to another hook
+func WithFallbackHook(hook logrus.Hook) Option {
+	return func(h *TelegramHook) {
+		h.SetFallbackHook(hook)
+	}
+}
 
-		if apiRes.Desc != nil {
-			msg = fmt.Sprintf("%s: %s", msg, *apiRes.Desc)
-		}
+// WithSpoolDir stores messages that could not be delivered in dir and sends them once Telegram is reachable again
+func WithSpoolDir(dir string) Option {
+	return func(h *TelegramHook) {
+		h.SetSpoolDir(dir)
+	}
+}
 
-		j, _ := json.MarshalIndent(apiRes, "", "\t")
-		return fmt.Errorf("%s\n%s", msg, j)
+// WithReplayRate sends at most perMinute spooled messages per minute once Telegram is reachable again
+func WithReplayRate(perMinute int) Option {
+	return func(h *TelegramHook) {
+		h.SetReplayRate(perMinute)
 	}
+}
 
-	return nil
+// WithSoftFail makes Fire always return nil, failures are only passed to the error handler
+func WithSoftFail(softFail bool) Option {
+	return func(h *TelegramHook) {
+		h.SetSoftFail(softFail)
+	}
 }
 
-// sendMessage issues the provided message to the Telegram API.
-func (h *TelegramHook) sendMessage(msg string) error {
-	apiReq := apiRequest{
-		ChatId:    h.ChatId(),
-		ThreadId:  h.ThreadId(),
-		Text:      msg,
-		ParseMode: "HTML",
+// WithUserAgent sets a custom User-Agent header for requests to the Telegram API
+func WithUserAgent(userAgent string) Option {
+	return func(h *TelegramHook) {
+		h.SetUserAgent(userAgent)
 	}
-	b, err := json.Marshal(apiReq)
-	if err != nil {
-		return err
+}
+
+// WithUserAgentVersion controls whether the hook identifies itself and its version in requests and error reports
+func WithUserAgentVersion(identify bool) Option {
+	return func(h *TelegramHook) {
+		h.SetUserAgentVersion(identify)
 	}
+}
 
-	endpoint, _ := url.JoinPath(h.ApiEndpoint(), "sendMessage")
+// WithApiEndpoint sets the API base URL, e.g. of a self-hosted Bot API server
+func WithApiEndpoint(baseURL string) Option {
+	return func(h *TelegramHook) {
+		h.SetApiEndpoint(baseURL)
+	}
+}
 
-	res, err := h.client.Post(endpoint, "application/json", bytes.NewReader(b))
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Encountered error when issuing request to Telegram API, %v", err)
-		return err
+// WithApiEndpoints sets several API base URLs that are tried in order when one is unreachable
+func WithApiEndpoints(baseURLs ...string) Option {
+	return func(h *TelegramHook) {
+		h.SetApiEndpoints(baseURLs...)
 	}
-	defer res.Body.Close()
+}
 
-	apiRes := apiResponse{}
-	if err := json.NewDecoder(res.Body).Decode(&apiRes); err != nil {
-		return err
+// WithFailoverCooldown sets how long a failed API endpoint is avoided
+func WithFailoverCooldown(cooldown time.Duration) Option {
+	return func(h *TelegramHook) {
+		h.SetFailoverCooldown(cooldown)
 	}
+}
 
-	if !apiRes.Ok {
-		// Received an error from the Telegram API
-		msg := "Received error response from Telegram API"
+// WithDNSCache caches resolved addresses of the API host for the refresh interval
+func WithDNSCache(refresh time.Duration) Option {
+	return func(h *TelegramHook) {
+		h.SetDNSCache(refresh)
+	}
+}
 
-		if apiRes.ErrorCode != nil {
-			msg = fmt.Sprintf("%s (error code %d)", msg, *apiRes.ErrorCode)
-		}
+// WithIPPreference sets which address family is tried first when connecting
+func WithIPPreference(preference IPPreference) Option {
+	return func(h *TelegramHook) {
+		h.SetIPPreference(preference)
+	}
+}
 
-		if apiRes.Desc != nil {
-			msg = fmt.Sprintf("%s: %s", msg, *apiRes.Desc)
-		}
+// WithLocalAddr binds outgoing connections to an IP address or network interface, e.g. "192.0.2.10" or "eth1"
+func WithLocalAddr(addr string) Option {
+	return func(h *TelegramHook) {
+		h.SetLocalAddr(addr)
+	}
+}
 
-		return fmt.Errorf(msg)
+// WithMaxQueueBytes limits the approximate memory used by messages waiting for
+// asynchronous delivery, least severe messages are dropped first when exceeded
+func WithMaxQueueBytes(n int) Option {
+	return func(h *TelegramHook) {
+		h.SetMaxQueueBytes(n)
 	}
+}
 
-	return nil
+// WithMaxEntrySize limits the estimated size of an entry, larger fields are replaced by a placeholder before formatting
+func WithMaxEntrySize(n int) Option {
+	return func(h *TelegramHook) {
+		h.SetMaxEntrySize(n)
+	}
 }
 
-// createMessage crafts an HTML-formatted message to send to the Telegram API.
-func (h *TelegramHook) createMessage(entry *logrus.Entry) string {
-	var msg string
-
-	switch entry.Level {
-	case logrus.PanicLevel:
-		msg = "<b>PANIC</b>"
-	case logrus.FatalLevel:
-		msg = "<b>FATAL</b>"
-	case logrus.ErrorLevel:
-		msg = "<b>ERROR</b>"
-	case logrus.WarnLevel:
-		msg = "<b>WARNING</b>"
-	case logrus.InfoLevel:
-		msg = "<b>INFO</b>"
-	case logrus.DebugLevel:
-		msg = "<b>DEBUG</b>"
-	}
-
-	msg = strings.Join([]string{msg, h.AppName()}, "@")
-	msg = strings.Join([]string{msg, entry.Message}, " - ")
-
-	if len(entry.Data) > 0 {
-		msg = strings.Join([]string{msg, "<pre>"}, "\n")
-		for k, v := range entry.Data {
-			msg = strings.Join([]string{msg, html.EscapeString(fmt.Sprintf("\t%s: %+v", k, v))}, "\n")
-		}
-		msg = strings.Join([]string{msg, "</pre>"}, "\n")
+// WithDeliveryFooter adds the delivering worker and the queue wait time to asynchronously sent messages
+func WithDeliveryFooter(footer bool) Option {
+	return func(h *TelegramHook) {
+		h.SetDeliveryFooter(footer)
 	}
+}
 
-	return msg
+// WithLivePanel maintains a pinned message that is edited to show the latest log lines and counters
+func WithLivePanel(panel LivePanel) Option {
+	return func(h *TelegramHook) {
+		h.SetLivePanel(&panel)
+	}
 }
 
-// Levels returns the log levels that the hook should be enabled for.
-func (h *TelegramHook) Levels() []logrus.Level {
-	h.mu.RLock()
-	defer h.mu.RUnlock()
-	return logrus.AllLevels[:h.level+1]
+// WithChatProfile sets how messages sent to chatId are rendered
+func WithChatProfile(chatId string, profile RenderProfile) Option {
+	return func(h *TelegramHook) {
+		h.SetChatProfile(chatId, profile)
+	}
 }
 
-// Fire emits a log message to the Telegram API.
-func (h *TelegramHook) Fire(entry *logrus.Entry) error {
-	msg := h.createMessage(entry)
+// WithHTTPBodyLimit sets how many bytes of a logged request body are shown
+func WithHTTPBodyLimit(n int) Option {
+	return func(h *TelegramHook) {
+		h.SetHTTPBodyLimit(n)
+	}
+}
 
-	if h.Async() {
-		go h.sendMessage(msg)
-		return nil
+// WithHumanize renders durations and *_bytes fields in human-readable units
+func WithHumanize(humanize bool) Option {
+	return func(h *TelegramHook) {
+		h.SetHumanize(humanize)
 	}
+}
 
-	if err := h.sendMessage(msg); err != nil {
-		fmt.Fprintf(os.Stderr, "Unable to send message, %v", err)
-		return err
+// WithErrorBudget adjusts the level to the error budget burn rate reported by a provider
+func WithErrorBudget(budget ErrorBudget) Option {
+	return func(h *TelegramHook) {
+		h.SetErrorBudget(&budget)
 	}
+}
 
-	return nil
+// WithRetry retries requests that failed with a network or server error up to maxAttempts in total
+func WithRetry(maxAttempts int, baseDelay time.Duration) Option {
+	return func(h *TelegramHook) {
+		h.SetRetry(maxAttempts, baseDelay)
+	}
 }
 
-// ApiEndpoint
-func (h *TelegramHook) ApiEndpoint() string {
-	h.mu.RLock()
-	defer h.mu.RUnlock()
-	return fmt.Sprintf("https://api.telegram.org/bot%s", h.authToken)
+// WithJitter sets how retry delays are randomized
+func WithJitter(jitter Jitter) Option {
+	return func(h *TelegramHook) {
+		h.SetJitter(jitter)
+	}
 }
 
-// AppName
-func (h *TelegramHook) AppName() string {
-	h.mu.RLock()
-	defer h.mu.RUnlock()
-	return h.appName
+// WithHostname adds the hostname field to every entry
+func WithHostname(include bool) Option {
+	return func(h *TelegramHook) {
+		h.SetHostname(include)
+	}
 }
 
-func (h *TelegramHook) SetAppName(appName string) {
-	h.mu.Lock()
-	defer h.mu.Unlock()
-	h.appName = appName
+// WithStaticFields adds fields such as the environment, region or version to every entry
+func WithStaticFields(fields logrus.Fields) Option {
+	return func(h *TelegramHook) {
+		h.SetStaticFields(fields)
+	}
 }
 
-// AuthToken
-func (h *TelegramHook) AuthToken() string {
-	h.mu.RLock()
-	defer h.mu.RUnlock()
-	return h.authToken
+// WithProcessInfo adds the PID, parent PID and executable path to fatal and lifecycle messages
+func WithProcessInfo(include bool) Option {
+	return func(h *TelegramHook) {
+		h.SetProcessInfo(include)
+	}
 }
 
-func (h *TelegramHook) SetAuthToken(authToken string) {
-	h.mu.Lock()
-	defer h.mu.Unlock()
-	h.authToken = authToken
+// WithSystemdWatchdog sends systemd watchdog pings while no queued message is older than maxQueueAge
+func WithSystemdWatchdog(maxQueueAge time.Duration) Option {
+	return func(h *TelegramHook) {
+		h.SetSystemdWatchdog(maxQueueAge)
+	}
 }
 
-// ChatId
-func (h *TelegramHook) ChatId() string {
-	h.mu.RLock()
-	defer h.mu.RUnlock()
-	return h.chatId
+// WithAutoTopics creates a forum topic per app or error signature and routes messages there
+func WithAutoTopics(mode TopicMode) Option {
+	return func(h *TelegramHook) {
+		h.SetAutoTopics(mode)
+	}
 }
 
-func (h *TelegramHook) SetChatId(chatId string) {
-	h.mu.Lock()
-	defer h.mu.Unlock()
-	h.chatId = chatId
+// WithTopicArchive closes automatically created topics that had no messages for idle and reopens them on recurrence
+func WithTopicArchive(idle time.Duration) Option {
+	return func(h *TelegramHook) {
+		h.SetTopicArchive(idle)
+	}
 }
 
-// ThreadId
-func (h *TelegramHook) ThreadId() string {
-	h.mu.RLock()
-	defer h.mu.RUnlock()
-	return h.threadId
+// WithStartupAnnouncement announces the start of the app after a random delay of up to spread, so replicas starting together do not post at once
+func WithStartupAnnouncement(spread time.Duration) Option {
+	return func(h *TelegramHook) {
+		h.SetStartupAnnouncement(spread)
+	}
 }
 
-func (h *TelegramHook) SetThreadId(threadId string) {
-	h.mu.Lock()
-	defer h.mu.Unlock()
-	h.threadId = threadId
+// WithSignatureHandler renders entries with signature, e.g. "error: connection refused", using handler
+func WithSignatureHandler(signature string, handler SignatureHandler) Option {
+	return func(h *TelegramHook) {
+		h.HandleSignature(signature, handler)
+	}
 }
 
-// Level
-func (h *TelegramHook) Level() logrus.Level {
-	h.mu.RLock()
-	defer h.mu.RUnlock()
-	return h.level
+// WithMatchHandler renders entries match returns true for using handler
+func WithMatchHandler(match func(*logrus.Entry) bool, handler SignatureHandler) Option {
+	return func(h *TelegramHook) {
+		h.HandleMatch(match, handler)
+	}
 }
 
-func (h *TelegramHook) SetLevel(level logrus.Level) {
-	h.mu.Lock()
-	defer h.mu.Unlock()
-	h.level = level
+// WithSignatureNormalizers normalizes messages before they are grouped by signature
+func WithSignatureNormalizers(normalizers ...Normalizer) Option {
+	return func(h *TelegramHook) {
+		h.SetSignatureNormalizers(normalizers...)
+	}
 }
 
-// Async
-func (h *TelegramHook) Async() bool {
-	h.mu.RLock()
-	defer h.mu.RUnlock()
-	return h.async
+// WithOutbox stores messages in outbox and sends them from a background dispatcher
+func WithOutbox(outbox Outbox) Option {
+	return func(h *TelegramHook) {
+		h.SetOutbox(outbox)
+	}
 }
 
-func (h *TelegramHook) SetAsync(async bool) {
-	h.mu.Lock()
-	defer h.mu.Unlock()
-	h.async = async
+// WithFieldEncryption encrypts the fields section of messages with an AES key shared by the team
+func WithFieldEncryption(key []byte) Option {
+	return func(h *TelegramHook) {
+		h.SetFieldEncryption(key)
+	}
+}
+
+// WithRedaction masks the values of the given fields and replaces matches of the patterns before sending
+func WithRedaction(keys []string, patterns []*regexp.Regexp) Option {
+	return func(h *TelegramHook) {
+		h.SetRedaction(keys, patterns)
+	}
+}
+
+// WithSharedRateLimit shares the rate limit with all hooks of the process sending through the same bot
+func WithSharedRateLimit(shared bool) Option {
+	return func(h *TelegramHook) {
+		h.SetSharedRateLimit(shared)
+	}
+}
+
+// WithRateLimit paces messages to stay within the Telegram rate limits
+func WithRateLimit(limit RateLimit) Option {
+	return func(h *TelegramHook) {
+		h.SetRateLimit(&limit)
+	}
+}
+
+// WithFirehoseCommand lets members of the chat start firehose mode by sending /firehose to the bot
+func WithFirehoseCommand(enabled bool) Option {
+	return func(h *TelegramHook) {
+		h.SetFirehoseCommand(enabled)
+	}
+}
+
+// WithAcknowledgement adds an acknowledge button to severe alerts and re-pings them until it is pressed
+func WithAcknowledgement(ack Acknowledgement) Option {
+	return func(h *TelegramHook) {
+		h.SetAcknowledgement(&ack)
+	}
+}
+
+// WithQueueSize limits the number of messages waiting for delivery in async mode and sets what happens when the queue is full
+func WithQueueSize(n int, policy QueuePolicy) Option {
+	return func(h *TelegramHook) {
+		h.SetQueueSize(n, policy)
+	}
+}
+
+// WithWorkers sets the number of workers delivering messages in async mode
+func WithWorkers(n int) Option {
+	return func(h *TelegramHook) {
+		h.SetWorkers(n)
+	}
+}
+
+// WithLocale formats numbers, dates and times in messages for a language tag like "de" or "en-GB"
+func WithLocale(tag string) Option {
+	return func(h *TelegramHook) {
+		h.SetLocale(tag)
+	}
+}
+
+// WithTimezone shows times in messages in the given time zone
+func WithTimezone(loc *time.Location) Option {
+	return func(h *TelegramHook) {
+		h.SetTimezone(loc)
+	}
+}
+
+// WithExitFlush registers a logrus exit handler that flushes queued messages for up to timeout, so Fatal messages are not lost
+func WithExitFlush(timeout time.Duration) Option {
+	return func(h *TelegramHook) {
+		h.SetExitFlush(timeout)
+	}
+}
+
+// WithAdaptiveBatching combines messages into one during bursts of traffic
+func WithAdaptiveBatching(batching AdaptiveBatching) Option {
+	return func(h *TelegramHook) {
+		h.SetAdaptiveBatching(&batching)
+	}
+}
+
+// WithBatchInterval collects messages for d and sends them as one combined message
+func WithBatchInterval(d time.Duration) Option {
+	return func(h *TelegramHook) {
+		h.SetBatchInterval(d)
+	}
+}
+
+// WithAlarmBot sends entries at level or above through a second bot, so they can be told apart by their sender
+func WithAlarmBot(authToken string, level logrus.Level) Option {
+	return func(h *TelegramHook) {
+		h.SetAlarmBot(authToken, level)
+	}
+}
+
+// WithFormatter renders messages with a custom Formatter instead of the built-in layout
+func WithFormatter(formatter Formatter) Option {
+	return func(h *TelegramHook) {
+		h.SetFormatter(formatter)
+	}
+}
+
+// WithCommonNoiseFilters suppresses error and less severe alerts for noisy errors like context canceled, broken pipe and EOF, counting them instead
+func WithCommonNoiseFilters(enabled bool) Option {
+	return func(h *TelegramHook) {
+		h.SetCommonNoiseFilters(enabled)
+	}
+}
+
+// WithConfigEcho posts the redacted effective configuration to target on startup, the hook's chat if zero
+func WithConfigEcho(target ChatTarget) Option {
+	return func(h *TelegramHook) {
+		h.SetConfigEcho(target)
+	}
+}
+
+// WithChats also sends every entry to targets, e.g. one chat per team
+func WithChats(targets ...ChatTarget) Option {
+	return func(h *TelegramHook) {
+		h.SetChats(targets...)
+	}
+}
+
+// WithLevelRouting sends entries of the given levels to their targets instead of the chat of the hook
+func WithLevelRouting(routes map[logrus.Level]ChatTarget) Option {
+	return func(h *TelegramHook) {
+		h.SetLevelRouting(routes)
+	}
+}
+
+// WithFieldRouting sends entries whose field has one of the given values to its target, e.g. by "component"
+func WithFieldRouting(field string, routes map[string]ChatTarget) Option {
+	return func(h *TelegramHook) {
+		h.SetFieldRouting(field, routes)
+	}
+}
+
+// WithSilent sends messages without notification sound
+func WithSilent(silent bool) Option {
+	return func(h *TelegramHook) {
+		h.SetSilent(silent)
+	}
+}
+
+// WithSilentLevels sends entries of the given levels without notification sound, e.g. Info and Debug
+func WithSilentLevels(levels ...logrus.Level) Option {
+	return func(h *TelegramHook) {
+		h.SetSilentLevels(levels...)
+	}
+}
+
+// WithChatTarget sets the chat, topic and sending options of the hook
+func WithChatTarget(target ChatTarget) Option {
+	return func(h *TelegramHook) {
+		h.SetChatTarget(target)
+	}
+}
+
+// WithTemplate renders messages through a text/template, see TemplateData
+func WithTemplate(tmpl string) Option {
+	return func(h *TelegramHook) {
+		if err := h.SetTemplate(tmpl); err != nil {
+			h.templateErr = err
+		}
+	}
+}
+
+// WithFaultInjection simulates Telegram failures, for tests and staging only
+func WithFaultInjection(faults FaultInjection) Option {
+	return func(h *TelegramHook) {
+		h.SetFaultInjection(&faults)
+	}
+}
+
+// WithParseMode sets how Telegram formats messages: HTML (default), MarkdownV2 or plain text
+func WithParseMode(mode ParseMode) Option {
+	return func(h *TelegramHook) {
+		h.SetParseMode(mode)
+	}
+}
+
+// WithControlChars sets what happens to ANSI escape sequences and control characters: stripped (default), visualized or kept
+func WithControlChars(mode ControlChars) Option {
+	return func(h *TelegramHook) {
+		h.SetControlChars(mode)
+	}
+}
+
+// WithLevelLabels replaces the labels messages start with, e.g. "🔥 PANIC" or "⚠️ WARNING"
+func WithLevelLabels(labels map[logrus.Level]string) Option {
+	return func(h *TelegramHook) {
+		h.SetLevelLabels(labels)
+	}
+}
+
+// WithTimestamp shows when the entry was logged in the message header, e.g. WithTimestamp(time.TimeOnly, nil)
+func WithTimestamp(layout string, loc *time.Location) Option {
+	return func(h *TelegramHook) {
+		h.SetTimestamp(layout, loc)
+	}
+}
+
+// WithCaller shows where entries were logged when logrus reports the caller
+func WithCaller(caller bool) Option {
+	return func(h *TelegramHook) {
+		h.SetCaller(caller)
+	}
+}
+
+// WithRelativeTimes renders time.Time fields with their age, e.g. "(3m ago)"
+func WithRelativeTimes(relative bool) Option {
+	return func(h *TelegramHook) {
+		h.SetRelativeTimes(relative)
+	}
+}
+
+// WithRawHTML inserts log messages as HTML instead of escaping them, for messages that embed formatting on purpose
+func WithRawHTML(raw bool) Option {
+	return func(h *TelegramHook) {
+		h.SetRawHTML(raw)
+	}
+}
+
+// WithPressureGate drops entries below a level while the async queue is saturated
+func WithPressureGate(gate PressureGate) Option {
+	return func(h *TelegramHook) {
+		h.SetPressureGate(&gate)
+	}
+}
+
+// WithVolumeProfile applies the queue, worker and batching settings of a profile like Standard
+func WithVolumeProfile(profile VolumeProfile) Option {
+	return func(h *TelegramHook) {
+		h.SetVolumeProfile(profile)
+	}
+}
+
+// WithSkipVerification constructs the hook without checking the token with Telegram, e.g. while Telegram is unreachable
+func WithSkipVerification(skip bool) Option {
+	return func(h *TelegramHook) {
+		h.SetSkipVerification(skip)
+	}
+}
+
+// WithDeliveryConfirmation checks delay after sending that messages exist in the chat and sends missing ones again
+func WithDeliveryConfirmation(delay time.Duration) Option {
+	return func(h *TelegramHook) {
+		h.SetDeliveryConfirmation(delay)
+	}
+}
+
+// WithDedupWindow suppresses identical messages for window and then reports how often they were repeated
+func WithDedupWindow(window time.Duration) Option {
+	return func(h *TelegramHook) {
+		h.SetDedupWindow(window)
+	}
+}
+
+// WithLimitWarnings reports when the send volume approaches Telegram's limits, before requests fail with 429
+func WithLimitWarnings(warnings LimitWarnings) Option {
+	return func(h *TelegramHook) {
+		h.SetLimitWarnings(&warnings)
+	}
+}
+
+// WithArchive stores every delivered message with its metadata in blob, e.g. an S3 or GCS bucket
+func WithArchive(blob Blob) Option {
+	return func(h *TelegramHook) {
+		h.SetArchive(blob)
+	}
+}
+
+// WithFloodSummary sends a periodic summary instead of individual messages while entries flood in
+func WithFloodSummary(summary FloodSummary) Option {
+	return func(h *TelegramHook) {
+		h.SetFloodSummary(&summary)
+	}
+}
+
+// WithCoalescing sends the first entry of a burst right away and the latest one with a count when it ends
+func WithCoalescing(coalescing Coalescing) Option {
+	return func(h *TelegramHook) {
+		h.SetCoalescing(&coalescing)
+	}
+}
+
+// New creates a new instance of a hook targeting the Telegram API.
+func NewTelegramHook(appName, authToken, chatId, threadId string, options ...Option) (*TelegramHook, error) {
+	client := &http.Client{}
+	return NewTelegramHookWithClient(appName, authToken, chatId, threadId, client, options...)
+}
+
+// NewTelegramHookWithClient creates a new instance of a hook targeting the Telegram API with custom http.Client.
+func NewTelegramHookWithClient(appName, authToken, chatId, threadId string, client *http.Client, options ...Option) (*TelegramHook, error) {
+	h := TelegramHook{
+		client: client,
+		done:   make(chan struct{}),
+		config: config{
+			appName:   appName,
+			authToken: authToken,
+			chatId:    chatId,
+			threadId:  threadId,
+			level:     logrus.ErrorLevel,
+			async:     false,
+
+			headlineFields:   3,
+			failoverCooldown: defaultFailoverCooldown,
+			maxEntrySize:     defaultMaxEntrySize,
+			httpBodyLimit:    defaultHTTPBodyLimit,
+			retryAttempts:    1,
+			retryDelay:       defaultRetryDelay,
+			queueSize:        defaultQueueSize,
+			workers:          1,
+		},
+	}
+
+	for _, opt := range options {
+		opt(&h)
+	}
+
+	if err := h.validate(); err != nil {
+		return nil, err
+	}
+
+	if err := h.configureTransport(); err != nil {
+		return nil, err
+	}
+
+	if err := h.openSpool(); err != nil {
+		return nil, err
+	}
+
+	// Verify the API token is valid and correct before continuing
+	if !h.skipVerification {
+		if err := h.verifyToken(); err != nil {
+			return nil, err
+		}
+		if err := h.verifyAlarmBot(); err != nil {
+			return nil, err
+		}
+	}
+
+	h.startWatchdog()
+	h.startTopicArchiver()
+	h.announceStartup()
+	h.echoConfig()
+	h.startOutbox()
+	h.startSpool()
+	h.startUpdateListener()
+
+	if h.exitFlush > 0 {
+		logrus.RegisterExitHandler(h.FlushOnExit)
+	}
+
+	return &h, nil
+}
+
+// closeTimeout bounds how long Close waits for queued messages.
+const closeTimeout = 5 * time.Second
+
+// Close delivers queued messages, waiting at most 5 seconds, and stops the
+// background tasks of the hook. It returns an error if messages were still
+// pending.
+func (h *TelegramHook) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), closeTimeout)
+	defer cancel()
+
+	var err error
+	h.closeOnce.Do(func() {
+		err = h.Flush(ctx)
+
+		if h.done != nil {
+			close(h.done)
+		}
+		h.pending.close()
+		h.acks.stop()
+		h.events.close()
+	})
+	return err
+}
+
+// validate checks the configuration for values that can never work.
+func (h *TelegramHook) validate() error {
+	if h.client == nil {
+		return &ConfigError{Field: "client", Reason: "is nil"}
+	}
+
+	if h.authToken == "" {
+		return &ConfigError{Field: "authToken", Reason: "is empty"}
+	}
+
+	if strings.ContainsAny(h.authToken, "/ \t\r\n") {
+		return &ConfigError{Field: "authToken", Reason: "contains invalid characters"}
+	}
+
+	if strings.ContainsAny(h.alarmToken, "/ \t\r\n") {
+		return &ConfigError{Field: "alarmToken", Reason: "contains invalid characters"}
+	}
+
+	if n := len(h.encryptionKey); n != 0 && n != 16 && n != 24 && n != 32 {
+		return &ConfigError{Field: "encryptionKey", Reason: "must be 16, 24 or 32 bytes"}
+	}
+
+	if h.templateErr != nil {
+		return &ConfigError{Field: "template", Reason: h.templateErr.Error()}
+	}
+
+	if _, ok := lookupLocale(h.localeTag); h.localeTag != "" && !ok {
+		return &ConfigError{Field: "locale", Reason: "is not supported"}
+	}
+
+	for _, base := range h.baseURLs {
+		if u, err := url.Parse(base); err != nil || u.Host == "" || u.Scheme != "http" && u.Scheme != "https" {
+			return &ConfigError{Field: "apiEndpoint", Reason: fmt.Sprintf("%q is not an http(s) URL", base)}
+		}
+	}
+
+	if h.errorBudget != nil && h.errorBudget.Provider == nil {
+		return &ConfigError{Field: "errorBudget", Reason: "has no provider"}
+	}
+
+	if h.gate != nil && !(h.gate.Threshold > 0 && h.gate.Threshold <= 1) {
+		return &ConfigError{Field: "pressureGate", Reason: "threshold must be more than 0 and at most 1"}
+	}
+	if h.gate != nil && h.gate.Level > logrus.TraceLevel {
+		return &ConfigError{Field: "pressureGate", Reason: fmt.Sprintf("unknown level %d", h.gate.Level)}
+	}
+
+	return nil
+}
+
+// sendMessage issues the provided message to the Telegram API, splitting it
+// into several messages with part indicators when it exceeds the Telegram
+// length limit. It returns the IDs of the messages sent, also when a later
+// part failed.
+func (h *TelegramHook) sendMessage(cfg config, msg string) ([]int64, error) {
+	return h.sendReply(context.Background(), cfg, msg, replyParameters{})
+}
+
+// sendReply is sendMessage with every part sent as the reply, unless it has no
+// message ID. The requests are cancelled once ctx is done.
+func (h *TelegramHook) sendReply(ctx context.Context, cfg config, msg string, reply replyParameters) ([]int64, error) {
+	return h.sendReplyFrom(ctx, cfg, msg, reply, 0)
+}
+
+// sendReplyFrom is sendReply skipping the first skip parts, which were sent
+// before.
+func (h *TelegramHook) sendReplyFrom(ctx context.Context, cfg config, msg string, reply replyParameters, skip int) ([]int64, error) {
+	var ids []int64
+	parts := splitMessage(msg)
+	if skip > len(parts) {
+		skip = len(parts)
+	}
+	for _, part := range parts[skip:] {
+		id, err := h.sendPart(ctx, cfg, part, reply)
+		if err != nil {
+			return ids, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// Levels returns the log levels that the hook should be enabled for.
+//
+// logrus reads the levels only once when the hook is added, so all levels are
+// returned and Fire filters by the current level. This way SetLevel and
+// EnableFirehose take effect on hooks that are already registered.
+func (h *TelegramHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire emits a log message to the Telegram API.
+func (h *TelegramHook) Fire(entry *logrus.Entry) error {
+	return h.fire(recordOf(entry))
+}
+
+// fire runs a record through the pipeline. Records of all loggers enter here;
+// they are turned into logrus entries only for rendering, which custom
+// formatters, templates and handlers are defined on.
+func (h *TelegramHook) fire(r Record) error {
+	cfg := h.snapshot()
+	h.stats.fired.Add(1)
+
+	if cfg.levelProvider != nil {
+		cfg.level = cfg.levelProvider.Level()
+	}
+	if cfg.errorBudget != nil {
+		cfg.level = h.budgetLevel(cfg)
+	}
+
+	level := r.Level.logrus()
+	enabled, firehose := cfg.enabled(level)
+	if !enabled {
+		h.stats.skip(level)
+		h.emit(Event{Type: EventMuted, Level: level, Key: r.key()})
+		return nil
+	}
+
+	if cfg.skipEmpty && r.Message == "" && len(r.Fields) == 0 {
+		h.emit(Event{Type: EventMuted, Level: level, Key: r.key()})
+		return nil
+	}
+
+	if h.gated(cfg, level) {
+		h.stats.dropped.Add(1)
+		h.emit(Event{Type: EventDropped, Level: level, Key: r.key()})
+		return nil
+	}
+
+	entry := r.entry()
+	// Fatal and panic entries are sent even when they look like noise, since
+	// the process is going down.
+	if cfg.noiseFilters && entry.Level >= logrus.ErrorLevel {
+		if name := noise(entry); name != "" {
+			h.noise.add(name)
+			h.emit(Event{Type: EventMuted, Level: entry.Level, Key: correlationKey(entry)})
+			return nil
+		}
+	}
+
+	entry = cfg.addMetadata(entry)
+	target, entry := entryTarget(entry)
+	attachments, entry := entryAttachments(entry)
+	if target == nil {
+		target = cfg.fieldRoute(entry)
+	}
+	if target == nil {
+		target = cfg.levelRoute(entry.Level)
+	}
+	entry = limitEntry(entry, cfg.maxEntrySize)
+	entry = sanitizeEntry(entry, cfg.controlChars)
+	entry = cfg.redactEntry(entry)
+
+	if cfg.panel != nil {
+		h.recordPanel(cfg, entry)
+		if cfg.panel.Exclusive {
+			return nil
+		}
+	}
+
+	if cfg.flood != nil && h.flooded(cfg, entry) {
+		h.emit(Event{Type: EventMuted, Level: entry.Level, Key: correlationKey(entry)})
+		return nil
+	}
+
+	if target != nil {
+		target.apply(&cfg)
+	}
+
+	msg, doc, handled := cfg.handle(entry)
+	if !handled {
+		msg, doc = cfg.renderFor(entry, cfg.chatId)
+	}
+	if firehose {
+		h.bufferFirehose(cfg, msg)
+		return nil
+	}
+
+	if cfg.dedupWindow > 0 && h.repeated(cfg, entry.Level, msg) {
+		h.emit(Event{Type: EventMuted, Level: entry.Level, Key: correlationKey(entry)})
+		return nil
+	}
+
+	alarm := cfg.useAlarmBot(entry.Level)
+	out := outgoing{
+		entry:       entry,
+		level:       entry.Level,
+		key:         correlationKey(entry),
+		signature:   cfg.signature(entry),
+		topic:       cfg.topicName(entry),
+		msg:         msg,
+		doc:         doc,
+		attachments: attachments,
+	}
+	if target != nil && target.ThreadId != "" {
+		out.topic = ""
+	}
+	inc := h.incidentFor(entry)
+	if inc != nil {
+		reply := inc.reply()
+		out.topic, out.replyTo, out.quote = "", reply.MessageId, reply.Quote
+		cfg.chatId, cfg.threadId = inc.cfg.chatId, inc.cfg.threadId
+	}
+
+	deliveries := []delivery{{cfg: cfg, out: out}}
+	if target == nil && inc == nil && len(cfg.chats) > 0 {
+		deliveries = h.fanOut(cfg, entry, out, handled)
+	}
+
+	if cfg.coalesce != nil && len(deliveries) == 1 && h.coalesced(deliveries[0].cfg, deliveries[0].out) {
+		h.emit(Event{Type: EventMuted, Level: entry.Level, Key: out.key})
+		return nil
+	}
+
+	if cfg.outbox != nil {
+		for _, d := range deliveries {
+			if err := h.putOutbox(entry.Context, d.cfg, d.out); err != nil {
+				h.reportError(entry, err)
+				if !cfg.softFail {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	if !alarm && len(deliveries) == 1 && target == nil && len(attachments) == 0 && cfg.batchingEnabled() && h.batchMessage(deliveries[0].cfg, deliveries[0].out) {
+		return nil
+	}
+
+	if cfg.async {
+		for _, d := range deliveries {
+			h.enqueue(d.cfg, d.out)
+		}
+		return nil
+	}
+
+	ctx := entry.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if err := h.deliverAll(ctx, deliveries); err != nil {
+		h.reportError(entry, err)
+		if cfg.softFail {
+			return nil
+		}
+		return err
+	}
+
+	return nil
+}
+
+// FireContext is Fire with ctx bounding the Telegram requests of a synchronous
+// delivery, e.g. to cancel them during shutdown or to limit their latency with
+// a deadline. Fire uses the context of the entry the same way, see
+// logrus.WithContext. Queued and batched messages are sent independently of
+// the context, as they outlive the call.
+func (h *TelegramHook) FireContext(ctx context.Context, entry *logrus.Entry) error {
+	e := *entry
+	e.Context = ctx
+	return h.Fire(&e)
+}
+
+// SendMessageContext sends the HTML formatted msg to the configured chat,
+// split into several messages if needed, and returns their IDs. The requests
+// are cancelled once ctx is done.
+func (h *TelegramHook) SendMessageContext(ctx context.Context, msg string) ([]int64, error) {
+	return h.sendReply(ctx, h.snapshot(), msg, replyParameters{})
+}
+
+// handleError reports a failure that does not concern a single entry.
+func (h *TelegramHook) handleError(err error) {
+	h.reportError(nil, err)
+}
+
+// reportError reports the failed delivery of entry to the ErrorHandler, or to
+// stderr when there is none.
+func (h *TelegramHook) reportError(entry *logrus.Entry, err error) {
+	if handler := h.ErrorHandler(); handler != nil {
+		handler(entry, err)
+		return
+	}
+	if h.UserAgentVersion() {
+		fmt.Fprintf(os.Stderr, "telegramhook %s: Unable to send message, %v", Version, err)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Unable to send message, %v", err)
+}
+
+// outgoing is a rendered entry on its way to Telegram.
+type outgoing struct {
+	entry       *logrus.Entry // nil for combined messages
+	level       logrus.Level
+	key         string // correlation key, see CorrelationKey
+	signature   string // see RecentAlerts, empty for combined messages
+	topic       string // forum topic name, see WithAutoTopics
+	replyTo     int64  // message the entry replies to, see IncidentKey
+	quote       string // part of the replied message that is quoted
+	msg         string
+	doc         *document
+	attachments []Attachment // see AttachmentsKey
+
+	// spoolFile is the file a message replayed from the spool was read from,
+	// logged when its entry was logged and sent the number of parts of msg
+	// delivered before, see WithSpoolDir.
+	spoolFile string
+	logged    time.Time
+	sent      int
+	// outboxed marks messages sent from the outbox, which keeps them for the
+	// next dispatch after transient failures, see WithOutbox.
+	outboxed bool
+}
+
+// reply returns the reply parameters of out, without a message ID unless it
+// replies to a message.
+func (out outgoing) reply() replyParameters {
+	if out.replyTo == 0 {
+		return replyParameters{}
+	}
+	return replyParameters{MessageId: out.replyTo, Quote: out.quote}
+}
+
+// deliver sends the message followed by the optional fields document. The
+// messages are tracked under the correlation key unless it is empty.
+func (h *TelegramHook) deliver(ctx context.Context, cfg config, out outgoing) error {
+	if cfg.spoolDir != "" && out.spoolFile == "" && h.spool.backlog() {
+		err := h.spoolMessage(cfg, out)
+		if err == nil {
+			return nil
+		}
+		h.handleError(fmt.Errorf("spool: %w", err))
+	}
+	if out.topic != "" {
+		cfg.threadId = h.topicThread(cfg, out.topic)
+	}
+	if cfg.silentLevel(out.level) {
+		cfg.silent = true
+	}
+
+	var ids []int64
+	var err error
+	if len(out.attachments) > 0 {
+		ids, err = h.sendAttachments(ctx, cfg, out.msg, out.reply(), out.attachments)
+	} else {
+		ids, err = h.sendReplyFrom(ctx, cfg, out.msg, out.reply(), out.sent)
+	}
+	h.sent.track(out.key, cfg.authToken, cfg.chatId, ids)
+	acked := cfg.ack != nil && out.level <= cfg.ack.Level && len(out.attachments) == 0
+	if err == nil && acked && len(ids) > 0 {
+		parts := splitMessage(out.msg)
+		h.requestAck(cfg, ids[len(ids)-1], parts[len(parts)-1])
+	}
+	if err == nil && !acked && cfg.confirmDelay > 0 && len(ids) > 0 {
+		h.scheduleConfirm(cfg, out, ids)
+	}
+	if err == nil && out.doc != nil {
+		err = h.sendDocument(ctx, cfg, *out.doc)
+	}
+
+	if err != nil {
+		h.stats.failed.Add(1)
+		h.emit(Event{Type: EventFailed, Level: out.level, Key: out.key, MessageIds: ids, Err: err})
+		if len(out.attachments) == 0 {
+			out.sent += len(ids)
+		}
+		if out.spoolFile != "" {
+			if len(ids) > 0 {
+				if serr := writeSpooled(out.spoolFile, cfg, out); serr != nil {
+					h.handleError(fmt.Errorf("spool: %w", serr))
+				}
+			}
+			return err
+		}
+		// Messages Telegram rejects would be rejected again, so only network
+		// and server errors are spooled.
+		if cfg.spoolDir != "" && transient(err) {
+			serr := h.spoolMessage(cfg, out)
+			if serr == nil {
+				return nil
+			}
+			h.handleError(fmt.Errorf("spool: %w", serr))
+		}
+		if out.outboxed && transient(err) {
+			return err
+		}
+		h.fallback(cfg, out)
+		return err
+	}
+
+	if cfg.archive != nil {
+		h.archiveMessage(ctx, cfg, out, ids)
+	}
+	h.recent.add(Alert{
+		ChatId:     cfg.chatId,
+		ThreadId:   cfg.threadId,
+		MessageIds: ids,
+		Level:      out.level,
+		Signature:  out.signature,
+		Key:        out.key,
+		Text:       out.msg,
+		Sent:       time.Now(),
+	})
+
+	h.stats.sent.Add(1)
+	h.emit(Event{Type: EventSent, Level: out.level, Key: out.key, MessageIds: ids})
+	return nil
+}
+
+// snapshot returns a copy of the current configuration.
+func (h *TelegramHook) snapshot() config {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.config
+}
+
+// ApiEndpoint returns the API base URL that is currently preferred, the first
+// configured one unless it failed recently.
+func (h *TelegramHook) ApiEndpoint() string {
+	cfg := h.snapshot()
+	return h.endpoints.order(cfg.apiBaseURLs())[0]
+}
+
+// SetApiEndpoint sets the API base URL, e.g. "http://localhost:8081" for a
+// self-hosted Bot API server. An empty baseURL restores the official server.
+func (h *TelegramHook) SetApiEndpoint(baseURL string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.baseURLs = nil
+	if baseURL != "" {
+		h.baseURLs = []string{baseURL}
+	}
+}
+
+// ApiEndpoints returns the configured API base URLs in failover order.
+func (h *TelegramHook) ApiEndpoints() []string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return append([]string(nil), h.apiBaseURLs()...)
+}
+
+// SetApiEndpoints sets the API base URLs tried in order, e.g. the official
+// server followed by a self-hosted mirror.
+func (h *TelegramHook) SetApiEndpoints(baseURLs ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.baseURLs = append([]string(nil), baseURLs...)
+}
+
+// FailoverCooldown
+func (h *TelegramHook) FailoverCooldown() time.Duration {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.failoverCooldown
+}
+
+func (h *TelegramHook) SetFailoverCooldown(cooldown time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.failoverCooldown = cooldown
+}
+
+// userAgent returns the User-Agent header value, empty to leave the client default.
+func (c *config) userAgent() string {
+	if c.customUA != "" {
+		return c.customUA
+	}
+	if c.anonymous {
+		return ""
+	}
+	return "logrus-hook-telegram/" + Version
+}
+
+// AppName
+func (h *TelegramHook) AppName() string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.appName
+}
+
+func (h *TelegramHook) SetAppName(appName string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.appName = appName
+}
+
+// AuthToken
+func (h *TelegramHook) AuthToken() string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.authToken
+}
+
+func (h *TelegramHook) SetAuthToken(authToken string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.authToken = authToken
+}
+
+// ChatId
+func (h *TelegramHook) ChatId() string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.chatId
+}
+
+func (h *TelegramHook) SetChatId(chatId string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.chatId = chatId
+}
+
+// ThreadId
+func (h *TelegramHook) ThreadId() string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.threadId
+}
+
+func (h *TelegramHook) SetThreadId(threadId string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.threadId = threadId
+}
+
+// Level
+func (h *TelegramHook) Level() logrus.Level {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.level
+}
+
+func (h *TelegramHook) SetLevel(level logrus.Level) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.level = level
+}
+
+// LevelProvider
+func (h *TelegramHook) LevelProvider() LevelProvider {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.levelProvider
+}
+
+// SetLevelProvider sets where the least severe level that is sent comes from,
+// consulted on every entry so it can follow a feature flag or remote config
+// without calling SetLevel. The error budget still raises it. nil uses the
+// configured level again.
+func (h *TelegramHook) SetLevelProvider(provider LevelProvider) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.levelProvider = provider
+}
+
+// Async
+func (h *TelegramHook) Async() bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.async
+}
+
+func (h *TelegramHook) SetAsync(async bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.async = async
+}
+
+// SkipEmpty
+func (h *TelegramHook) SkipEmpty() bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.skipEmpty
+}
+
+func (h *TelegramHook) SetSkipEmpty(skip bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.skipEmpty = skip
+}
+
+// HeadlineFields
+func (h *TelegramHook) HeadlineFields() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.headlineFields
+}
+
+func (h *TelegramHook) SetHeadlineFields(n int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if n < 0 {
+		n = 0
+	}
+	h.headlineFields = n
+}
+
+// ErrorKeyPromotion
+func (h *TelegramHook) ErrorKeyPromotion() bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.promoteErrorKey
+}
+
+func (h *TelegramHook) SetErrorKeyPromotion(promote bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.promoteErrorKey = promote
+}
+
+// FieldsTable
+func (h *TelegramHook) FieldsTable() *TableLayout {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.table
+}
+
+// SetFieldsTable enables table rendering of fields, nil restores the default list.
+func (h *TelegramHook) SetFieldsTable(layout *TableLayout) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.table = layout
+}
+
+// MaxFields
+func (h *TelegramHook) MaxFields() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.maxFields
+}
+
+// SetMaxFields limits the number of rendered fields, zero renders all of them.
+func (h *TelegramHook) SetMaxFields(n int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if n < 0 {
+		n = 0
+	}
+	h.maxFields = n
+}
+
+// FieldOrder
+func (h *TelegramHook) FieldOrder() []string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return append([]string(nil), h.fieldOrder...)
+}
+
+// SetFieldOrder pins fields to the top of messages in the given order. The
+// error field follows them and the remaining fields are sorted alphabetically.
+func (h *TelegramHook) SetFieldOrder(keys []string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.fieldOrder = append([]string(nil), keys...)
+}
+
+// HiddenFields
+func (h *TelegramHook) HiddenFields() []string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return append([]string(nil), h.hiddenFields...)
+}
+
+// SetHiddenFields leaves noisy fields out of messages and fields documents.
+// They can still be used for routing, topics and correlation keys.
+func (h *TelegramHook) SetHiddenFields(keys []string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.hiddenFields = append([]string(nil), keys...)
+}
+
+// FieldsDocument
+func (h *TelegramHook) FieldsDocument() bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.fieldsDocument
+}
+
+func (h *TelegramHook) SetFieldsDocument(attach bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.fieldsDocument = attach
+}
+
+// SoftFail
+func (h *TelegramHook) SoftFail() bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.softFail
+}
+
+func (h *TelegramHook) SetSoftFail(softFail bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.softFail = softFail
+}
+
+// ErrorHandler
+func (h *TelegramHook) ErrorHandler() ErrorHandler {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.errorHandler
+}
+
+// SetErrorHandler sets the function failures are reported to, e.g. to count
+// them in metrics or log them elsewhere. nil prints them to stderr.
+func (h *TelegramHook) SetErrorHandler(handler ErrorHandler) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.errorHandler = handler
+}
+
+// SpoolDir
+func (h *TelegramHook) SpoolDir() string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.spoolDir
+}
+
+// SetSpoolDir sets the directory messages that could not be delivered, even
+// after retries, are stored in as files, to be sent in order once Telegram is
+// reachable again. Empty disables the spool. The directory is only created and
+// replayed by NewTelegramHook.
+func (h *TelegramHook) SetSpoolDir(dir string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.spoolDir = dir
+}
+
+// ReplayRate
+func (h *TelegramHook) ReplayRate() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.replayRate
+}
+
+// SetReplayRate sets how many spooled messages are sent per minute at most,
+// zero sends them as fast as the rate limit allows.
+func (h *TelegramHook) SetReplayRate(perMinute int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.replayRate = perMinute
+}
+
+// Fallback
+func (h *TelegramHook) Fallback() io.Writer {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.fallbackWriter
+}
+
+// SetFallback sets where messages are written as plain text, one per line,
+// when they could not be delivered even after retries, e.g. a local file. nil
+// disables it.
+func (h *TelegramHook) SetFallback(w io.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.fallbackWriter = w
+}
+
+// FallbackHook
+func (h *TelegramHook) FallbackHook() logrus.Hook {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.fallbackHook
+}
+
+// SetFallbackHook sets the hook entries are forwarded to when their message
+// could not be delivered even after retries, if it fires for their level.
+// Combined messages such as batches are forwarded as a new entry with the
+// message text. nil disables it.
+func (h *TelegramHook) SetFallbackHook(hook logrus.Hook) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.fallbackHook = hook
+}
+
+// UserAgent
+func (h *TelegramHook) UserAgent() string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.userAgent()
+}
+
+// SetUserAgent sets a custom User-Agent header, empty restores the default.
+func (h *TelegramHook) SetUserAgent(userAgent string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.customUA = userAgent
+}
+
+// UserAgentVersion
+func (h *TelegramHook) UserAgentVersion() bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return !h.anonymous
+}
+
+func (h *TelegramHook) SetUserAgentVersion(identify bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.anonymous = !identify
+}
+
+// MaxQueueBytes
+func (h *TelegramHook) MaxQueueBytes() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.maxQueueBytes
+}
+
+// SetMaxQueueBytes limits the memory of queued messages, zero means unlimited.
+func (h *TelegramHook) SetMaxQueueBytes(n int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.maxQueueBytes = n
+}
+
+// MaxEntrySize
+func (h *TelegramHook) MaxEntrySize() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.maxEntrySize
+}
+
+// SetMaxEntrySize limits the estimated entry size in bytes, zero disables the limit.
+func (h *TelegramHook) SetMaxEntrySize(n int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.maxEntrySize = n
+}
+
+// DeliveryFooter
+func (h *TelegramHook) DeliveryFooter() bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.deliveryFooter
+}
+
+func (h *TelegramHook) SetDeliveryFooter(footer bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.deliveryFooter = footer
+}
+
+// LivePanel
+func (h *TelegramHook) LivePanel() *LivePanel {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.panel
+}
+
+// SetLivePanel enables the live panel, nil disables it.
+func (h *TelegramHook) SetLivePanel(panel *LivePanel) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.panel = panel
+}
+
+// ChatProfile returns the render profile for chatId and whether one is set.
+func (h *TelegramHook) ChatProfile(chatId string) (RenderProfile, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	profile, ok := h.profiles[chatId]
+	return profile, ok
+}
+
+func (h *TelegramHook) SetChatProfile(chatId string, profile RenderProfile) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	profiles := make(map[string]RenderProfile, len(h.profiles)+1)
+	for k, v := range h.profiles {
+		profiles[k] = v
+	}
+	profiles[chatId] = profile
+	h.profiles = profiles
+}
+
+// HTTPBodyLimit
+func (h *TelegramHook) HTTPBodyLimit() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.httpBodyLimit
+}
+
+// SetHTTPBodyLimit sets the number of request body bytes shown, zero hides bodies.
+func (h *TelegramHook) SetHTTPBodyLimit(n int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.httpBodyLimit = n
+}
+
+// Humanize
+func (h *TelegramHook) Humanize() bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.humanize
+}
+
+// SetHumanize enables human-readable durations and byte counts.
+func (h *TelegramHook) SetHumanize(humanize bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.humanize = humanize
+}
+
+// LevelLabels
+func (h *TelegramHook) LevelLabels() map[logrus.Level]string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	labels := make(map[logrus.Level]string, len(h.levelLabels))
+	for level, label := range h.levelLabels {
+		labels[level] = label
+	}
+	return labels
+}
+
+// SetLevelLabels sets the labels messages of a level start with, shown in
+// bold, e.g. with an emoji. Levels without a label keep the default such as
+// "ERROR"; an empty label leaves it out. nil restores the defaults.
+func (h *TelegramHook) SetLevelLabels(labels map[logrus.Level]string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.levelLabels = make(map[logrus.Level]string, len(labels))
+	for level, label := range labels {
+		h.levelLabels[level] = label
+	}
+}
+
+// Timestamp
+func (h *TelegramHook) Timestamp() (string, *time.Location) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.timeLayout, h.timeLocation
+}
+
+// SetTimestamp shows the time of the entry in the message header, formatted
+// with layout in loc, so delayed or batched messages still tell when the event
+// happened. A nil loc uses the time zone of WithTimezone, or the one the entry
+// was logged in. An empty layout leaves the time out.
+func (h *TelegramHook) SetTimestamp(layout string, loc *time.Location) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.timeLayout, h.timeLocation = layout, loc
+}
+
+// Caller
+func (h *TelegramHook) Caller() bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.caller
+}
+
+// SetCaller shows the file, line and function an entry was logged from below
+// the headline, e.g. "pkg/server/handler.go:42 (HandleRequest)". It needs
+// logger.SetReportCaller(true); entries without a caller are unaffected.
+func (h *TelegramHook) SetCaller(caller bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.caller = caller
+}
+
+// RelativeTimes
+func (h *TelegramHook) RelativeTimes() bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.relativeTimes
+}
+
+// SetRelativeTimes enables rendering time.Time fields both as they are and
+// relative to when the entry was logged, which is easier to read on a phone.
+func (h *TelegramHook) SetRelativeTimes(relative bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.relativeTimes = relative
+}
+
+// ErrorBudget
+func (h *TelegramHook) ErrorBudget() *ErrorBudget {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.errorBudget
+}
+
+// SetErrorBudget enables level adjustment by error budget, nil disables it.
+func (h *TelegramHook) SetErrorBudget(budget *ErrorBudget) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.errorBudget = budget
+}
+
+// Retry returns the maximum number of attempts per request and the base delay between them.
+func (h *TelegramHook) Retry() (int, time.Duration) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.retryAttempts, h.retryDelay
+}
+
+// SetRetry sets the maximum number of attempts per request, 1 disables retries.
+// The delay doubles with every retry, starting at baseDelay.
+func (h *TelegramHook) SetRetry(maxAttempts int, baseDelay time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.retryAttempts = maxAttempts
+	h.retryDelay = baseDelay
+}
+
+// Jitter
+func (h *TelegramHook) Jitter() Jitter {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.jitter
+}
+
+// SetJitter sets how retry delays are randomized.
+func (h *TelegramHook) SetJitter(jitter Jitter) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.jitter = jitter
+}
+
+// ProcessInfo
+func (h *TelegramHook) ProcessInfo() bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.processInfo
+}
+
+// SetProcessInfo includes process information in fatal and lifecycle messages.
+func (h *TelegramHook) SetProcessInfo(include bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.processInfo = include
+}
+
+// Hostname
+func (h *TelegramHook) Hostname() bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.hostname
+}
+
+// SetHostname adds the name of the host as the hostname field to every entry,
+// unless the entry has that field.
+func (h *TelegramHook) SetHostname(include bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.hostname = include
+}
+
+// StaticFields
+func (h *TelegramHook) StaticFields() logrus.Fields {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	fields := make(logrus.Fields, len(h.staticFields))
+	for k, v := range h.staticFields {
+		fields[k] = v
+	}
+	return fields
+}
+
+// SetStaticFields sets fields added to every entry, e.g. the environment,
+// region and version of the application. Fields of the entry take precedence.
+func (h *TelegramHook) SetStaticFields(fields logrus.Fields) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.staticFields = make(logrus.Fields, len(fields))
+	for k, v := range fields {
+		h.staticFields[k] = v
+	}
+}
+
+// SystemdWatchdog
+func (h *TelegramHook) SystemdWatchdog() time.Duration {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.watchdogMaxAge
+}
+
+// SetSystemdWatchdog sets the queue age at which watchdog pings stop. The watchdog is only started by NewTelegramHook.
+func (h *TelegramHook) SetSystemdWatchdog(maxQueueAge time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.watchdogMaxAge = maxQueueAge
+}
+
+// AutoTopics
+func (h *TelegramHook) AutoTopics() TopicMode {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.autoTopics
+}
+
+// SetAutoTopics sets how forum topics are created automatically, TopicsOff disables it.
+func (h *TelegramHook) SetAutoTopics(mode TopicMode) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.autoTopics = mode
+}
+
+// TopicArchive
+func (h *TelegramHook) TopicArchive() time.Duration {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.topicArchive
+}
+
+// SetTopicArchive sets after how long without messages automatically created topics are closed, zero keeps them open. Archiving is only started by NewTelegramHook.
+func (h *TelegramHook) SetTopicArchive(idle time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.topicArchive = idle
+}
+
+// StartupAnnouncement returns the maximum delay of the startup announcement and whether it is enabled.
+func (h *TelegramHook) StartupAnnouncement() (time.Duration, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.startupSpread, h.startup
+}
+
+// SetStartupAnnouncement enables the startup announcement with a random delay
+// of up to spread. The announcement is only sent by NewTelegramHook.
+func (h *TelegramHook) SetStartupAnnouncement(spread time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.startup = true
+	h.startupSpread = spread
+}
+
+// SignatureNormalizers
+func (h *TelegramHook) SignatureNormalizers() []Normalizer {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return append([]Normalizer(nil), h.normalizers...)
+}
+
+// SetSignatureNormalizers sets the normalizers applied in order to messages
+// before they are grouped by signature, e.g. DefaultNormalizers().
+func (h *TelegramHook) SetSignatureNormalizers(normalizers ...Normalizer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.normalizers = append([]Normalizer(nil), normalizers...)
+}
+
+// Outbox
+func (h *TelegramHook) Outbox() Outbox {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.outbox
+}
+
+// SetOutbox sets the outbox messages are stored in before they are sent, nil
+// sends them directly. The dispatcher is only started by NewTelegramHook.
+func (h *TelegramHook) SetOutbox(outbox Outbox) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.outbox = outbox
+}
+
+// FieldEncryption
+func (h *TelegramHook) FieldEncryption() []byte {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return append([]byte(nil), h.encryptionKey...)
+}
+
+// SetFieldEncryption sets the AES key of 16, 24 or 32 bytes the fields section
+// is encrypted with, nil sends it in plaintext.
+func (h *TelegramHook) SetFieldEncryption(key []byte) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if key == nil {
+		h.encryptionKey = nil
+		return
+	}
+	h.encryptionKey = append([]byte(nil), key...)
+}
+
+// Redaction
+func (h *TelegramHook) Redaction() ([]string, []*regexp.Regexp) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return append([]string(nil), h.redactKeys...), append([]*regexp.Regexp(nil), h.redactPatterns...)
+}
+
+// SetRedaction masks the values of the fields named keys, compared
+// case-insensitively, e.g. DefaultRedactedFields, and replaces the matches of
+// patterns in messages and in string and error fields, e.g. API keys or card
+// numbers, with "[REDACTED]".
+func (h *TelegramHook) SetRedaction(keys []string, patterns []*regexp.Regexp) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.redactKeys = append([]string(nil), keys...)
+	h.redactPatterns = append([]*regexp.Regexp(nil), patterns...)
+}
+
+// RateLimit
+func (h *TelegramHook) RateLimit() *RateLimit {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.rateLimit
+}
+
+// SetRateLimit enables pacing of messages, nil disables it.
+func (h *TelegramHook) SetRateLimit(limit *RateLimit) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.rateLimit = limit
+}
+
+// SharedRateLimit
+func (h *TelegramHook) SharedRateLimit() bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.sharedRateLimit
+}
+
+// SetSharedRateLimit makes the hook draw from a rate limit budget shared by
+// all hooks of the process that send through the same bot and opted in, so
+// several hooks pointing at different chats cannot exceed the limits of the bot
+// together during a broad incident. Retries draw from it as well. Without a
+// RateLimit the limits documented by Telegram apply.
+func (h *TelegramHook) SetSharedRateLimit(shared bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.sharedRateLimit = shared
+}
+
+// Acknowledgement
+func (h *TelegramHook) Acknowledgement() *Acknowledgement {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.ack
+}
+
+// SetAcknowledgement sets which alerts require acknowledgement, nil disables
+// it. The listener for button presses is only started by NewTelegramHook.
+func (h *TelegramHook) SetAcknowledgement(ack *Acknowledgement) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.ack = ack
+}
+
+// DNSCache
+func (h *TelegramHook) DNSCache() time.Duration {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.dnsRefresh
+}
+
+// SetDNSCache sets how long resolved addresses of the API host are cached, 0
+// disables caching. Transport settings only take effect in NewTelegramHook.
+func (h *TelegramHook) SetDNSCache(refresh time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.dnsRefresh = refresh
+}
+
+// IPPreference
+func (h *TelegramHook) IPPreference() IPPreference {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.ipPreference
+}
+
+// SetIPPreference sets which address family is tried first when connecting.
+// Transport settings only take effect in NewTelegramHook.
+func (h *TelegramHook) SetIPPreference(preference IPPreference) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.ipPreference = preference
+}
+
+// LocalAddr
+func (h *TelegramHook) LocalAddr() string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.localAddr
+}
+
+// SetLocalAddr sets the IP address or network interface outgoing connections
+// are bound to, "" for any. Transport settings only take effect in
+// NewTelegramHook.
+func (h *TelegramHook) SetLocalAddr(addr string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.localAddr = addr
+}
+
+// FirehoseCommand
+func (h *TelegramHook) FirehoseCommand() bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.firehoseCommand
+}
+
+// SetFirehoseCommand sets whether /firehose messages in the chat start and end
+// firehose mode. The listener for commands is only started by NewTelegramHook.
+func (h *TelegramHook) SetFirehoseCommand(enabled bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.firehoseCommand = enabled
+}
+
+// QueueSize returns the maximum number of queued messages and the policy applied when the queue is full.
+func (h *TelegramHook) QueueSize() (int, QueuePolicy) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.queueSize, h.queuePolicy
+}
+
+// SetQueueSize sets the maximum number of queued messages, zero leaves the
+// queue unbounded, and the policy applied when it is full.
+func (h *TelegramHook) SetQueueSize(n int, policy QueuePolicy) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.queueSize = n
+	h.queuePolicy = policy
+}
+
+// Workers
+func (h *TelegramHook) Workers() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.workers
+}
+
+// SetWorkers sets the number of workers delivering queued messages. Workers
+// are started with the first queued message, later changes have no effect.
+func (h *TelegramHook) SetWorkers(n int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.workers = n
+}
+
+// Locale
+func (h *TelegramHook) Locale() string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.localeTag
+}
+
+// SetLocale sets the language tag numbers, dates and times are formatted for
+func (h *TelegramHook) SetLocale(tag string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.localeTag = tag
+}
+
+// Timezone
+func (h *TelegramHook) Timezone() *time.Location {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.timezone
+}
+
+// SetTimezone sets the time zone times in messages are shown in, nil for the zone they were logged in
+func (h *TelegramHook) SetTimezone(loc *time.Location) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.timezone = loc
+}
+
+// ExitFlush
+func (h *TelegramHook) ExitFlush() time.Duration {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.exitFlush
+}
+
+// SetExitFlush sets how long the exit handler waits for queued messages
+func (h *TelegramHook) SetExitFlush(timeout time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.exitFlush = timeout
+}
+
+// AdaptiveBatching
+func (h *TelegramHook) AdaptiveBatching() *AdaptiveBatching {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.batching
+}
+
+// SetAdaptiveBatching enables batching during bursts, nil disables it
+func (h *TelegramHook) SetAdaptiveBatching(batching *AdaptiveBatching) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.batching = batching
+}
+
+// BatchInterval
+func (h *TelegramHook) BatchInterval() time.Duration {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.batchInterval
+}
+
+// SetBatchInterval sets how long messages are collected into one message, 0 disables batching
+func (h *TelegramHook) SetBatchInterval(d time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.batchInterval = d
+}
+
+// AlarmBot
+func (h *TelegramHook) AlarmBot() (string, logrus.Level) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.alarmToken, h.alarmLevel
+}
+
+// SetAlarmBot sets the bot entries at level or above are sent through, an
+// empty token sends all entries through the main bot.
+func (h *TelegramHook) SetAlarmBot(authToken string, level logrus.Level) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.alarmToken = authToken
+	h.alarmLevel = level
+}
+
+// Formatter
+func (h *TelegramHook) Formatter() Formatter {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.formatter
+}
+
+// SetFormatter sets the Formatter messages are rendered with, nil restores the built-in layout
+func (h *TelegramHook) SetFormatter(formatter Formatter) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.formatter = formatter
+}
+
+// CommonNoiseFilters
+func (h *TelegramHook) CommonNoiseFilters() bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.noiseFilters
+}
+
+// SetCommonNoiseFilters enables the built-in noise filters
+func (h *TelegramHook) SetCommonNoiseFilters(enabled bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.noiseFilters = enabled
+}
+
+// ConfigEcho returns the chat the configuration is echoed to on startup and whether it is enabled.
+func (h *TelegramHook) ConfigEcho() (ChatTarget, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.echoTarget, h.configEcho
+}
+
+// SetConfigEcho enables posting the redacted effective configuration to target,
+// the hook's chat if zero. The configuration is only posted by NewTelegramHook.
+func (h *TelegramHook) SetConfigEcho(target ChatTarget) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.configEcho = true
+	h.echoTarget = target
+}
+
+// ChatTarget returns the chat, topic and sending options of the hook.
+func (h *TelegramHook) ChatTarget() ChatTarget {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.target()
+}
+
+// SetChatTarget sets the chat, topic and sending options of the hook. A zero
+// ParseMode (ParseModeDefault) and a nil Formatter keep the current settings.
+func (h *TelegramHook) SetChatTarget(target ChatTarget) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	target.apply(&h.config)
+	h.silent = target.Silent
+}
+
+// Silent
+func (h *TelegramHook) Silent() bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.silent
+}
+
+// SetSilent sets whether messages are sent without notification sound. Members
+// of the chat still see them, but their phones stay quiet.
+func (h *TelegramHook) SetSilent(silent bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.silent = silent
+}
+
+// SilentLevels
+func (h *TelegramHook) SilentLevels() []logrus.Level {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return append([]logrus.Level(nil), h.silentLevels...)
+}
+
+// SetSilentLevels sets the levels whose entries are sent without notification
+// sound, e.g. Info and Debug, while more severe entries still notify. No
+// levels leave it to the Silent setting of the chat.
+func (h *TelegramHook) SetSilentLevels(levels ...logrus.Level) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.silentLevels = append([]logrus.Level(nil), levels...)
+}
+
+// SetTemplate parses tmpl and renders messages through it, replacing the
+// Formatter. The template is executed with TemplateData.
+func (h *TelegramHook) SetTemplate(tmpl string) error {
+	t, err := template.New("message").Parse(tmpl)
+	if err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.formatter = &templateFormatter{h: h, tmpl: t}
+	return nil
+}
+
+// FaultInjection
+func (h *TelegramHook) FaultInjection() *FaultInjection {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.faults
+}
+
+// SetFaultInjection enables simulated Telegram failures, nil disables them
+func (h *TelegramHook) SetFaultInjection(faults *FaultInjection) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.faults = faults
+}
+
+// ParseMode
+func (h *TelegramHook) ParseMode() ParseMode {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.parseMode
+}
+
+// SetParseMode sets the parse mode messages are sent with
+func (h *TelegramHook) SetParseMode(mode ParseMode) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.parseMode = mode
+}
+
+// ControlChars
+func (h *TelegramHook) ControlChars() ControlChars {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.controlChars
+}
+
+// SetControlChars sets what happens to ANSI escape sequences and control characters in entries
+func (h *TelegramHook) SetControlChars(mode ControlChars) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.controlChars = mode
+}
+
+// RawHTML
+func (h *TelegramHook) RawHTML() bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.rawHTML
+}
+
+// SetRawHTML sets whether log messages are inserted as HTML instead of being escaped
+func (h *TelegramHook) SetRawHTML(raw bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.rawHTML = raw
+}
+
+// PressureGate
+func (h *TelegramHook) PressureGate() *PressureGate {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.gate
+}
+
+// SetPressureGate sets the pressure gate, nil disables it
+func (h *TelegramHook) SetPressureGate(gate *PressureGate) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.gate = gate
+}
+
+// SetVolumeProfile applies the queue, worker and batching settings of profile.
+// Options given after WithVolumeProfile override individual settings. A
+// synchronous profile keeps the queue and worker settings, which only apply
+// to asynchronous delivery.
+func (h *TelegramHook) SetVolumeProfile(profile VolumeProfile) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.async = profile.Async
+	if profile.Async {
+		h.workers = profile.Workers
+		h.queueSize = profile.QueueSize
+		h.maxQueueBytes = profile.MaxQueueBytes
+	}
+	h.batching = nil
+	if profile.Batching != nil {
+		batching := *profile.Batching
+		h.batching = &batching
+	}
+}
+
+// SkipVerification
+func (h *TelegramHook) SkipVerification() bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.skipVerification
+}
+
+// SetSkipVerification sets whether the constructor skips checking the token with Telegram
+func (h *TelegramHook) SetSkipVerification(skip bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.skipVerification = skip
+}
+
+// Chats
+func (h *TelegramHook) Chats() []ChatTarget {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return append([]ChatTarget(nil), h.chats...)
+}
+
+// SetChats sets further targets every entry is sent to besides the chat of the
+// hook. With an empty chat ID the hook only sends to these targets.
+func (h *TelegramHook) SetChats(targets ...ChatTarget) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.chats = append([]ChatTarget(nil), targets...)
+}
+
+// LevelRouting
+func (h *TelegramHook) LevelRouting() map[logrus.Level]ChatTarget {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	routes := make(map[logrus.Level]ChatTarget, len(h.levelRoutes))
+	for level, target := range h.levelRoutes {
+		routes[level] = target
+	}
+	return routes
+}
+
+// SetLevelRouting sets the targets entries of a level are sent to, e.g. errors
+// to an on-call chat and warnings to a monitoring topic. Entries of other levels
+// go to the chat of the hook. nil removes all routes.
+func (h *TelegramHook) SetLevelRouting(routes map[logrus.Level]ChatTarget) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.levelRoutes = make(map[logrus.Level]ChatTarget, len(routes))
+	for level, target := range routes {
+		h.levelRoutes[level] = target
+	}
+}
+
+// FieldRouting
+func (h *TelegramHook) FieldRouting() (string, map[string]ChatTarget) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	routes := make(map[string]ChatTarget, len(h.fieldRoutes))
+	for value, target := range h.fieldRoutes {
+		routes[value] = target
+	}
+	return h.routeField, routes
+}
+
+// SetFieldRouting sets the targets entries are sent to by the value of field,
+// e.g. component=payments to the payments topic. Entries without a route go to
+// the chat of the hook. An empty field removes the routing.
+func (h *TelegramHook) SetFieldRouting(field string, routes map[string]ChatTarget) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.routeField = field
+	h.fieldRoutes = make(map[string]ChatTarget, len(routes))
+	for value, target := range routes {
+		h.fieldRoutes[value] = target
+	}
+}
+
+// DeliveryConfirmation
+func (h *TelegramHook) DeliveryConfirmation() time.Duration {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.confirmDelay
+}
+
+// SetDeliveryConfirmation sets how long after sending messages are checked to exist in the chat, 0 disables the check
+func (h *TelegramHook) SetDeliveryConfirmation(delay time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.confirmDelay = delay
+}
+
+// DedupWindow
+func (h *TelegramHook) DedupWindow() time.Duration {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.dedupWindow
+}
+
+// SetDedupWindow sets how long identical messages are suppressed, 0 disables deduplication
+func (h *TelegramHook) SetDedupWindow(window time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.dedupWindow = window
+}
+
+// LimitWarnings
+func (h *TelegramHook) LimitWarnings() *LimitWarnings {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.limitWarnings
+}
+
+// SetLimitWarnings reports when the send volume approaches Telegram's limits, nil disables the warnings
+func (h *TelegramHook) SetLimitWarnings(warnings *LimitWarnings) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.limitWarnings = warnings
+}
+
+// Archive
+func (h *TelegramHook) Archive() Blob {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.archive
+}
+
+// SetArchive sets the Blob delivered messages are archived in, nil disables archiving
+func (h *TelegramHook) SetArchive(blob Blob) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.archive = blob
+}
+
+// FloodSummary
+func (h *TelegramHook) FloodSummary() *FloodSummary {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.flood
+}
+
+// SetFloodSummary sends a periodic summary instead of individual messages during floods, nil disables it
+func (h *TelegramHook) SetFloodSummary(summary *FloodSummary) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.flood = summary
+}
+
+// Coalescing
+func (h *TelegramHook) Coalescing() *Coalescing {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.coalesce
+}
+
+// SetCoalescing coalesces bursts of entries with the same signature into their first and latest entry, nil disables it
+func (h *TelegramHook) SetCoalescing(coalescing *Coalescing) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.coalesce = coalescing
 }
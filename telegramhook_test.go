@@ -1,9 +1,16 @@
 package telegramhook
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
+	"io"
+	"net/http"
 	"os"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	log "github.com/andoma-go/logrus"
 )
@@ -32,3 +39,290 @@ func TestNewTelegramHook(t *testing.T) {
 		"html":   "<b>bold</b>",
 	}).Errorf("A walrus appears")
 }
+
+func newTestHook(options ...Option) *TelegramHook {
+	h := &TelegramHook{
+		config: config{
+			appName:        "testing",
+			level:          log.ErrorLevel,
+			headlineFields: 3,
+		},
+	}
+	for _, opt := range options {
+		opt(h)
+	}
+	return h
+}
+
+func createMessage(h *TelegramHook, entry *log.Entry) string {
+	cfg := h.snapshot()
+	return cfg.createMessage(entry)
+}
+
+func createFieldsDocument(h *TelegramHook, entry *log.Entry) *document {
+	cfg := h.snapshot()
+	return cfg.createFieldsDocument(entry)
+}
+
+func TestCreateMessageEmptyHeadline(t *testing.T) {
+	h := newTestHook(WithHeadlineFields(2))
+
+	msg := createMessage(h, &log.Entry{
+		Level: log.ErrorLevel,
+		Data:  log.Fields{"b": 2, "a": "<1>", "c": 3},
+	})
+	if !strings.HasPrefix(msg, "<b>ERROR</b>@testing - a=&lt;1&gt;, b=2\n") {
+		t.Errorf("Unexpected headline for entry without message: %q", msg)
+	}
+
+	msg = createMessage(h, &log.Entry{
+		Level: log.ErrorLevel,
+		Data:  log.Fields{"a": 1, log.ErrorKey: errors.New("boom")},
+	})
+	if !strings.HasPrefix(msg, "<b>ERROR</b>@testing - boom\n") {
+		t.Errorf("Error field not used as headline: %q", msg)
+	}
+}
+
+func TestFireSkipEmpty(t *testing.T) {
+	h := newTestHook(WithSkipEmpty(true))
+
+	if err := h.Fire(&log.Entry{Level: log.ErrorLevel}); err != nil {
+		t.Errorf("Empty entry was not skipped: %s", err)
+	}
+}
+
+func TestLevelProvider(t *testing.T) {
+	api := &fakeAPI{}
+	level := log.ErrorLevel
+	h := newTestHook(WithLevelProvider(LevelProviderFunc(func() log.Level { return level })))
+	h.client = api.client()
+
+	for _, threshold := range []log.Level{log.ErrorLevel, log.InfoLevel} {
+		level = threshold
+		if err := h.Fire(&log.Entry{Level: log.InfoLevel, Message: "m"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := len(api.methods()); n != 1 {
+		t.Errorf("Expected the entry to be sent only once the provider lowered the level, got %d messages", n)
+	}
+}
+
+func TestCreateMessageErrorKeyPromotion(t *testing.T) {
+	h := newTestHook(WithErrorKeyPromotion(true))
+
+	msg := createMessage(h, &log.Entry{
+		Level:   log.ErrorLevel,
+		Message: "request failed",
+		Data:    log.Fields{log.ErrorKey: errors.New("<timeout>")},
+	})
+	if msg != "<b>ERROR</b>@testing - request failed: &lt;timeout&gt;" {
+		t.Errorf("Error was not promoted into headline: %q", msg)
+	}
+}
+
+func TestCreateMessageMaxFields(t *testing.T) {
+	h := newTestHook(WithMaxFields(2), WithFieldsDocument(true))
+	entry := &log.Entry{
+		Level:   log.ErrorLevel,
+		Message: "m",
+		Data:    log.Fields{"c": 3, "b": 2, "a": 1, log.ErrorKey: "e"},
+	}
+
+	want := "<b>ERROR</b>@testing - m\n<pre>\n\terror: e\n\ta: 1\n</pre>\n<i>… and 2 more fields</i>"
+	if msg := createMessage(h, entry); msg != want {
+		t.Errorf("createMessage() = %q, want %q", msg, want)
+	}
+
+	doc := createFieldsDocument(h, entry)
+	if doc == nil || string(doc.content) != "b: 2\nc: 3\n" {
+		t.Errorf("Expected the folded fields in the document, got %+v", doc)
+	}
+
+	// The promoted error field is in the headline, so nothing is folded.
+	h.SetErrorKeyPromotion(true)
+	entry.Data = log.Fields{"b": 2, "a": 1, log.ErrorKey: "e"}
+	if doc := createFieldsDocument(h, entry); doc != nil {
+		t.Errorf("Expected no fields document without folded fields, got %q", doc.content)
+	}
+}
+
+func TestFieldOrder(t *testing.T) {
+	h := newTestHook(WithFieldOrder([]string{"request_id", "missing", "user"}), WithHiddenFields([]string{"trace"}))
+	entry := &log.Entry{
+		Level:   log.ErrorLevel,
+		Message: "m",
+		Data:    log.Fields{"user": "bob", "b": 2, "a": 1, "request_id": "r-1", "trace": "noise", log.ErrorKey: "e"},
+	}
+
+	cfg := h.snapshot()
+	msg, _ := cfg.renderFor(entry, cfg.chatId)
+	want := "<b>ERROR</b>@testing - m\n<pre>\n\trequest_id: r-1\n\tuser: bob\n\terror: e\n\ta: 1\n\tb: 2\n</pre>"
+	if msg != want {
+		t.Errorf("Rendered %q, want %q", msg, want)
+	}
+	if _, ok := entry.Data["trace"]; !ok {
+		t.Error("Hiding fields modified the entry")
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func TestFireSoftFail(t *testing.T) {
+	h := newTestHook(WithSoftFail(true))
+	h.client = &http.Client{Transport: roundTripFunc(func(*http.Request) (*http.Response, error) {
+		return nil, errors.New("unreachable")
+	})}
+
+	if err := h.Fire(&log.Entry{Level: log.ErrorLevel, Message: "m"}); err != nil {
+		t.Errorf("Fire returned an error in soft-fail mode: %s", err)
+	}
+}
+
+func TestErrorHandler(t *testing.T) {
+	type failure struct {
+		entry *log.Entry
+		err   error
+	}
+	failures := make(chan failure, 1)
+	h := newTestHook(WithAsync(true), WithErrorHandler(func(entry *log.Entry, err error) {
+		failures <- failure{entry, err}
+	}))
+	h.client = &http.Client{Transport: roundTripFunc(func(*http.Request) (*http.Response, error) {
+		return nil, errors.New("unreachable")
+	})}
+
+	entry := &log.Entry{Level: log.ErrorLevel, Message: "m"}
+	if err := h.Fire(entry); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case f := <-failures:
+		if f.entry != entry || !strings.Contains(f.err.Error(), "unreachable") {
+			t.Errorf("Expected the failed entry and its error, got %v and %v", f.entry, f.err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the failure to be passed to the error handler")
+	}
+}
+
+func TestUserAgent(t *testing.T) {
+	var got []string
+	client := &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		got = append(got, r.Header.Get("User-Agent"))
+		return jsonResponse(200, `{"ok":true,"result":{}}`), nil
+	})}
+
+	if _, err := NewTelegramHookWithClient("app", "123:abc", "1", "", client); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewTelegramHookWithClient("app", "123:abc", "1", "", client, WithUserAgentVersion(false)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewTelegramHookWithClient("app", "123:abc", "1", "", client, WithUserAgent("custom/1")); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"logrus-hook-telegram/" + Version, "", "custom/1"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("User-Agent headers = %q, want %q", got, want)
+	}
+}
+
+// fakeAPI records Bot API calls and answers them successfully unless respond
+// returns an error response.
+type fakeAPI struct {
+	mu      sync.Mutex
+	calls   []fakeCall
+	respond func(method string, body []byte) *http.Response
+}
+
+type fakeCall struct {
+	method string
+	body   []byte
+}
+
+func (f *fakeAPI) client() *http.Client {
+	return &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		var body []byte
+		if r.Body != nil {
+			body, _ = io.ReadAll(r.Body)
+		}
+		method := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+
+		f.mu.Lock()
+		f.calls = append(f.calls, fakeCall{method: method, body: body})
+		respond := f.respond
+		f.mu.Unlock()
+
+		if respond != nil {
+			if res := respond(method, body); res != nil {
+				return res, nil
+			}
+		}
+		return jsonResponse(200, `{"ok":true,"result":{"message_id":1}}`), nil
+	})}
+}
+
+func (f *fakeAPI) methods() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	methods := make([]string, 0, len(f.calls))
+	for _, c := range f.calls {
+		methods = append(methods, c.method)
+	}
+	return methods
+}
+
+func (f *fakeAPI) texts() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var texts []string
+	for _, c := range f.calls {
+		var req struct {
+			Text string `json:"text"`
+		}
+		if json.Unmarshal(c.body, &req) == nil && req.Text != "" {
+			texts = append(texts, req.Text)
+		}
+	}
+	return texts
+}
+
+func TestFireContext(t *testing.T) {
+	var got context.Context
+	h := newTestHook()
+	h.client = &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		got = r.Context()
+		return jsonResponse(http.StatusOK, `{"ok":true,"result":{"message_id":1}}`), nil
+	})}
+
+	type ctxKey struct{}
+	ctx := context.WithValue(context.Background(), ctxKey{}, "request")
+	entry := &log.Entry{Level: log.ErrorLevel, Message: "boom"}
+
+	if err := h.FireContext(ctx, entry); err != nil {
+		t.Fatal(err)
+	}
+	if got == nil || got.Value(ctxKey{}) != "request" {
+		t.Error("Request was not sent with the context passed to FireContext")
+	}
+	if entry.Context != nil {
+		t.Error("FireContext modified the entry")
+	}
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	h.client = &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		return nil, r.Context().Err()
+	})}
+	if err := h.Fire(entry.WithContext(cancelled)); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the context of the entry to cancel the request, got %v", err)
+	}
+}
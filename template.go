@@ -0,0 +1,55 @@
+package telegramhook
+
+import (
+	"fmt"
+	"html"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/andoma-go/logrus"
+)
+
+// TemplateData is passed to templates set with WithTemplate. Strings are HTML
+// escaped, so templates can add formatting tags around them. The message is
+// passed as it is with WithRawHTML.
+type TemplateData struct {
+	Level   string // upper case, e.g. "ERROR"
+	AppName string
+	Message string
+	Fields  map[string]string
+	Time    time.Time
+	Caller  string // function and file:line, empty unless logrus reports callers
+}
+
+// templateFormatter renders entries through a text/template.
+type templateFormatter struct {
+	h    *TelegramHook
+	tmpl *template.Template
+}
+
+// Format implements Formatter.
+func (f *templateFormatter) Format(entry *logrus.Entry) (string, error) {
+	data := TemplateData{
+		Level:   strings.ToUpper(entry.Level.String()),
+		AppName: html.EscapeString(f.h.AppName()),
+		Message: html.EscapeString(entry.Message),
+		Fields:  make(map[string]string, len(entry.Data)),
+		Time:    entry.Time,
+	}
+	if f.h.RawHTML() {
+		data.Message = entry.Message
+	}
+	for k, v := range entry.Data {
+		data.Fields[k] = html.EscapeString(formatValue(v))
+	}
+	if entry.Caller != nil {
+		data.Caller = html.EscapeString(fmt.Sprintf("%s %s:%d", entry.Caller.Function, entry.Caller.File, entry.Caller.Line))
+	}
+
+	var b strings.Builder
+	if err := f.tmpl.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,39 @@
+package telegramhook
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	log "github.com/andoma-go/logrus"
+)
+
+func TestTemplate(t *testing.T) {
+	h := newTestHook(WithTemplate(`{{.Level}} <b>{{.AppName}}</b> {{.Time.Format "15:04"}}: {{.Message}}{{range $k, $v := .Fields}} {{$k}}={{$v}}{{end}}`))
+	cfg := h.snapshot()
+
+	entry := &log.Entry{
+		Level:   log.ErrorLevel,
+		Message: "x < y",
+		Time:    time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Data:    log.Fields{"user": "<admin>", "id": 7},
+	}
+	msg, _ := cfg.renderFor(entry, cfg.chatId)
+	if want := "ERROR <b>testing</b> 03:04: x &lt; y id=7 user=&lt;admin&gt;"; msg != want {
+		t.Errorf("Rendered %q, want %q", msg, want)
+	}
+}
+
+func TestTemplateInvalid(t *testing.T) {
+	var cerr *ConfigError
+	_, err := NewTelegramHookWithClient("app", "123:abc", "1", "", (&fakeAPI{}).client(), WithTemplate("{{.Message"))
+	if !errors.As(err, &cerr) || cerr.Field != "template" {
+		t.Errorf("Expected a template ConfigError, got %v", err)
+	}
+
+	h := newTestHook()
+	if err := h.SetTemplate("{{end}}"); err == nil || !strings.Contains(err.Error(), "unexpected") {
+		t.Errorf("Expected a parse error, got %v", err)
+	}
+}
@@ -0,0 +1,271 @@
+package telegramhook
+
+import (
+	"encoding/json"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/andoma-go/logrus"
+)
+
+// TopicMode selects how forum topics are created automatically.
+type TopicMode int
+
+const (
+	// TopicsOff sends all messages to the configured thread.
+	TopicsOff TopicMode = iota
+	// TopicPerApp creates one topic named after the app.
+	TopicPerApp
+	// TopicPerSignature creates one topic per error signature; entries less
+	// severe than errors go to the configured thread.
+	TopicPerSignature
+)
+
+func (m TopicMode) String() string {
+	switch m {
+	case TopicPerApp:
+		return "per-app"
+	case TopicPerSignature:
+		return "per-signature"
+	}
+	return "off"
+}
+
+const (
+	// maxTopicName is the Telegram limit for the name of a forum topic.
+	maxTopicName = 128
+	// maxAutoTopics bounds the number of topics created by a hook, further
+	// messages go to the configured thread.
+	maxAutoTopics = 100
+	// topicRetryDelay is how long messages of a topic that could not be
+	// created go to the configured thread before creating it is tried again.
+	topicRetryDelay = time.Minute
+)
+
+// forumTopics caches the automatically created topics by chat and name.
+type forumTopics struct {
+	mu     sync.Mutex
+	topics map[string]*forumTopic
+	// calls holds the topics with a create, reopen or close call in flight,
+	// the channel is closed when the call returned.
+	calls map[string]chan struct{}
+	// failed holds when topics that could not be created are tried again.
+	failed map[string]time.Time
+}
+
+// forumTopic is an automatically created topic.
+type forumTopic struct {
+	chatId   string
+	thread   string
+	lastSeen time.Time
+	closed   bool
+}
+
+// topicRequest is the payload of closeForumTopic and reopenForumTopic.
+type topicRequest struct {
+	ChatId          string `json:"chat_id"`
+	MessageThreadId string `json:"message_thread_id"`
+}
+
+// createForumTopicRequest is the payload of createForumTopic.
+type createForumTopicRequest struct {
+	ChatId string `json:"chat_id"`
+	Name   string `json:"name"`
+}
+
+// apiForumTopic is the part of a created forum topic that the hook uses.
+type apiForumTopic struct {
+	MessageThreadId int64 `json:"message_thread_id"`
+}
+
+// topicName returns the name of the topic entry is routed to, empty when it
+// goes to the configured thread.
+func (c *config) topicName(entry *logrus.Entry) string {
+	switch c.autoTopics {
+	case TopicPerApp:
+		return truncateText(c.appName, maxTopicName)
+	case TopicPerSignature:
+		if entry.Level <= logrus.ErrorLevel {
+			return truncateText(c.signature(entry), maxTopicName)
+		}
+	}
+	return ""
+}
+
+// topicThread returns the thread of the topic with the given name, creating
+// the topic on first use and reopening it when it was archived. When the topic
+// cannot be created the configured thread is used for a while. The topics are
+// not locked during API calls; messages of a topic that is being created,
+// reopened or closed wait for the call.
+func (h *TelegramHook) topicThread(cfg config, name string) string {
+	t := &h.topics
+	key := cfg.chatId + "\x00" + name
+
+	t.mu.Lock()
+	for t.calls[key] != nil {
+		call := t.calls[key]
+		t.mu.Unlock()
+		<-call
+		t.mu.Lock()
+	}
+
+	if topic, ok := t.topics[key]; ok {
+		topic.lastSeen = time.Now()
+		if !topic.closed {
+			t.mu.Unlock()
+			return topic.thread
+		}
+		done := t.startCall(key)
+		t.mu.Unlock()
+
+		err := h.callTopic(cfg, "reopenForumTopic", topic)
+
+		t.mu.Lock()
+		if err == nil {
+			topic.closed = false
+		}
+		t.endCall(key, done)
+		t.mu.Unlock()
+		if err != nil {
+			h.handleError(err)
+		}
+		return topic.thread
+	}
+
+	if len(t.topics)+len(t.calls) >= maxAutoTopics || time.Now().Before(t.failed[key]) {
+		t.mu.Unlock()
+		return cfg.threadId
+	}
+	done := t.startCall(key)
+	t.mu.Unlock()
+
+	result, err := h.callJSON(cfg, "createForumTopic", createForumTopicRequest{
+		ChatId: cfg.chatId,
+		Name:   name,
+	})
+	var topic apiForumTopic
+	if err == nil {
+		err = json.Unmarshal(result, &topic)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	defer t.endCall(key, done)
+
+	if err != nil {
+		t.fail(key)
+		h.handleError(err)
+		return cfg.threadId
+	}
+
+	if t.topics == nil {
+		t.topics = map[string]*forumTopic{}
+	}
+	delete(t.failed, key)
+	thread := strconv.FormatInt(topic.MessageThreadId, 10)
+	t.topics[key] = &forumTopic{chatId: cfg.chatId, thread: thread, lastSeen: time.Now()}
+	return thread
+}
+
+// startCall marks a call for the topic key as in flight. The caller must hold
+// the lock.
+func (t *forumTopics) startCall(key string) chan struct{} {
+	if t.calls == nil {
+		t.calls = map[string]chan struct{}{}
+	}
+	done := make(chan struct{})
+	t.calls[key] = done
+	return done
+}
+
+// endCall releases the messages waiting for the call for the topic key. The
+// caller must hold the lock.
+func (t *forumTopics) endCall(key string, done chan struct{}) {
+	delete(t.calls, key)
+	close(done)
+}
+
+// fail remembers that the topic key could not be created, dropping expired
+// failures. The caller must hold the lock.
+func (t *forumTopics) fail(key string) {
+	now := time.Now()
+	for k, retry := range t.failed {
+		if now.After(retry) {
+			delete(t.failed, k)
+		}
+	}
+	if t.failed == nil {
+		t.failed = map[string]time.Time{}
+	}
+	t.failed[key] = now.Add(topicRetryDelay)
+}
+
+// startTopicArchiver periodically closes topics that had no messages for the
+// configured idle period, mirroring resolved incidents in the chat.
+func (h *TelegramHook) startTopicArchiver() {
+	idle := h.TopicArchive()
+	if idle <= 0 {
+		return
+	}
+
+	interval := idle / 4
+	if interval < time.Second {
+		interval = time.Second
+	}
+
+	ticker := time.NewTicker(interval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-h.done:
+				return
+			case <-ticker.C:
+				h.archiveTopics(h.snapshot())
+			}
+		}
+	}()
+}
+
+// archiveTopics closes open topics that were idle for longer than configured.
+func (h *TelegramHook) archiveTopics(cfg config) {
+	if cfg.topicArchive <= 0 {
+		return
+	}
+
+	t := &h.topics
+	t.mu.Lock()
+	idle := map[string]*forumTopic{}
+	calls := map[string]chan struct{}{}
+	for key, topic := range t.topics {
+		if topic.closed || t.calls[key] != nil || time.Since(topic.lastSeen) < cfg.topicArchive {
+			continue
+		}
+		idle[key], calls[key] = topic, t.startCall(key)
+	}
+	t.mu.Unlock()
+
+	for key, topic := range idle {
+		err := h.callTopic(cfg, "closeForumTopic", topic)
+
+		t.mu.Lock()
+		if err == nil {
+			topic.closed = true
+		}
+		t.endCall(key, calls[key])
+		t.mu.Unlock()
+		if err != nil {
+			h.handleError(err)
+		}
+	}
+}
+
+// callTopic issues a Bot API method that acts on topic.
+func (h *TelegramHook) callTopic(cfg config, method string, topic *forumTopic) error {
+	_, err := h.callJSON(cfg, method, topicRequest{
+		ChatId:          topic.chatId,
+		MessageThreadId: topic.thread,
+	})
+	return err
+}
@@ -0,0 +1,110 @@
+package telegramhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	log "github.com/andoma-go/logrus"
+)
+
+func TestAutoTopics(t *testing.T) {
+	next := 76
+	api := &fakeAPI{respond: func(method string, body []byte) *http.Response {
+		if method == "createForumTopic" {
+			next++
+			return jsonResponse(http.StatusOK, fmt.Sprintf(`{"ok":true,"result":{"message_thread_id":%d,"name":"x"}}`, next))
+		}
+		return nil
+	}}
+	h := newTestHook(WithAutoTopics(TopicPerSignature), WithLevel(log.WarnLevel))
+	h.threadId = "1"
+	h.client = api.client()
+
+	for _, e := range []*log.Entry{
+		{Level: log.ErrorLevel, Message: "db down"},
+		{Level: log.ErrorLevel, Message: "cache down"},
+		{Level: log.ErrorLevel, Message: "db down"},
+		{Level: log.WarnLevel, Message: "slow"},
+	} {
+		if err := h.Fire(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var threads []string
+	api.mu.Lock()
+	for _, c := range api.calls {
+		if c.method == "sendMessage" {
+			var req apiRequest
+			if err := json.Unmarshal(c.body, &req); err != nil {
+				t.Fatal(err)
+			}
+			threads = append(threads, req.ThreadId)
+		}
+	}
+	api.mu.Unlock()
+
+	if want := []string{"77", "78", "77", "1"}; !reflect.DeepEqual(threads, want) {
+		t.Errorf("Messages sent to threads %v, want %v", threads, want)
+	}
+}
+
+func TestAutoTopicsUnsupported(t *testing.T) {
+	api := &fakeAPI{respond: func(method string, body []byte) *http.Response {
+		if method == "createForumTopic" {
+			return jsonResponse(http.StatusBadRequest, `{"ok":false,"error_code":400,"description":"Bad Request: the chat is not a forum"}`)
+		}
+		return nil
+	}}
+	h := newTestHook(WithAutoTopics(TopicPerApp))
+	h.client = api.client()
+
+	for i := 0; i < 2; i++ {
+		if err := h.Fire(&log.Entry{Level: log.ErrorLevel, Message: "db down"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := api.texts(); len(got) != 2 {
+		t.Errorf("Expected the messages to be sent to the configured thread, got %q", got)
+	}
+	if got := strings.Count(strings.Join(api.methods(), " "), "createForumTopic"); got != 1 {
+		t.Errorf("Expected the failed create not to be retried right away, got %d calls", got)
+	}
+}
+
+func TestTopicArchive(t *testing.T) {
+	api := &fakeAPI{respond: func(method string, body []byte) *http.Response {
+		if method == "createForumTopic" {
+			return jsonResponse(http.StatusOK, `{"ok":true,"result":{"message_thread_id":77,"name":"x"}}`)
+		}
+		return nil
+	}}
+	h := newTestHook(WithAutoTopics(TopicPerSignature), WithTopicArchive(time.Hour))
+	h.client = api.client()
+
+	entry := &log.Entry{Level: log.ErrorLevel, Message: "db down"}
+	if err := h.Fire(entry); err != nil {
+		t.Fatal(err)
+	}
+
+	h.archiveTopics(h.snapshot())
+	for _, topic := range h.topics.topics {
+		topic.lastSeen = time.Now().Add(-2 * time.Hour)
+	}
+	h.archiveTopics(h.snapshot())
+	h.archiveTopics(h.snapshot())
+
+	if err := h.Fire(entry); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"createForumTopic", "sendMessage", "closeForumTopic", "reopenForumTopic", "sendMessage"}
+	if got := api.methods(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Unexpected calls %v, want %v", got, want)
+	}
+}
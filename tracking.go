@@ -0,0 +1,242 @@
+package telegramhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/andoma-go/logrus"
+)
+
+// CorrelationKey is the field that associates sent messages with a key, so
+// they can later be retracted or edited together.
+const CorrelationKey = "correlation_key"
+
+// maxTrackedKeys bounds the number of correlation keys whose messages are
+// remembered; the oldest keys are forgotten first.
+const maxTrackedKeys = 1000
+
+// sentMessage identifies a message sent by the hook and the token of the bot
+// that sent it, which is the only bot allowed to delete it.
+type sentMessage struct {
+	authToken string
+	chatId    string
+	messageId int64
+}
+
+// sentMessages remembers the messages sent for each correlation key and the
+// messages the hook deleted.
+type sentMessages struct {
+	mu    sync.Mutex
+	byKey map[string][]sentMessage
+	order []string
+
+	deleted      map[sentMessage]bool // keyed without the token
+	deletedOrder []sentMessage
+}
+
+// track records the messages sent to chatId by the bot with authToken under
+// key.
+func (s *sentMessages) track(key, authToken, chatId string, ids []int64) {
+	if key == "" || len(ids) == 0 {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.byKey == nil {
+		s.byKey = map[string][]sentMessage{}
+	}
+	if _, ok := s.byKey[key]; !ok {
+		s.order = append(s.order, key)
+	}
+	for _, id := range ids {
+		s.byKey[key] = append(s.byKey[key], sentMessage{authToken: authToken, chatId: chatId, messageId: id})
+	}
+
+	for len(s.order) > maxTrackedKeys {
+		delete(s.byKey, s.order[0])
+		s.order = s.order[1:]
+	}
+}
+
+// take returns and forgets the messages sent under key.
+func (s *sentMessages) take(key string) []sentMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	msgs, ok := s.byKey[key]
+	if !ok {
+		return nil
+	}
+	delete(s.byKey, key)
+	for i, k := range s.order {
+		if k == key {
+			s.order = append(s.order[:i], s.order[i+1:]...)
+			break
+		}
+	}
+	return msgs
+}
+
+// markDeleted remembers that the message with id was deleted from chatId, so
+// it is not sent again by delivery confirmation.
+func (s *sentMessages) markDeleted(chatId string, id int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m := sentMessage{chatId: chatId, messageId: id}
+	if s.deleted == nil {
+		s.deleted = map[sentMessage]bool{}
+	}
+	if !s.deleted[m] {
+		s.deleted[m] = true
+		s.deletedOrder = append(s.deletedOrder, m)
+	}
+	for len(s.deletedOrder) > maxTrackedKeys {
+		delete(s.deleted, s.deletedOrder[0])
+		s.deletedOrder = s.deletedOrder[1:]
+	}
+}
+
+// wasDeleted reports whether the hook deleted the message with id from chatId.
+func (s *sentMessages) wasDeleted(chatId string, id int64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.deleted[sentMessage{chatId: chatId, messageId: id}]
+}
+
+// maxRecentAlerts bounds the number of sent alerts that are remembered for
+// RecentAlerts; the oldest are forgotten first.
+const maxRecentAlerts = 1000
+
+// Alert is a message sent by the hook, see RecentAlerts.
+type Alert struct {
+	ChatId     string
+	ThreadId   string
+	MessageIds []int64
+	Level      logrus.Level
+	// Signature groups alerts about the same problem, see
+	// WithSignatureNormalizers. It is empty for combined messages.
+	Signature string
+	// Key is the correlation key, see CorrelationKey.
+	Key  string
+	Text string
+	Sent time.Time
+}
+
+// AlertFilter selects alerts. Zero fields match all alerts.
+type AlertFilter struct {
+	Levels    []logrus.Level
+	Signature string
+	Since     time.Time
+	Until     time.Time
+}
+
+// match reports whether a passes the filter.
+func (f AlertFilter) match(a Alert) bool {
+	if len(f.Levels) > 0 {
+		found := false
+		for _, level := range f.Levels {
+			found = found || level == a.Level
+		}
+		if !found {
+			return false
+		}
+	}
+	if f.Signature != "" && f.Signature != a.Signature {
+		return false
+	}
+	if !f.Since.IsZero() && a.Sent.Before(f.Since) {
+		return false
+	}
+	return f.Until.IsZero() || a.Sent.Before(f.Until)
+}
+
+// recentAlerts remembers the latest alerts sent by the hook.
+type recentAlerts struct {
+	mu     sync.Mutex
+	alerts []Alert // oldest first
+}
+
+// add remembers a, forgetting the oldest alert when the store is full.
+func (r *recentAlerts) add(a Alert) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.alerts = append(r.alerts, a)
+	if len(r.alerts) > maxRecentAlerts {
+		r.alerts = append(r.alerts[:0], r.alerts[len(r.alerts)-maxRecentAlerts:]...)
+	}
+}
+
+// RecentAlerts returns the latest alerts sent by the hook that match filter,
+// newest first, e.g. to show the current alerts on a dashboard. Only the last
+// 1000 alerts are remembered.
+func (h *TelegramHook) RecentAlerts(filter AlertFilter) []Alert {
+	r := &h.recent
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var alerts []Alert
+	for i := len(r.alerts) - 1; i >= 0; i-- {
+		if a := r.alerts[i]; filter.match(a) {
+			a.MessageIds = append([]int64(nil), a.MessageIds...)
+			alerts = append(alerts, a)
+		}
+	}
+	return alerts
+}
+
+// correlationKey returns the correlation key of entry, empty if it has none.
+func correlationKey(entry *logrus.Entry) string {
+	v, ok := entry.Data[CorrelationKey]
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+// deleteMessageRequest is the payload of deleteMessage.
+type deleteMessageRequest struct {
+	ChatId    string `json:"chat_id"`
+	MessageId int64  `json:"message_id"`
+}
+
+// DeleteMessage deletes a message previously sent to chatId by the main bot.
+func (h *TelegramHook) DeleteMessage(ctx context.Context, chatId string, messageId int64) error {
+	return h.deleteMessage(ctx, sentMessage{chatId: chatId, messageId: messageId})
+}
+
+// deleteMessage deletes m using the bot that sent it, the main bot if its
+// token is empty.
+func (h *TelegramHook) deleteMessage(ctx context.Context, m sentMessage) error {
+	cfg := h.snapshot()
+	if m.authToken != "" {
+		cfg.authToken = m.authToken
+	}
+	_, err := h.callJSONContext(ctx, cfg, "deleteMessage", deleteMessageRequest{
+		ChatId:    m.chatId,
+		MessageId: m.messageId,
+	})
+	if err == nil {
+		h.sent.markDeleted(m.chatId, m.messageId)
+	}
+	return err
+}
+
+// Retract deletes all messages sent for entries with the given correlation
+// key, e.g. alerts that turned out to be false positives. Only the most recent
+// keys are remembered, see CorrelationKey.
+func (h *TelegramHook) Retract(key string) error {
+	var errs []error
+	for _, m := range h.sent.take(key) {
+		if err := h.deleteMessage(context.Background(), m); err != nil {
+			errs = append(errs, fmt.Errorf("message %d in chat %s: %w", m.messageId, m.chatId, err))
+		}
+	}
+	return errors.Join(errs...)
+}
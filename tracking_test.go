@@ -0,0 +1,150 @@
+package telegramhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	log "github.com/andoma-go/logrus"
+)
+
+func TestRetract(t *testing.T) {
+	next := int64(0)
+	api := &fakeAPI{respond: func(method string, body []byte) *http.Response {
+		if method == "sendMessage" {
+			next++
+			return jsonResponse(http.StatusOK, fmt.Sprintf(`{"ok":true,"result":{"message_id":%d}}`, next))
+		}
+		return nil
+	}}
+	h := newTestHook()
+	h.chatId = "42"
+	h.client = api.client()
+
+	for _, key := range []string{"db-down", "other", "db-down"} {
+		if err := h.Fire(&log.Entry{Level: log.ErrorLevel, Message: "alert", Data: log.Fields{CorrelationKey: key}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := h.Retract("db-down"); err != nil {
+		t.Fatal(err)
+	}
+
+	var deleted []deleteMessageRequest
+	api.mu.Lock()
+	for _, c := range api.calls {
+		if c.method == "deleteMessage" {
+			var req deleteMessageRequest
+			if err := json.Unmarshal(c.body, &req); err != nil {
+				t.Fatal(err)
+			}
+			deleted = append(deleted, req)
+		}
+	}
+	api.mu.Unlock()
+
+	want := []deleteMessageRequest{{ChatId: "42", MessageId: 1}, {ChatId: "42", MessageId: 3}}
+	if !reflect.DeepEqual(deleted, want) {
+		t.Errorf("Deleted %v, want %v", deleted, want)
+	}
+
+	if err := h.Retract("db-down"); err != nil {
+		t.Errorf("Expected retracting a key twice to do nothing, got %v", err)
+	}
+}
+
+func TestSentMessagesBounded(t *testing.T) {
+	var s sentMessages
+	for i := 0; i < maxTrackedKeys+10; i++ {
+		s.track(fmt.Sprint(i), "1:main", "42", []int64{int64(i)})
+	}
+
+	if got := s.take("0"); got != nil {
+		t.Errorf("Expected the oldest key to be forgotten, got %v", got)
+	}
+	if got := s.take(fmt.Sprint(maxTrackedKeys + 9)); len(got) != 1 {
+		t.Errorf("Expected the newest key to be tracked, got %v", got)
+	}
+}
+
+func TestRetractIsolatedBetweenHooks(t *testing.T) {
+	api := &fakeAPI{}
+	app1, app2 := newTestHook(), newTestHook()
+	app1.chatId, app2.chatId = "42", "42"
+	app1.client, app2.client = api.client(), api.client()
+
+	entry := &log.Entry{Level: log.ErrorLevel, Message: "alert", Data: log.Fields{CorrelationKey: "db-down"}}
+	if err := app2.Fire(entry); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := app1.Retract("db-down"); err != nil {
+		t.Fatal(err)
+	}
+	if got := api.methods(); !reflect.DeepEqual(got, []string{"sendMessage"}) {
+		t.Errorf("Expected one app not to retract another app's message, got calls %v", got)
+	}
+}
+
+func TestRecentAlerts(t *testing.T) {
+	api := &fakeAPI{}
+	h := newTestHook(WithLevel(log.WarnLevel))
+	h.chatId = "42"
+	h.client = api.client()
+
+	start := time.Now()
+	for _, entry := range []*log.Entry{
+		{Level: log.ErrorLevel, Message: "disk full", Data: log.Fields{CorrelationKey: "disk"}},
+		{Level: log.WarnLevel, Message: "slow query"},
+		{Level: log.ErrorLevel, Message: "disk full"},
+	} {
+		if err := h.Fire(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	all := h.RecentAlerts(AlertFilter{})
+	if len(all) != 3 {
+		t.Fatalf("Expected 3 alerts, got %d", len(all))
+	}
+	if all[0].Key != "" || all[2].Key != "disk" {
+		t.Errorf("Expected the newest alert first, got keys %q and %q", all[0].Key, all[2].Key)
+	}
+	if a := all[2]; a.ChatId != "42" || !reflect.DeepEqual(a.MessageIds, []int64{1}) || a.Level != log.ErrorLevel ||
+		a.Signature != "error: disk full" || !strings.Contains(a.Text, "disk full") || a.Sent.Before(start) {
+		t.Errorf("Unexpected alert %+v", a)
+	}
+
+	tests := []struct {
+		filter AlertFilter
+		want   int
+	}{
+		{AlertFilter{Levels: []log.Level{log.WarnLevel}}, 1},
+		{AlertFilter{Levels: []log.Level{log.PanicLevel, log.ErrorLevel}}, 2},
+		{AlertFilter{Signature: "error: disk full"}, 2},
+		{AlertFilter{Since: start}, 3},
+		{AlertFilter{Since: time.Now().Add(time.Minute)}, 0},
+		{AlertFilter{Until: start}, 0},
+	}
+	for _, test := range tests {
+		if got := h.RecentAlerts(test.filter); len(got) != test.want {
+			t.Errorf("Filter %+v matched %d alerts, want %d", test.filter, len(got), test.want)
+		}
+	}
+}
+
+func TestRecentAlertsBounded(t *testing.T) {
+	var r recentAlerts
+	for i := 0; i < maxRecentAlerts+10; i++ {
+		r.add(Alert{Key: fmt.Sprint(i)})
+	}
+
+	if len(r.alerts) != maxRecentAlerts || r.alerts[0].Key != "10" {
+		t.Errorf("Expected the %d newest alerts to be kept, got %d starting at %q", maxRecentAlerts, len(r.alerts), r.alerts[0].Key)
+	}
+}
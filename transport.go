@@ -0,0 +1,330 @@
+package telegramhook
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// IPPreference selects which address family is tried first when connecting
+// to the Telegram API.
+type IPPreference int
+
+const (
+	// IPAny keeps the order returned by the resolver.
+	IPAny IPPreference = iota
+	// IPv4 tries IPv4 addresses before IPv6 addresses.
+	IPv4
+	// IPv6 tries IPv6 addresses before IPv4 addresses.
+	IPv6
+)
+
+func (p IPPreference) String() string {
+	switch p {
+	case IPv4:
+		return "ipv4"
+	case IPv6:
+		return "ipv6"
+	}
+	return "any"
+}
+
+// dnsCache resolves host names and keeps the results for the refresh interval.
+// When a refresh fails the previous addresses are used until a lookup succeeds.
+type dnsCache struct {
+	refresh time.Duration
+
+	mu      sync.Mutex
+	entries map[string]dnsEntry
+}
+
+type dnsEntry struct {
+	ips     []net.IP
+	expires time.Time
+}
+
+// lookup returns the addresses of host, from the cache when still fresh.
+func (c *dnsCache) lookup(ctx context.Context, host string) ([]net.IP, error) {
+	c.mu.Lock()
+	entry, ok := c.entries[host]
+	c.mu.Unlock()
+
+	if ok && time.Now().Before(entry.expires) {
+		return entry.ips, nil
+	}
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		if ok {
+			return entry.ips, nil
+		}
+		return nil, err
+	}
+
+	ips := make([]net.IP, 0, len(addrs))
+	for _, addr := range addrs {
+		ips = append(ips, addr.IP)
+	}
+
+	if c.refresh > 0 {
+		c.mu.Lock()
+		if c.entries == nil {
+			c.entries = map[string]dnsEntry{}
+		}
+		c.entries[host] = dnsEntry{ips: ips, expires: time.Now().Add(c.refresh)}
+		c.mu.Unlock()
+	}
+
+	return ips, nil
+}
+
+// fallbackDelay is how long the addresses of the preferred family are tried
+// alone before those of the other family are dialed in parallel, as with
+// "Happy Eyeballs" (RFC 6555) in net.Dialer.
+const fallbackDelay = 300 * time.Millisecond
+
+// minDialTimeout bounds how short the share of a single address in the dial
+// deadline can get.
+const minDialTimeout = 2 * time.Second
+
+// dialer connects to resolved addresses in the preferred order.
+type dialer struct {
+	dial       func(ctx context.Context, network, addr string) (net.Conn, error)
+	cache      *dnsCache
+	preference IPPreference
+}
+
+// dialResult is the outcome of dialing the addresses of one family.
+type dialResult struct {
+	conn net.Conn
+	err  error
+}
+
+// DialContext resolves the host of addr and connects to its addresses. The
+// addresses of the preferred family, the family of the first address without
+// a preference, are tried first; the other family is raced against them after
+// fallbackDelay or as soon as they failed.
+func (d *dialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil || net.ParseIP(host) != nil {
+		return d.dial(ctx, network, addr)
+	}
+
+	ips, err := d.cache.lookup(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	ips = sortIPs(ips, d.preference)
+	if len(ips) == 0 {
+		return nil, &net.DNSError{Err: "no addresses", Name: host, IsNotFound: true}
+	}
+
+	var primaries, fallbacks []net.IP
+	for _, ip := range ips {
+		if (ip.To4() != nil) == (ips[0].To4() != nil) {
+			primaries = append(primaries, ip)
+		} else {
+			fallbacks = append(fallbacks, ip)
+		}
+	}
+	if len(fallbacks) == 0 {
+		return d.dialSerial(ctx, network, port, primaries)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan dialResult, 2)
+	start := func(ips []net.IP) {
+		go func() {
+			conn, err := d.dialSerial(ctx, network, port, ips)
+			results <- dialResult{conn: conn, err: err}
+		}()
+	}
+	start(primaries)
+	timer := time.NewTimer(fallbackDelay)
+	defer timer.Stop()
+
+	pending, fallbackStarted := 1, false
+	var firstErr error
+	for {
+		select {
+		case <-timer.C:
+			if !fallbackStarted {
+				fallbackStarted = true
+				pending++
+				start(fallbacks)
+			}
+		case r := <-results:
+			pending--
+			if r.err == nil {
+				// The losing family is cancelled; a connection it made just
+				// before is closed.
+				go func(n int) {
+					for ; n > 0; n-- {
+						if r := <-results; r.conn != nil {
+							r.conn.Close()
+						}
+					}
+				}(pending)
+				return r.conn, nil
+			}
+			if firstErr == nil {
+				firstErr = r.err
+			}
+			if !fallbackStarted {
+				fallbackStarted = true
+				pending++
+				start(fallbacks)
+			}
+			if pending == 0 {
+				return nil, firstErr
+			}
+		}
+	}
+}
+
+// dialSerial tries the addresses in turn. With a deadline on ctx, every
+// address gets an equal share of the time left, but at least minDialTimeout,
+// so one address that never answers does not use up the whole deadline.
+func (d *dialer) dialSerial(ctx context.Context, network, port string, ips []net.IP) (net.Conn, error) {
+	var lastErr error
+	for i, ip := range ips {
+		if err := ctx.Err(); err != nil {
+			if lastErr == nil {
+				lastErr = err
+			}
+			break
+		}
+
+		dialCtx, cancel := ctx, context.CancelFunc(func() {})
+		if deadline, ok := ctx.Deadline(); ok {
+			timeout := time.Until(deadline) / time.Duration(len(ips)-i)
+			if timeout < minDialTimeout {
+				timeout = minDialTimeout
+			}
+			dialCtx, cancel = context.WithTimeout(ctx, timeout)
+		}
+		conn, err := d.dial(dialCtx, network, net.JoinHostPort(ip.String(), port))
+		cancel()
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// sortIPs returns ips with the preferred address family first.
+func sortIPs(ips []net.IP, preference IPPreference) []net.IP {
+	if preference == IPAny {
+		return ips
+	}
+
+	sorted := make([]net.IP, 0, len(ips))
+	var rest []net.IP
+	for _, ip := range ips {
+		if (ip.To4() != nil) == (preference == IPv4) {
+			sorted = append(sorted, ip)
+		} else {
+			rest = append(rest, ip)
+		}
+	}
+	return append(sorted, rest...)
+}
+
+// localIPs returns the addresses addr stands for, an IP address or the name of
+// a network interface.
+func localIPs(addr string) ([]net.IP, error) {
+	if ip := net.ParseIP(addr); ip != nil {
+		return []net.IP{ip}, nil
+	}
+
+	iface, err := net.InterfaceByName(addr)
+	if err != nil {
+		return nil, fmt.Errorf("is neither an IP address nor a network interface: %w", err)
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil, err
+	}
+
+	var ips []net.IP
+	for _, a := range addrs {
+		if ipNet, ok := a.(*net.IPNet); ok && ipNet.IP.IsGlobalUnicast() {
+			ips = append(ips, ipNet.IP)
+		}
+	}
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("has no usable address")
+	}
+	return ips, nil
+}
+
+// boundDial returns a dial function that connects from the local address of
+// the same family as the remote address.
+func boundDial(ips []net.IP) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		remote := net.ParseIP(host)
+
+		for _, ip := range ips {
+			if remote != nil && (ip.To4() != nil) != (remote.To4() != nil) {
+				continue
+			}
+			d := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second, LocalAddr: &net.TCPAddr{IP: ip}}
+			return d.DialContext(ctx, network, addr)
+		}
+		return nil, fmt.Errorf("dial %s: no local address of the same family", addr)
+	}
+}
+
+// configureTransport installs the caching dialer on a copy of the client when
+// DNS caching, an address family preference or a local address is configured.
+// The client and its transport are cloned so other users of them are not
+// affected; a custom dial function, e.g. for a proxy, is kept and called with
+// the resolved addresses unless connections are bound to a local address.
+func (h *TelegramHook) configureTransport() error {
+	cfg := h.snapshot()
+	if cfg.dnsRefresh <= 0 && cfg.ipPreference == IPAny && cfg.localAddr == "" {
+		return nil
+	}
+
+	var transport *http.Transport
+	switch t := h.client.Transport.(type) {
+	case nil:
+		transport = http.DefaultTransport.(*http.Transport).Clone()
+	case *http.Transport:
+		transport = t.Clone()
+	default:
+		return &ConfigError{Field: "client", Reason: "has a transport that does not support DNS caching"}
+	}
+
+	d := &dialer{
+		dial:       transport.DialContext,
+		cache:      &dnsCache{refresh: cfg.dnsRefresh},
+		preference: cfg.ipPreference,
+	}
+	if d.dial == nil {
+		d.dial = (&net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}).DialContext
+	}
+	if cfg.localAddr != "" {
+		ips, err := localIPs(cfg.localAddr)
+		if err != nil {
+			return &ConfigError{Field: "localAddr", Reason: err.Error()}
+		}
+		d.dial = boundDial(ips)
+	}
+	transport.DialContext = d.DialContext
+
+	client := *h.client
+	client.Transport = transport
+	h.client = &client
+	return nil
+}
@@ -0,0 +1,147 @@
+package telegramhook
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestDialerPreference(t *testing.T) {
+	cache := &dnsCache{refresh: time.Minute, entries: map[string]dnsEntry{
+		"api.telegram.org": {
+			ips:     []net.IP{net.ParseIP("2001:67c:4e8::1"), net.ParseIP("149.154.167.220")},
+			expires: time.Now().Add(time.Minute),
+		},
+	}}
+
+	var dialed []string
+	d := &dialer{
+		cache:      cache,
+		preference: IPv4,
+		dial: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			dialed = append(dialed, addr)
+			return nil, errors.New("refused")
+		},
+	}
+
+	if _, err := d.DialContext(context.Background(), "tcp", "api.telegram.org:443"); err == nil {
+		t.Fatal("Expected dial error")
+	}
+
+	if len(dialed) != 2 || dialed[0] != "149.154.167.220:443" || dialed[1] != "[2001:67c:4e8::1]:443" {
+		t.Errorf("Unexpected dial order: %q", dialed)
+	}
+}
+
+func TestDialerFallback(t *testing.T) {
+	cache := &dnsCache{refresh: time.Minute, entries: map[string]dnsEntry{
+		"api.telegram.org": {
+			ips:     []net.IP{net.ParseIP("2001:67c:4e8::1"), net.ParseIP("2001:67c:4e8::2"), net.ParseIP("149.154.167.220")},
+			expires: time.Now().Add(time.Minute),
+		},
+	}}
+
+	d := &dialer{
+		cache:      cache,
+		preference: IPv6,
+		dial: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			if addr == "149.154.167.220:443" {
+				client, server := net.Pipe()
+				server.Close()
+				return client, nil
+			}
+			<-ctx.Done() // a broken route that never answers
+			return nil, ctx.Err()
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	start := time.Now()
+	conn, err := d.DialContext(ctx, "tcp", "api.telegram.org:443")
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected the IPv4 address to be raced after the fallback delay, took %s", elapsed)
+	}
+
+	// Within a family, an address that never answers only gets its share of
+	// the deadline.
+	d.preference = IPAny
+	ctx, cancel = context.WithTimeout(context.Background(), 2*minDialTimeout)
+	defer cancel()
+	var dialed []string
+	d.dial = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		dialed = append(dialed, addr)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	cache.entries["api.telegram.org"] = dnsEntry{
+		ips:     []net.IP{net.ParseIP("149.154.167.220"), net.ParseIP("149.154.167.221")},
+		expires: time.Now().Add(time.Minute),
+	}
+	if _, err := d.DialContext(ctx, "tcp", "api.telegram.org:443"); err == nil {
+		t.Fatal("Expected dial error")
+	}
+	if len(dialed) != 2 {
+		t.Errorf("Expected both addresses to be tried within the deadline, got %q", dialed)
+	}
+}
+
+func TestBoundDial(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	ips, err := localIPs("127.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dial := boundDial(ips)
+
+	conn, err := dial(context.Background(), "tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if local := conn.LocalAddr().(*net.TCPAddr); !local.IP.Equal(net.ParseIP("127.0.0.1")) {
+		t.Errorf("Expected the connection to be bound to 127.0.0.1, got %s", local)
+	}
+
+	if _, err := dial(context.Background(), "tcp", "[::1]:443"); err == nil {
+		t.Error("Expected an IPv6 address not to be dialed from an IPv4 address")
+	}
+}
+
+func TestLocalAddrInvalid(t *testing.T) {
+	h := newTestHook(WithLocalAddr("no-such-interface0"))
+	h.client = &http.Client{}
+
+	var cfgErr *ConfigError
+	if err := h.configureTransport(); !errors.As(err, &cfgErr) || cfgErr.Field != "localAddr" {
+		t.Errorf("Expected a ConfigError for localAddr, got %v", err)
+	}
+}
+
+func TestConfigureTransportCopiesClient(t *testing.T) {
+	client := &http.Client{}
+	h := newTestHook(WithIPPreference(IPv4))
+	h.client = client
+
+	if err := h.configureTransport(); err != nil {
+		t.Fatal(err)
+	}
+	if h.client == client || client.Transport != nil {
+		t.Error("Expected the caller's client to be left unchanged")
+	}
+	if _, ok := h.client.Transport.(*http.Transport); !ok {
+		t.Errorf("Expected the dialing transport on the hook's client, got %T", h.client.Transport)
+	}
+}
@@ -0,0 +1,55 @@
+package telegramhook
+
+import "time"
+
+// VolumeProfile bundles the queue, worker and batching settings for an
+// expected log volume. The predefined profiles are untuned presets: their
+// values follow from the defaults of the hook and the limit of 20 messages per
+// minute Telegram applies to groups, not from measurements. The soak test in
+// soak_test.go, run with "go test -tags soak -run Soak", checks how a profile
+// copes with the load of a service.
+type VolumeProfile struct {
+	// Async queues messages instead of sending them from Fire. Without it the
+	// queue and worker settings are ignored.
+	Async bool
+	// Workers is the number of workers delivering queued messages.
+	Workers int
+	// QueueSize is the maximum number of queued messages, zero for no limit.
+	QueueSize int
+	// MaxQueueBytes limits the approximate memory used by queued messages,
+	// zero for no limit.
+	MaxQueueBytes int
+	// Batching combines messages during bursts, nil sends every message on its
+	// own.
+	Batching *AdaptiveBatching
+}
+
+var (
+	// LowVolume sends every message synchronously from Fire, so delivery
+	// errors are returned to logrus. It suits services logging a few alerts
+	// per hour.
+	LowVolume = VolumeProfile{}
+
+	// Standard queues messages for a single worker, keeping them in order, and
+	// combines them during bursts of more than 20 messages per minute. It
+	// suits most services.
+	Standard = VolumeProfile{
+		Async:         true,
+		Workers:       1,
+		QueueSize:     defaultQueueSize,
+		MaxQueueBytes: 8 << 20,
+		Batching:      &AdaptiveBatching{Threshold: 20, Interval: 10 * time.Second},
+	}
+
+	// HighVolume delivers with four workers, so messages may arrive out of
+	// order, from a larger queue and batches as soon as traffic exceeds what a
+	// Telegram group accepts. It suits fleets funnelling many instances into
+	// one chat.
+	HighVolume = VolumeProfile{
+		Async:         true,
+		Workers:       4,
+		QueueSize:     10000,
+		MaxQueueBytes: 32 << 20,
+		Batching:      &AdaptiveBatching{Threshold: 20, Interval: 3 * time.Second},
+	}
+)
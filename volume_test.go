@@ -0,0 +1,28 @@
+package telegramhook
+
+import "testing"
+
+func TestVolumeProfile(t *testing.T) {
+	h := newTestHook(WithVolumeProfile(HighVolume), WithWorkers(2))
+
+	if !h.Async() {
+		t.Error("Expected HighVolume to queue messages")
+	}
+	if n := h.Workers(); n != 2 {
+		t.Errorf("Expected a later option to override the workers, got %d", n)
+	}
+	if n, _ := h.QueueSize(); n != HighVolume.QueueSize {
+		t.Errorf("Unexpected queue size %d", n)
+	}
+	if b := h.AdaptiveBatching(); b == nil || b == HighVolume.Batching {
+		t.Error("Expected a copy of the batching settings of the profile")
+	}
+
+	h.SetVolumeProfile(LowVolume)
+	if h.Async() || h.AdaptiveBatching() != nil {
+		t.Error("Expected LowVolume to send synchronously without batching")
+	}
+	if n := h.Workers(); n != 2 {
+		t.Errorf("Expected LowVolume to keep the workers, got %d", n)
+	}
+}
@@ -0,0 +1,74 @@
+package telegramhook
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// watchdogInterval returns how often systemd expects watchdog pings for this
+// process, zero when the service has no watchdog.
+func watchdogInterval() time.Duration {
+	if os.Getenv("NOTIFY_SOCKET") == "" {
+		return 0
+	}
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0
+	}
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+	return time.Duration(usec) * time.Microsecond
+}
+
+// sdNotify sends state to the systemd notification socket.
+func sdNotify(state string) error {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if strings.HasPrefix(socket, "@") {
+		socket = "\x00" + socket[1:]
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = conn.Write([]byte(state))
+	return err
+}
+
+// startWatchdog pings the systemd watchdog at half its interval for as long as
+// the delivery pipeline is healthy. When the oldest queued message waited
+// longer than the configured age, the sender is considered wedged and pings
+// stop, so systemd restarts the service.
+func (h *TelegramHook) startWatchdog() {
+	interval := watchdogInterval()
+	if interval == 0 || h.SystemdWatchdog() <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval / 2)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-h.done:
+				return
+			case <-ticker.C:
+				if h.healthy() {
+					_ = sdNotify("WATCHDOG=1")
+				}
+			}
+		}
+	}()
+}
+
+// healthy reports whether queued messages are delivered in time.
+func (h *TelegramHook) healthy() bool {
+	oldest, ok := h.pending.oldest()
+	return !ok || time.Since(oldest) <= h.SystemdWatchdog()
+}
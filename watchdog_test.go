@@ -0,0 +1,55 @@
+package telegramhook
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatchdog(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	socket := filepath.Join(dir, "notify")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	t.Setenv("NOTIFY_SOCKET", socket)
+	t.Setenv("WATCHDOG_USEC", "20000")
+	t.Setenv("WATCHDOG_PID", "")
+
+	h := newTestHook(WithSystemdWatchdog(time.Minute))
+	h.done = make(chan struct{})
+	h.startWatchdog()
+	defer h.Close()
+
+	buf := make([]byte, 64)
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(buf[:n]); got != "WATCHDOG=1" {
+		t.Errorf("Unexpected notification %q", got)
+	}
+}
+
+func TestWatchdogHealthy(t *testing.T) {
+	h := newTestHook(WithSystemdWatchdog(time.Minute))
+	if !h.healthy() {
+		t.Error("Expected an empty queue to be healthy")
+	}
+
+	h.pending.push(&pendingMessage{outgoing: outgoing{msg: "stuck"}, enqueued: time.Now().Add(-2 * time.Minute)}, 0, 0, false)
+	if h.healthy() {
+		t.Error("Expected a queue with a stale message to be unhealthy")
+	}
+}